@@ -0,0 +1,51 @@
+// Draws the strength of a password in a <meter data-password="input-id">.
+// It follows Measure of internal/passwords, the server has the last word.
+(function () {
+    const thresholds = [28, 36, 60, 80]
+    const labels = ["Muito fraca", "Fraca", "Razoável", "Forte", "Muito forte"]
+
+    function entropy(password) {
+        let lower = false, upper = false, digit = false, symbol = false, other = false
+        let length = 0, prev = 0, prevStep = 0, first = true
+
+        for (const char of password) {
+            const c = char.codePointAt(0)
+
+            if (c >= 97 && c <= 122) lower = true
+            else if (c >= 65 && c <= 90) upper = true
+            else if (c >= 48 && c <= 57) digit = true
+            else if (c < 127) symbol = true
+            else other = true
+
+            const step = c - prev
+
+            if (!first && (step === 0 || (Math.abs(step) === 1 && step === prevStep))) {
+                length += 0.25
+            } else {
+                length++
+            }
+
+            first = false
+            prev = c
+            prevStep = step
+        }
+
+        const pool = (lower ? 26 : 0) + (upper ? 26 : 0) + (digit ? 10 : 0) + (symbol ? 33 : 0) + (other ? 100 : 0)
+
+        return pool === 0 ? 0 : length * Math.log2(pool)
+    }
+
+    $("meter[data-password]").each(function () {
+        const meter = $(this)
+        const label = meter.next(".password-strength-label")
+
+        $("#" + meter.data("password")).on("input", function () {
+            const password = $(this).val()
+            const bits = entropy(password)
+            const score = thresholds.filter(threshold => bits >= threshold).length
+
+            meter.val(score)
+            label.text(password === "" ? "" : labels[score])
+        })
+    })
+})()
@@ -0,0 +1,10 @@
+// Switches the workspace as soon as one is picked in the header. Without
+// JavaScript the form keeps its button.
+$(".workspace-switcher").each(function () {
+    const form = $(this)
+
+    form.find("button").hide()
+    form.find("select").on("change", function () {
+        form.submit()
+    })
+})
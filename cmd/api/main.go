@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"go_pro/config"
 	"go_pro/internal/database"
+	"go_pro/internal/handlers"
+	"go_pro/internal/inbox"
+	"go_pro/internal/jobs"
 	"go_pro/internal/loggers"
 	"go_pro/internal/mailers"
+	"go_pro/internal/passwords"
 	"go_pro/internal/repositories"
 	"go_pro/internal/router"
 	"log/slog"
@@ -13,6 +18,7 @@ import (
 	"os"
 	"strconv"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/alexedwards/scs/pgxstore"
 	"github.com/alexedwards/scs/v2"
@@ -25,8 +31,15 @@ func main() {
 	port := fmt.Sprintf(":%s", config.ServerPort)
 	db, err := database.LoadDataBase(config.DBConnURL)
 
+	sessionPolicy := config.GetSessionPolicy()
+
+	// Sessions only outlive the browser after RememberMe. The idle timeout of
+	// scs is the longest one, the shorter one of the usual sessions is
+	// enforced by the auth middleware.
 	sessionManager := scs.New()
-	sessionManager.Lifetime = time.Hour
+	sessionManager.Lifetime = sessionPolicy.Lifetime
+	sessionManager.IdleTimeout = sessionPolicy.RememberIdleTimeout
+	sessionManager.Cookie.Persist = false
 	sessionManager.Store = pgxstore.New(db)
 
 	pgxstore.NewWithCleanupInterval(db, 30*time.Minute)
@@ -46,22 +59,92 @@ func main() {
 		From:     config.MailFrom,
 	})
 
-	noteRepo := repositories.NewNoteRepository(db)
-	userRepo := repositories.NewUserRepository(db)
+	passwordChecker, err := passwords.NewChecker(config.GetPasswordPolicy())
+
+	if err != nil {
+		slog.Error("Failed loading the password policy", "error", err)
+		panic("Server Error!")
+	}
+
+	passwordHasher := passwords.NewHasher(config.GetPasswordHashParams())
+
+	noteRepo := repositories.NewNoteRepository(db, config.GetQuota())
+	userRepo := repositories.NewUserRepository(db, config.GetUserTokenTTL())
+	commentRepo := repositories.NewCommentRepository(db)
+	calendarRepo := repositories.NewCalendarRepository(db)
+	quotaRepo := repositories.NewQuotaRepository(db, config.GetQuota())
+	sessionRepo := repositories.NewSessionRepository(db)
+	accountRepo := repositories.NewAccountRepository(db)
+	twoFactorRepo := repositories.NewTwoFactorRepository(db)
+	accessTokenRepo := repositories.NewAccessTokenRepository(db)
+	loginSecurityRepo := repositories.NewLoginSecurityRepository(db)
+	rateLimitRepo := repositories.NewRateLimitRepository(db)
+	adminRepo := repositories.NewAdminRepository(db)
+	profileRepo := repositories.NewProfileRepository(db)
+	workspaceRepo := repositories.NewWorkspaceRepository(db, config.GetUserTokenTTL().WorkspaceInvitation)
+	dashboardRepo := repositories.NewCachedDashboardRepository(repositories.NewDashboardRepository(db), config.GetDashboardCacheTTL())
 
 	slog.SetDefault(log)
 	slog.Info(fmt.Sprintf("Servidor rodando na porta %s\n", config.ServerPort))
 
-	mux := router.LoadRoutes(sessionManager, db, noteRepo, userRepo, mailService)
+	if config.IsInboxEnabled() {
+		inboxServer := inbox.NewServer(inbox.Config{
+			Addr:    fmt.Sprintf(":%s", config.InboxPort),
+			Domain:  config.InboxDomain,
+			MaxSize: config.GetInboxMaxSize(),
+		}, userRepo, noteRepo)
+
+		go func() {
+			if err := inboxServer.ListenAndServe(); err != nil {
+				slog.Error("Inbox Error", "error", err)
+			}
+		}()
+	}
+
+	go jobs.Every(context.Background(), "purge-deleted-accounts", time.Hour, func(ctx context.Context) error {
+		purged, err := accountRepo.PurgeDeleted(ctx, config.GetAccountDeletionGrace())
+
+		if purged > 0 {
+			slog.Info(fmt.Sprintf("%d contas excluídas", purged))
+		}
+
+		return err
+	})
+
+	go jobs.Every(context.Background(), "delete-unconfirmed-accounts", time.Hour, func(ctx context.Context) error {
+		deleted, err := userRepo.DeleteUnconfirmed(ctx, config.GetUnconfirmedAccountAge())
+
+		if deleted > 0 {
+			slog.Info(fmt.Sprintf("%d cadastros não confirmados excluídos", deleted))
+		}
+
+		return err
+	})
+
+	go jobs.Every(context.Background(), "delete-idle-rate-limits", time.Hour, func(ctx context.Context) error {
+		return rateLimitRepo.DeleteIdle(ctx, 24*time.Hour)
+	})
+
+	go jobs.Every(context.Background(), "delete-expired-sessions", time.Hour, func(ctx context.Context) error {
+		return sessionRepo.DeleteExpired(ctx, time.Hour)
+	})
+
+	go jobs.Every(context.Background(), "delete-old-failed-logins", 24*time.Hour, func(ctx context.Context) error {
+		return loginSecurityRepo.DeleteOldFailedLogins(ctx, 90*24*time.Hour)
+	})
+
+	mux := router.LoadRoutes(sessionManager, db, noteRepo, userRepo, commentRepo, dashboardRepo, calendarRepo, quotaRepo, sessionRepo, accountRepo, twoFactorRepo, accessTokenRepo, loginSecurityRepo, rateLimitRepo, adminRepo, profileRepo, workspaceRepo, passwordChecker, passwordHasher, mailService, config)
 
 	csrfMiddleware := csrf.Protect([]byte(config.CSRFKey))
+	bodyLimit := handlers.LimitRequestBody(config.GetMaxRequestBody())
+	tokenMiddleware := handlers.NewAuthMiddleware(sessionManager, accessTokenRepo, sessionRepo, userRepo, config.IsProxyTrusted(), sessionPolicy.IdleTimeout).AuthenticateToken
 
 	// if err = http.ListenAndServeTLS(port, "cer.cer", "cer.key", sessionManager.LoadAndSave(csrfMiddleware(mux))); err != nil {
 	// 	slog.Error("Server Error", "error", err)
 	// 	panic("Server Error!")
 	// }
 
-	if err = http.ListenAndServe(port, sessionManager.LoadAndSave(csrfMiddleware(mux))); err != nil {
+	if err = http.ListenAndServe(port, bodyLimit(sessionManager.LoadAndSave(tokenMiddleware(csrfMiddleware(mux))))); err != nil {
 		slog.Error("Server Error", "error", err)
 		panic("Server Error!")
 	}
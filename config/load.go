@@ -1,11 +1,15 @@
 package config
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"go_pro/internal/models"
 	"log/slog"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -20,6 +24,78 @@ type Config struct {
 	MailPassword string `env:"QNS_MAIL_PASSWORD,required"`
 	MailFrom     string `env:"QNS_MAIL_FROM,quicknotes@quick.com"`
 	CSRFKey      string `env:"QNS_CSRF_KEY,required"`
+	BaseURL      string `env:"QNS_BASE_URL,http://localhost:3000"`
+
+	SessionLifetime            string `env:"QNS_SESSION_LIFETIME,12h"`
+	SessionIdleTimeout         string `env:"QNS_SESSION_IDLE_TIMEOUT,1h"`
+	SessionRememberLifetime    string `env:"QNS_SESSION_REMEMBER_LIFETIME,720h"`
+	SessionRememberIdleTimeout string `env:"QNS_SESSION_REMEMBER_IDLE_TIMEOUT,168h"`
+
+	DashboardCacheTTL string `env:"QNS_DASHBOARD_CACHE_TTL,1m"`
+
+	InboxEnabled string `env:"QNS_INBOX_ENABLED,false"`
+	InboxPort    string `env:"QNS_INBOX_PORT,2525"`
+	InboxDomain  string `env:"QNS_INBOX_DOMAIN,notes.local"`
+	InboxMaxSize string `env:"QNS_INBOX_MAX_SIZE,1048576"`
+
+	NoteUnlockWindow string `env:"QNS_NOTE_UNLOCK_WINDOW,5m"`
+	NoteSessionKey   string `env:"QNS_NOTE_SESSION_KEY,"`
+
+	QuotaMaxNotes    string `env:"QNS_QUOTA_MAX_NOTES,1000"`
+	QuotaMaxNoteSize string `env:"QNS_QUOTA_MAX_NOTE_SIZE,65536"`
+	QuotaMaxStorage  string `env:"QNS_QUOTA_MAX_STORAGE,10485760"`
+
+	MaxRequestBody string `env:"QNS_MAX_REQUEST_BODY,4194304"`
+
+	AccountDeletionGrace string `env:"QNS_ACCOUNT_DELETION_GRACE,720h"`
+
+	OIDCIssuer       string `env:"QNS_OIDC_ISSUER,"`
+	OIDCClientID     string `env:"QNS_OIDC_CLIENT_ID,"`
+	OIDCClientSecret string `env:"QNS_OIDC_CLIENT_SECRET,"`
+	OIDCRedirectURL  string `env:"QNS_OIDC_REDIRECT_URL,http://localhost:3000/user/oidc/callback"`
+	OIDCProviderName string `env:"QNS_OIDC_PROVIDER_NAME,SSO"`
+
+	TrustProxy string `env:"QNS_TRUST_PROXY,false"`
+
+	RateLimitSigninIP             string `env:"QNS_RATE_LIMIT_SIGNIN_IP,30/15m"`
+	RateLimitSigninEmail          string `env:"QNS_RATE_LIMIT_SIGNIN_EMAIL,10/15m"`
+	RateLimitSignupIP             string `env:"QNS_RATE_LIMIT_SIGNUP_IP,10/1h"`
+	RateLimitSignupEmail          string `env:"QNS_RATE_LIMIT_SIGNUP_EMAIL,3/1h"`
+	RateLimitForgetPasswordIP     string `env:"QNS_RATE_LIMIT_FORGET_PASSWORD_IP,10/1h"`
+	RateLimitForgetPasswordEmail  string `env:"QNS_RATE_LIMIT_FORGET_PASSWORD_EMAIL,3/1h"`
+	RateLimitResendConfirmIP      string `env:"QNS_RATE_LIMIT_RESEND_CONFIRMATION_IP,10/1h"`
+	RateLimitResendConfirmEmail   string `env:"QNS_RATE_LIMIT_RESEND_CONFIRMATION_EMAIL,3/1h"`
+	RateLimitMagicLinkIP          string `env:"QNS_RATE_LIMIT_MAGIC_LINK_IP,10/1h"`
+	RateLimitMagicLinkEmail       string `env:"QNS_RATE_LIMIT_MAGIC_LINK_EMAIL,3/1h"`
+	RateLimitTwoFactorIP          string `env:"QNS_RATE_LIMIT_TWO_FACTOR_IP,30/15m"`
+	RateLimitTwoFactorUser        string `env:"QNS_RATE_LIMIT_TWO_FACTOR_USER,10/15m"`
+	RateLimitLinkSigninIP         string `env:"QNS_RATE_LIMIT_LINK_SIGNIN_IP,30/15m"`
+	RateLimitOIDCCallbackIP       string `env:"QNS_RATE_LIMIT_OIDC_CALLBACK_IP,30/15m"`
+	RateLimitNoteUnlockIP         string `env:"QNS_RATE_LIMIT_NOTE_UNLOCK_IP,30/15m"`
+	RateLimitNoteUnlockNote       string `env:"QNS_RATE_LIMIT_NOTE_UNLOCK_NOTE,5/15m"`
+	RateLimitWorkspaceInviteIP    string `env:"QNS_RATE_LIMIT_WORKSPACE_INVITE_IP,20/1h"`
+	RateLimitWorkspaceInviteEmail string `env:"QNS_RATE_LIMIT_WORKSPACE_INVITE_EMAIL,3/1h"`
+
+	ConfirmationTokenTTL  string `env:"QNS_CONFIRMATION_TOKEN_TTL,48h"`
+	PasswordResetTokenTTL string `env:"QNS_PASSWORD_RESET_TOKEN_TTL,4h"`
+	UnconfirmedAccountAge string `env:"QNS_UNCONFIRMED_ACCOUNT_AGE,168h"`
+	MagicLinkTokenTTL     string `env:"QNS_MAGIC_LINK_TOKEN_TTL,15m"`
+	MagicLinkSameBrowser  string `env:"QNS_MAGIC_LINK_SAME_BROWSER,false"`
+
+	WorkspaceInvitationTokenTTL string `env:"QNS_WORKSPACE_INVITATION_TOKEN_TTL,168h"`
+	EmailChangeTokenTTL         string `env:"QNS_EMAIL_CHANGE_TOKEN_TTL,24h"`
+
+	LockoutThreshold   string `env:"QNS_LOCKOUT_THRESHOLD,5"`
+	LockoutDuration    string `env:"QNS_LOCKOUT_DURATION,1m"`
+	LockoutMaxDuration string `env:"QNS_LOCKOUT_MAX_DURATION,24h"`
+
+	PasswordMinLength    string `env:"QNS_PASSWORD_MIN_LENGTH,8"`
+	PasswordMinEntropy   string `env:"QNS_PASSWORD_MIN_ENTROPY,36"`
+	PasswordBreachedList string `env:"QNS_PASSWORD_BREACHED_LIST,"`
+
+	PasswordHashMemory      string `env:"QNS_PASSWORD_HASH_MEMORY,19456"`
+	PasswordHashIterations  string `env:"QNS_PASSWORD_HASH_ITERATIONS,2"`
+	PasswordHashParallelism string `env:"QNS_PASSWORD_HASH_PARALLELISM,1"`
 }
 
 func (c Config) GetLevelLog() slog.Level {
@@ -37,6 +113,372 @@ func (c Config) GetLevelLog() slog.Level {
 	}
 }
 
+// GetBaseURL returns the address the app is reached at, without a trailing
+// slash. The links of the emails and of the calendar feed are built from it,
+// never from the Host header of the request.
+func (c Config) GetBaseURL() string {
+	return strings.TrimRight(c.BaseURL, "/")
+}
+
+// GetSessionPolicy returns the absolute and idle lifetimes of the sessions,
+// for the usual ones and for those of users who asked to be remembered. A
+// remembered session never lives shorter than a usual one.
+func (c Config) GetSessionPolicy() models.SessionPolicy {
+	policy := models.SessionPolicy{
+		Lifetime:            parseDuration(c.SessionLifetime, 12*time.Hour),
+		IdleTimeout:         parseDuration(c.SessionIdleTimeout, time.Hour),
+		RememberLifetime:    parseDuration(c.SessionRememberLifetime, 30*24*time.Hour),
+		RememberIdleTimeout: parseDuration(c.SessionRememberIdleTimeout, 7*24*time.Hour),
+	}
+
+	policy.IdleTimeout = min(policy.IdleTimeout, policy.Lifetime)
+	policy.RememberLifetime = max(policy.RememberLifetime, policy.Lifetime)
+	policy.RememberIdleTimeout = max(policy.RememberIdleTimeout, policy.IdleTimeout)
+
+	return policy
+}
+
+func (c Config) GetDashboardCacheTTL() time.Duration {
+	ttl, err := time.ParseDuration(c.DashboardCacheTTL)
+
+	if err != nil {
+		return time.Minute
+	}
+
+	return ttl
+}
+
+func (c Config) IsInboxEnabled() bool {
+	enabled, _ := strconv.ParseBool(c.InboxEnabled)
+
+	return enabled
+}
+
+// GetInboxDomain returns the domain of the mail inbox, or an empty string
+// when the inbox is disabled.
+func (c Config) GetInboxDomain() string {
+	if !c.IsInboxEnabled() {
+		return ""
+	}
+
+	return c.InboxDomain
+}
+
+func (c Config) GetInboxMaxSize() int64 {
+	size, err := strconv.ParseInt(c.InboxMaxSize, 10, 64)
+
+	if err != nil || size <= 0 {
+		return 1 << 20
+	}
+
+	return size
+}
+
+func (c Config) GetNoteUnlockWindow() time.Duration {
+	window, err := time.ParseDuration(c.NoteUnlockWindow)
+
+	if err != nil {
+		return 5 * time.Minute
+	}
+
+	return window
+}
+
+// GetNoteSessionKey returns the AES-256 key that seals the keys of unlocked
+// notes in the sessions. Without QNS_NOTE_SESSION_KEY it is derived from the
+// CSRF key, so every instance of the app shares it.
+func (c Config) GetNoteSessionKey() []byte {
+	secret := c.NoteSessionKey
+
+	if secret == "" {
+		secret = "note-session-key:" + c.CSRFKey
+	}
+
+	key := sha256.Sum256([]byte(secret))
+
+	return key[:]
+}
+
+// GetQuota returns the default limits of every user. A limit of zero means
+// no limit.
+func (c Config) GetQuota() models.Quota {
+	return models.Quota{
+		MaxNotes:    parseLimit(c.QuotaMaxNotes, 1000),
+		MaxNoteSize: parseLimit(c.QuotaMaxNoteSize, 64<<10),
+		MaxStorage:  parseLimit(c.QuotaMaxStorage, 10<<20),
+	}
+}
+
+// GetMaxRequestBody returns the largest request body read, in bytes. It must
+// fit an avatar upload and the largest note.
+func (c Config) GetMaxRequestBody() int64 {
+	return parseLimit(c.MaxRequestBody, 4<<20)
+}
+
+// GetAccountDeletionGrace returns how long an account marked for deletion is
+// kept, and its export available, before it is purged.
+func (c Config) GetAccountDeletionGrace() time.Duration {
+	grace, err := time.ParseDuration(c.AccountDeletionGrace)
+
+	if err != nil || grace <= 0 {
+		return 30 * 24 * time.Hour
+	}
+
+	return grace
+}
+
+// IsOIDCEnabled tells if the sign in with an external OpenID Connect provider
+// is configured.
+func (c Config) IsOIDCEnabled() bool {
+	return c.OIDCIssuer != "" && c.OIDCClientID != ""
+}
+
+// IsProxyTrusted tells if the server runs behind a reverse proxy whose
+// X-Forwarded-For header gives the address of the client.
+func (c Config) IsProxyTrusted() bool {
+	trusted, _ := strconv.ParseBool(c.TrustProxy)
+
+	return trusted
+}
+
+// GetSigninRateLimit returns the limits of the sign in form. A limit is
+// written as requests/period, e.g. 10/15m.
+func (c Config) GetSigninRateLimit() models.FormRateLimit {
+	return models.FormRateLimit{
+		ByIP:    parseRateLimit(c.RateLimitSigninIP, models.RateLimit{Requests: 30, Period: 15 * time.Minute}),
+		ByEmail: parseRateLimit(c.RateLimitSigninEmail, models.RateLimit{Requests: 10, Period: 15 * time.Minute}),
+	}
+}
+
+func (c Config) GetSignupRateLimit() models.FormRateLimit {
+	return models.FormRateLimit{
+		ByIP:    parseRateLimit(c.RateLimitSignupIP, models.RateLimit{Requests: 10, Period: time.Hour}),
+		ByEmail: parseRateLimit(c.RateLimitSignupEmail, models.RateLimit{Requests: 3, Period: time.Hour}),
+	}
+}
+
+func (c Config) GetForgetPasswordRateLimit() models.FormRateLimit {
+	return models.FormRateLimit{
+		ByIP:    parseRateLimit(c.RateLimitForgetPasswordIP, models.RateLimit{Requests: 10, Period: time.Hour}),
+		ByEmail: parseRateLimit(c.RateLimitForgetPasswordEmail, models.RateLimit{Requests: 3, Period: time.Hour}),
+	}
+}
+
+func (c Config) GetResendConfirmationRateLimit() models.FormRateLimit {
+	return models.FormRateLimit{
+		ByIP:    parseRateLimit(c.RateLimitResendConfirmIP, models.RateLimit{Requests: 10, Period: time.Hour}),
+		ByEmail: parseRateLimit(c.RateLimitResendConfirmEmail, models.RateLimit{Requests: 3, Period: time.Hour}),
+	}
+}
+
+func (c Config) GetMagicLinkRateLimit() models.FormRateLimit {
+	return models.FormRateLimit{
+		ByIP:    parseRateLimit(c.RateLimitMagicLinkIP, models.RateLimit{Requests: 10, Period: time.Hour}),
+		ByEmail: parseRateLimit(c.RateLimitMagicLinkEmail, models.RateLimit{Requests: 3, Period: time.Hour}),
+	}
+}
+
+// GetTwoFactorRateLimit limits the codes tried at the second step of a sign
+// in, per address and per user, whatever session they come from.
+func (c Config) GetTwoFactorRateLimit() models.FormRateLimit {
+	return models.FormRateLimit{
+		ByIP:    parseRateLimit(c.RateLimitTwoFactorIP, models.RateLimit{Requests: 30, Period: 15 * time.Minute}),
+		ByEmail: parseRateLimit(c.RateLimitTwoFactorUser, models.RateLimit{Requests: 10, Period: 15 * time.Minute}),
+	}
+}
+
+// GetNoteUnlockRateLimit limits the passwords tried on locked notes, per
+// address and per user and note.
+func (c Config) GetNoteUnlockRateLimit() models.FormRateLimit {
+	return models.FormRateLimit{
+		ByIP:    parseRateLimit(c.RateLimitNoteUnlockIP, models.RateLimit{Requests: 30, Period: 15 * time.Minute}),
+		ByEmail: parseRateLimit(c.RateLimitNoteUnlockNote, models.RateLimit{Requests: 5, Period: 15 * time.Minute}),
+	}
+}
+
+// GetLinkSigninRateLimit limits the sign ins with a link sent by email, per
+// address. The link itself names no account.
+func (c Config) GetLinkSigninRateLimit() models.FormRateLimit {
+	return models.FormRateLimit{
+		ByIP: parseRateLimit(c.RateLimitLinkSigninIP, models.RateLimit{Requests: 30, Period: 15 * time.Minute}),
+	}
+}
+
+// GetOIDCCallbackRateLimit limits the returns from the identity provider,
+// per address.
+func (c Config) GetOIDCCallbackRateLimit() models.FormRateLimit {
+	return models.FormRateLimit{
+		ByIP: parseRateLimit(c.RateLimitOIDCCallbackIP, models.RateLimit{Requests: 30, Period: 15 * time.Minute}),
+	}
+}
+
+// GetWorkspaceInviteRateLimit limits the invitations sent by email, per
+// address of the sender and per invited email.
+func (c Config) GetWorkspaceInviteRateLimit() models.FormRateLimit {
+	return models.FormRateLimit{
+		ByIP:    parseRateLimit(c.RateLimitWorkspaceInviteIP, models.RateLimit{Requests: 20, Period: time.Hour}),
+		ByEmail: parseRateLimit(c.RateLimitWorkspaceInviteEmail, models.RateLimit{Requests: 3, Period: time.Hour}),
+	}
+}
+
+// GetUserTokenTTL returns how long the links sent to confirm a sign up, to
+// reset a password, to sign in without one, to join a workspace and to
+// confirm a new email are valid.
+func (c Config) GetUserTokenTTL() models.UserTokenTTL {
+	ttl := models.UserTokenTTL{
+		Confirmation:        48 * time.Hour,
+		PasswordReset:       4 * time.Hour,
+		MagicLink:           c.GetMagicLinkPolicy().TTL,
+		WorkspaceInvitation: parseDuration(c.WorkspaceInvitationTokenTTL, 7*24*time.Hour),
+		EmailChange:         parseDuration(c.EmailChangeTokenTTL, 24*time.Hour),
+	}
+
+	if d, err := time.ParseDuration(c.ConfirmationTokenTTL); err == nil && d > 0 {
+		ttl.Confirmation = d
+	}
+
+	if d, err := time.ParseDuration(c.PasswordResetTokenTTL); err == nil && d > 0 {
+		ttl.PasswordReset = d
+	}
+
+	return ttl
+}
+
+// GetMagicLinkPolicy returns how long a sign in link sent by email is valid,
+// and if it only works in the browser it was asked from.
+func (c Config) GetMagicLinkPolicy() models.MagicLinkPolicy {
+	sameBrowser, _ := strconv.ParseBool(c.MagicLinkSameBrowser)
+
+	return models.MagicLinkPolicy{
+		TTL:         parseDuration(c.MagicLinkTokenTTL, 15*time.Minute),
+		SameBrowser: sameBrowser,
+	}
+}
+
+// GetUnconfirmedAccountAge returns how long an account waits for the
+// confirmation of its email before it is deleted. It is never shorter than
+// the confirmation link.
+func (c Config) GetUnconfirmedAccountAge() time.Duration {
+	age, err := time.ParseDuration(c.UnconfirmedAccountAge)
+
+	if err != nil || age <= 0 {
+		age = 7 * 24 * time.Hour
+	}
+
+	return max(age, c.GetUserTokenTTL().Confirmation)
+}
+
+// GetLockoutPolicy returns after how many failed password checks an account
+// is locked, and for how long.
+func (c Config) GetLockoutPolicy() models.LockoutPolicy {
+	policy := models.LockoutPolicy{
+		Threshold:   int(parseLimit(c.LockoutThreshold, 5)),
+		Duration:    time.Minute,
+		MaxDuration: 24 * time.Hour,
+	}
+
+	if policy.Threshold == 0 {
+		policy.Threshold = 5
+	}
+
+	if d, err := time.ParseDuration(c.LockoutDuration); err == nil && d > 0 {
+		policy.Duration = d
+	}
+
+	if d, err := time.ParseDuration(c.LockoutMaxDuration); err == nil && d >= policy.Duration {
+		policy.MaxDuration = d
+	}
+
+	return policy
+}
+
+// GetPasswordPolicy returns the rules of new passwords. The entropy is in
+// bits, as estimated by the passwords package.
+func (c Config) GetPasswordPolicy() models.PasswordPolicy {
+	policy := models.PasswordPolicy{
+		MinLength:    int(parseLimit(c.PasswordMinLength, 8)),
+		MinEntropy:   36,
+		BreachedList: c.PasswordBreachedList,
+	}
+
+	if policy.MinLength == 0 {
+		policy.MinLength = 8
+	}
+
+	if entropy, err := strconv.ParseFloat(c.PasswordMinEntropy, 64); err == nil && entropy >= 0 {
+		policy.MinEntropy = entropy
+	}
+
+	return policy
+}
+
+// GetPasswordHashParams returns the argon2id costs of new password hashes,
+// the memory in KiB. Hashes written with other costs are upgraded the next
+// time their user signs in.
+func (c Config) GetPasswordHashParams() models.PasswordHashParams {
+	params := models.PasswordHashParams{
+		Memory:      19456,
+		Iterations:  2,
+		Parallelism: 1,
+	}
+
+	if memory, err := strconv.ParseUint(c.PasswordHashMemory, 10, 32); err == nil && memory >= 8 {
+		params.Memory = uint32(memory)
+	}
+
+	if iterations, err := strconv.ParseUint(c.PasswordHashIterations, 10, 32); err == nil && iterations > 0 {
+		params.Iterations = uint32(iterations)
+	}
+
+	if parallelism, err := strconv.ParseUint(c.PasswordHashParallelism, 10, 8); err == nil && parallelism > 0 {
+		params.Parallelism = uint8(parallelism)
+	}
+
+	return params
+}
+
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(value)
+
+	if err != nil || d <= 0 {
+		return fallback
+	}
+
+	return d
+}
+
+func parseRateLimit(value string, fallback models.RateLimit) models.RateLimit {
+	requests, period, found := strings.Cut(value, "/")
+
+	if !found {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(requests)
+
+	if err != nil || n <= 0 {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(period)
+
+	if err != nil || d <= 0 {
+		return fallback
+	}
+
+	return models.RateLimit{Requests: n, Period: d}
+}
+
+func parseLimit(value string, fallback int64) int64 {
+	limit, err := strconv.ParseInt(value, 10, 64)
+
+	if err != nil || limit < 0 {
+		return fallback
+	}
+
+	return limit
+}
+
 func (c Config) SPrint() (envs string) {
 	v := reflect.ValueOf(c)
 	t := v.Type()
@@ -16,3 +16,7 @@ var ErrorInternalServer = func(text string) error {
 var ErrorNotFound = func(text string) error {
 	return NewWithStatus(errors.New(text), http.StatusNotFound)
 }
+
+var ErrorForbidden = func(text string) error {
+	return NewWithStatus(errors.New(text), http.StatusForbidden)
+}
@@ -0,0 +1,110 @@
+package calendar
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxLineOctets = 75
+	localFormat   = "20060102T150405"
+	utcFormat     = "20060102T150405Z"
+)
+
+type Event struct {
+	UID          string
+	Summary      string
+	Description  string
+	URL          string
+	Start        time.Time
+	Duration     time.Duration
+	LastModified time.Time
+}
+
+// Build renders the events as an RFC 5545 calendar. Start times are written
+// as floating local times, the same wall clock the user typed in the form.
+func Build(name string, events []Event) []byte {
+	buff := &bytes.Buffer{}
+
+	writeLine(buff, "BEGIN:VCALENDAR")
+	writeLine(buff, "VERSION:2.0")
+	writeLine(buff, "PRODID:-//Quicknotes//Notes Calendar//PT")
+	writeLine(buff, "CALSCALE:GREGORIAN")
+	writeLine(buff, "METHOD:PUBLISH")
+	writeLine(buff, "X-WR-CALNAME:"+escapeText(name))
+
+	for _, event := range events {
+		writeLine(buff, "BEGIN:VEVENT")
+		writeLine(buff, "UID:"+escapeText(event.UID))
+		writeLine(buff, "DTSTAMP:"+event.LastModified.UTC().Format(utcFormat))
+		writeLine(buff, "LAST-MODIFIED:"+event.LastModified.UTC().Format(utcFormat))
+		writeLine(buff, "DTSTART:"+event.Start.Format(localFormat))
+		writeLine(buff, "DURATION:"+formatDuration(event.Duration))
+		writeLine(buff, "SUMMARY:"+escapeText(event.Summary))
+
+		if event.Description != "" {
+			writeLine(buff, "DESCRIPTION:"+escapeText(event.Description))
+		}
+
+		if event.URL != "" {
+			writeLine(buff, "URL:"+event.URL)
+		}
+
+		writeLine(buff, "BEGIN:VALARM")
+		writeLine(buff, "ACTION:DISPLAY")
+		writeLine(buff, "DESCRIPTION:"+escapeText(event.Summary))
+		writeLine(buff, "TRIGGER:-PT15M")
+		writeLine(buff, "END:VALARM")
+		writeLine(buff, "END:VEVENT")
+	}
+
+	writeLine(buff, "END:VCALENDAR")
+
+	return buff.Bytes()
+}
+
+func escapeText(s string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+
+	return replacer.Replace(s)
+}
+
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "PT0S"
+	}
+
+	return fmt.Sprintf("PT%dM", int(d.Minutes()))
+}
+
+// writeLine folds content lines longer than 75 octets as required by the RFC,
+// without splitting a multi-byte character across lines.
+func writeLine(buff *bytes.Buffer, line string) {
+	limit := maxLineOctets
+
+	for len(line) > limit {
+		cut := limit
+
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		buff.WriteString(line[:cut])
+		buff.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineOctets - 1
+	}
+
+	buff.WriteString(line)
+	buff.WriteString("\r\n")
+}
@@ -0,0 +1,146 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// unfold joins the folded lines back, as a calendar client reads them.
+func unfold(body []byte) []string {
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(string(body), "\r\n ", ""), "\r\n"), "\r\n")
+}
+
+func findLine(t *testing.T, lines []string, prefix string) string {
+	t.Helper()
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			return line
+		}
+	}
+
+	t.Fatalf("no line starting with %q in %q", prefix, lines)
+	return ""
+}
+
+func TestBuildEscapesText(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary string
+		want    string
+	}{
+		{"plain", "Reunião", `SUMMARY:Reunião`},
+		{"comma and semicolon", "a, b; c", `SUMMARY:a\, b\; c`},
+		{"backslash", `C:\notas`, `SUMMARY:C:\\notas`},
+		{"newlines", "um\r\ndois\ntrês\rquatro", `SUMMARY:um\ndois\ntrês\nquatro`},
+		{"literal backslash and n", `\n`, `SUMMARY:\\n`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := unfold(Build("Quicknotes", []Event{{UID: "note-1@example.com", Summary: tt.summary}}))
+
+			if got := findLine(t, lines, "SUMMARY:"); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildFoldsLongLines(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+	}{
+		{"ascii", strings.Repeat("a", 300)},
+		{"multi-byte", strings.Repeat("ação ", 60)},
+		{"emoji", strings.Repeat("📝", 80)},
+		{"exactly the limit", strings.Repeat("b", maxLineOctets-len("DESCRIPTION:"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := Build("Quicknotes", []Event{{UID: "note-1@example.com", Summary: "x", Description: tt.description}})
+
+			if !bytes.HasSuffix(body, []byte("\r\n")) {
+				t.Fatal("the calendar does not end with CRLF")
+			}
+
+			for _, line := range strings.Split(strings.TrimSuffix(string(body), "\r\n"), "\r\n") {
+				if len(line) > maxLineOctets {
+					t.Fatalf("line of %d octets: %q", len(line), line)
+				}
+
+				if !utf8.ValidString(line) {
+					t.Fatalf("line splits a character: %q", line)
+				}
+			}
+
+			if got := findLine(t, unfold(body), "DESCRIPTION:"); got != "DESCRIPTION:"+tt.description {
+				t.Fatalf("unfolded description = %q", got)
+			}
+		})
+	}
+}
+
+func TestBuildEvent(t *testing.T) {
+	event := Event{
+		UID:          "note-7@example.com",
+		Summary:      "Dentista",
+		URL:          "https://example.com/notes/7",
+		Start:        time.Date(2024, 3, 10, 14, 30, 0, 0, time.FixedZone("BRT", -3*60*60)),
+		Duration:     30 * time.Minute,
+		LastModified: time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("BRT", -3*60*60)),
+	}
+
+	lines := unfold(Build("Quicknotes", []Event{event}))
+
+	want := []string{
+		"UID:note-7@example.com",
+		// the start keeps the wall clock, without a zone
+		"DTSTART:20240310T143000",
+		"DURATION:PT30M",
+		// the stamps are converted to UTC
+		"DTSTAMP:20240301T150000Z",
+		"URL:https://example.com/notes/7",
+	}
+
+	for _, line := range want {
+		prefix, _, _ := strings.Cut(line, ":")
+
+		if got := findLine(t, lines, prefix+":"); got != line {
+			t.Errorf("got %q, want %q", got, line)
+		}
+	}
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, "DESCRIPTION:") && line != "DESCRIPTION:Dentista" {
+			t.Errorf("unexpected description %q for an event without one", line)
+		}
+	}
+
+	if lines[0] != "BEGIN:VCALENDAR" || lines[len(lines)-1] != "END:VCALENDAR" {
+		t.Errorf("calendar is not wrapped in VCALENDAR: %q", lines)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "PT0S"},
+		{-time.Minute, "PT0S"},
+		{30 * time.Minute, "PT30M"},
+		{2 * time.Hour, "PT120M"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -15,6 +15,10 @@ func LoadDataBase(url string) (*pgxpool.Pool, error) {
 		return nil, err
 	}
 
+	// now() and the current_timestamp defaults fill timestamp columns in the
+	// time zone of the session, so every connection runs in UTC.
+	config.ConnConfig.RuntimeParams["timezone"] = "UTC"
+
 	dbpool, err := pgxpool.NewWithConfig(context.Background(), config)
 
 	if err != nil {
@@ -0,0 +1,29 @@
+package querys
+
+var (
+	ListAccessTokensQuery string = `
+		select id, name, scope, expires_at, last_used_at, created_at
+		from personal_access_tokens
+		where user_id = $1
+		order by created_at desc;
+	`
+	CreateAccessTokenQuery string = `
+		insert into personal_access_tokens (user_id, name, token_hash, scope, expires_at)
+		values ($1, $2, $3, $4, case when $5::int > 0 then now() + make_interval(days => $5::int) end);
+	`
+	DeleteAccessTokenQuery string = `
+		delete from personal_access_tokens where id = $1 and user_id = $2;
+	`
+	DeleteUserAccessTokensQuery string = `
+		delete from personal_access_tokens where user_id = $1;
+	`
+	UseAccessTokenQuery string = `
+		update personal_access_tokens t set last_used_at = now()
+		from users u
+		where t.token_hash = $1
+		and u.id = t.user_id
+		and u.disabled_at is null
+		and (t.expires_at is null or t.expires_at > now())
+		returning t.user_id, t.scope;
+	`
+)
@@ -0,0 +1,75 @@
+package querys
+
+var (
+	BuildAccountExportQuery string = `
+		select json_build_object(
+			'exported_at', now(),
+			'account', (
+				select json_build_object(
+					'email', email, 'active', active,
+					'created_at', created_at, 'updated_at', updated_at)
+				from users where id = $1
+			),
+			'notes', coalesce((
+				select json_agg(json_build_object(
+					'id', id, 'title', title, 'content', content, 'color', color,
+					'due_at', due_at, 'locked', locked,
+					'created_at', created_at, 'updated_at', updated_at) order by position)
+				from notes where user_id = $1 and workspace_id is null
+			), '[]'),
+			'tokens', json_build_object(
+				'email', coalesce((
+					select json_agg(json_build_object(
+						'purpose', purpose, 'expires_at', expires_at, 'used_at', used_at,
+						'created_at', created_at) order by created_at)
+					from user_tokens where user_id = $1
+				), '[]'),
+				'calendar', (
+					select json_build_object('created_at', created_at)
+					from calendar_tokens where user_id = $1
+				),
+				'inbox', (select inbox_token_hash is not null from users where id = $1)
+			),
+			'activity', json_build_object(
+				'comments', coalesce((
+					select json_agg(json_build_object(
+						'note_id', note_id, 'body', body,
+						'created_at', created_at, 'updated_at', updated_at) order by created_at)
+					from note_comments where user_id = $1
+				), '[]'),
+				'sessions', coalesce((
+					select json_agg(json_build_object('created_at', created_at) order by created_at)
+					from user_sessions where user_id = $1
+				), '[]'),
+				'email_changes', coalesce((
+					select json_agg(json_build_object(
+						'new_email', new_email, 'confirmed', confirmed,
+						'created_at', created_at) order by created_at)
+					from users_email_changes where user_id = $1
+				), '[]')
+			)
+		);
+	`
+	CreateAccountExportQuery string = `
+		insert into account_exports (user_id, token_hash, data, expires_at)
+		values ($1, $2, $3, now() + make_interval(secs => $4));
+	`
+	GetAccountExportQuery string = `
+		select data from account_exports where token_hash = $1 and expires_at > now();
+	`
+	DeleteExpiredAccountExportsQuery string = `
+		delete from account_exports where expires_at <= now();
+	`
+	RequestAccountDeletionQuery string = `
+		update users set deletion_requested_at = now(), updated_at = now() where id = $1;
+	`
+	CancelAccountDeletionQuery string = `
+		update users set deletion_requested_at = null, updated_at = now() where id = $1;
+	`
+	PurgeDeletedAccountsQuery string = `
+		delete from users
+		where deletion_requested_at is not null
+		and deletion_requested_at < now() - make_interval(secs => $1)
+		and not exists (select 1 from workspace_members m where m.user_id = users.id and m.role = 'owner');
+	`
+)
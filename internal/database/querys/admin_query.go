@@ -0,0 +1,35 @@
+package querys
+
+var (
+	ListAdminUsersQuery string = `
+		select u.id, u.email, u.role, u.active, u.disabled_at, u.last_login_at, u.created_at,
+			(select count(*) from notes n where n.user_id = u.id)
+		from users u
+		where u.email ilike '%' || $1 || '%'
+		order by u.created_at desc, u.id desc
+		limit $2 offset $3;
+	`
+	UpdateUserDisabledQuery string = `
+		update users set
+			disabled_at = case when $2::bool then coalesce(disabled_at, now()) end,
+			updated_at = now()
+		where id = $1;
+	`
+	AdminConfirmUserQuery string = `
+		update users set active = true, updated_at = now() where id = $1;
+	`
+	AdminDeleteUserQuery string = `
+		delete from users where id = $1;
+	`
+	CreateAdminAuditQuery string = `
+		insert into admin_audit_log (admin_id, admin_email, target_user_id, target_email, action)
+		select a.id, a.email, t.id, t.email, $3
+		from users a, users t
+		where a.id = $1 and t.id = $2;
+	`
+	ListAdminAuditQuery string = `
+		select id, admin_email, target_email, action, created_at from admin_audit_log
+		order by created_at desc, id desc
+		limit $1 offset $2;
+	`
+)
@@ -0,0 +1,18 @@
+package querys
+
+var (
+	HasCalendarTokenQuery string = `
+		select exists(select 1 from calendar_tokens where user_id = $1);
+	`
+	UpsertCalendarTokenQuery string = `
+		insert into calendar_tokens (user_id, token_hash)
+		values ($1, $2)
+		on conflict (user_id) do update set token_hash = excluded.token_hash, created_at = now();
+	`
+	DeleteCalendarTokenQuery string = `
+		delete from calendar_tokens where user_id = $1;
+	`
+	GetUserIdByCalendarTokenQuery string = `
+		select user_id from calendar_tokens where token_hash = $1;
+	`
+)
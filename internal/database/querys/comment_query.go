@@ -0,0 +1,37 @@
+package querys
+
+var (
+	ListCommentsByNoteQuery string = `
+		select c.id, c.note_id, c.user_id, u.email, coalesce(p.display_name, u.email),
+			c.body, c.created_at, c.updated_at
+		from note_comments c inner join users u on u.id = c.user_id
+			left join user_profiles p on p.user_id = u.id
+		where c.note_id = $1
+		order by c.created_at, c.id;
+	`
+	GetCommentByIdQuery string = `
+		select c.id, c.note_id, c.user_id, u.email, coalesce(p.display_name, u.email),
+			c.body, c.created_at, c.updated_at
+		from note_comments c inner join users u on u.id = c.user_id
+			left join user_profiles p on p.user_id = u.id
+		where c.id = $1 and c.note_id = $2;
+	`
+	CreateCommentQuery string = `
+		insert into note_comments (note_id, user_id, body)
+		values ($1, $2, $3)
+		returning id, created_at;
+	`
+	UpdateCommentQuery string = `
+		update note_comments set body = $1, updated_at = now() where id = $2 and user_id = $3 and note_id = $4;
+	`
+	DeleteCommentQuery string = `
+		delete from note_comments where id = $1;
+	`
+	GetNoteOwnerQuery string = `
+		select u.id, u.email from notes n inner join users u on u.id = coalesce(n.user_id, n.author_id)
+		where n.id = $1
+		and (n.workspace_id is null or exists (
+			select 1 from workspace_members m where m.workspace_id = n.workspace_id and m.user_id = u.id
+		));
+	`
+)
@@ -0,0 +1,30 @@
+package querys
+
+var (
+	CountNotesQuery string = `
+		select count(*) from notes where ` + noteScope + `;
+	`
+	CountNotesByColorQuery string = `
+		select color, count(*) from notes where ` + noteScope + `
+		group by color order by count(*) desc, color;
+	`
+	CountNotesPerWeekQuery string = `
+		select w.week::date, count(n.id)
+		from generate_series(
+			date_trunc('week', now()) - ($3::int - 1) * interval '1 week',
+			date_trunc('week', now()),
+			interval '1 week'
+		) as w(week)
+		left join notes n on ` + noteScope + ` and date_trunc('week', n.created_at) = w.week
+		group by w.week order by w.week;
+	`
+	LongestNotesQuery string = `
+		select id, title, length(coalesce(content, '')) from notes where ` + noteScope + `
+		order by 3 desc, id limit $3;
+	`
+	StaleNotesQuery string = `
+		select id, title, coalesce(updated_at, created_at) from notes
+		where ` + noteScope + ` and coalesce(updated_at, created_at) < now() - $3::int * interval '1 day'
+		order by 3, id limit $4;
+	`
+)
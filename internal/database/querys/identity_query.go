@@ -0,0 +1,32 @@
+package querys
+
+var (
+	FindUserByIdentityQuery string = `
+		select u.id, u.email, u.password, u.active, u.deletion_requested_at, u.totp_secret, u.totp_enabled, u.role, u.disabled_at
+		from user_identities i inner join users u on u.id = i.user_id
+		where i.issuer = $1 and i.subject = $2;
+	`
+	UpdateIdentityEmailQuery string = `
+		update user_identities set email = $1, updated_at = now() where issuer = $2 and subject = $3;
+	`
+	FindUserByEmailForUpdateQuery string = `
+		select id, email, password, active, deletion_requested_at, totp_secret, totp_enabled, role, disabled_at
+		from users where email = $1
+		for update;
+	`
+	ActivateUserQuery string = `
+		update users set active = true, password = $2, updated_at = now() where id = $1 and active = false;
+	`
+	CreateActiveUserQuery string = `
+		insert into users (email, password, active)
+		values ($1, $2, true)
+		returning id;
+	`
+	CreateIdentityQuery string = `
+		insert into user_identities (user_id, issuer, subject, email)
+		values ($1, $2, $3, $4);
+	`
+	IsIdentityLinkedQuery string = `
+		select exists(select 1 from user_identities where user_id = $1 and issuer = $2 and subject = $3);
+	`
+)
@@ -0,0 +1,85 @@
+package querys
+
+var (
+	GetLockoutQuery string = `
+		select failed_attempts, locked_until,
+			greatest(coalesce(extract(epoch from locked_until - now()), 0), 0)::double precision
+		from user_lockouts
+		where user_id = $1;
+	`
+	IncrementFailedAttemptsQuery string = `
+		insert into user_lockouts (user_id, failed_attempts)
+		values ($1, 1)
+		on conflict (user_id) do update set
+			failed_attempts = user_lockouts.failed_attempts + 1,
+			updated_at = now()
+		returning failed_attempts;
+	`
+	LockUserQuery string = `
+		update user_lockouts set
+			failed_attempts = 0,
+			lockout_count = lockout_count + 1,
+			locked_until = now() + make_interval(secs => least($2::double precision, $3::double precision * power(2, lockout_count))),
+			unlock_token_hash = $4,
+			unlock_expires_at = now() + make_interval(secs => $2::double precision),
+			updated_at = now()
+		where user_id = $1
+		returning extract(epoch from locked_until - now())::double precision;
+	`
+	CreateFailedLoginQuery string = `
+		insert into user_failed_logins (user_id, ip, user_agent) values ($1, $2, $3);
+	`
+	ResetLockoutQuery string = `
+		update user_lockouts set
+			failed_attempts = 0,
+			lockout_count = 0,
+			locked_until = null,
+			unlock_token_hash = null,
+			unlock_expires_at = null,
+			updated_at = now()
+		where user_id = $1;
+	`
+	UnlockUserByTokenQuery string = `
+		update user_lockouts set
+			failed_attempts = 0,
+			lockout_count = 0,
+			locked_until = null,
+			unlock_token_hash = null,
+			unlock_expires_at = null,
+			updated_at = now()
+		where unlock_token_hash = $1
+		and unlock_expires_at > now()
+		returning user_id;
+	`
+	ListFailedLoginsQuery string = `
+		select id, ip, user_agent, created_at
+		from user_failed_logins
+		where user_id = $1
+		order by created_at desc
+		limit $2;
+	`
+	DeleteOldFailedLoginsQuery string = `
+		delete from user_failed_logins where created_at < now() - make_interval(secs => $1);
+	`
+	TouchDeviceQuery string = `
+		update user_devices set ip = $3, user_agent = $4, last_seen_at = now()
+		where user_id = $1 and device_hash = $2;
+	`
+	HasDevicesQuery string = `
+		select exists (select 1 from user_devices where user_id = $1);
+	`
+	CreateDeviceQuery string = `
+		insert into user_devices (user_id, device_hash, ip, user_agent)
+		values ($1, $2, $3, $4)
+		on conflict (user_id, device_hash) do update set
+			ip = excluded.ip,
+			user_agent = excluded.user_agent,
+			last_seen_at = now();
+	`
+	ListDevicesQuery string = `
+		select id, ip, user_agent, device_hash = $2, created_at, last_seen_at
+		from user_devices
+		where user_id = $1
+		order by last_seen_at desc;
+	`
+)
@@ -1,21 +1,91 @@
 package querys
 
+// noteScope limits a query to the personal notes of user $1 or, when $2 is
+// not zero, to the notes of workspace $2 as long as user $1 is a member.
+const noteScope string = `(case when $2 = 0 then workspace_id is null and user_id = $1
+	else workspace_id = $2 and exists (
+		select 1 from workspace_members m where m.workspace_id = $2 and m.user_id = $1
+	) end)`
+
+// noteManager further limits noteScope to the notes user $1 may manage: the
+// personal ones, the ones they wrote, and every note of the workspaces they
+// own or administer. It must match models.NoteAccess.CanManage.
+const noteManager string = noteScope + ` and (workspace_id is null or author_id = $1 or exists (
+		select 1 from workspace_members m
+		where m.workspace_id = notes.workspace_id and m.user_id = $1 and m.role in ('owner', 'admin')
+	))`
+
 var (
 	ListNoteQuery string = `
-		select id, title, content, color, created_at, updated_at from notes where user_id = $1;
+		select id, title, content, color, due_at, locked, created_at, updated_at from notes where ` + noteScope + `
+		order by %s;
+	`
+	ListNoteOrderBy = map[string]string{
+		"created": "created_at, id",
+		"recent":  "coalesce(updated_at, created_at) desc, id desc",
+		"title":   "lower(title), id",
+		"custom":  "position, id",
+	}
+	ListNoteWithDueDateQuery string = `
+		select id, title, content, color, due_at, locked, created_at, updated_at from notes
+		where ` + noteScope + ` and due_at is not null
+		order by due_at;
 	`
 	GetByIdNoteQuery string = `
-		select id, title, content, color, created_at, updated_at from notes where id = $1 and user_id = $2;
+		select id, title, content, color, due_at, locked, created_at, updated_at from notes
+		where ` + noteScope + ` and id = $3;
+	`
+	GetNoteAccessQuery string = `
+		select coalesce((
+			select m.role from workspace_members m where m.workspace_id = notes.workspace_id and m.user_id = $1
+		), ''), coalesce(author_id = $1, false)
+		from notes where ` + noteScope + ` and id = $3;
 	`
 	CreateNoteQuery string = `
-		INSERT INTO notes (title, content, color, user_id)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO notes (user_id, workspace_id, author_id, title, content, color, due_at, position)
+		VALUES (case when $2::bigint = 0 then $1::bigint end, nullif($2::bigint, 0), $1, $3, $4, $5, $6, $7)
 		RETURNING id, created_at;
 	`
+	LockUserNotesQuery string = `
+		select pg_advisory_xact_lock($1);
+	`
+	// Workspaces lock on their negated id, apart from the ids of users.
+	LockWorkspaceNotesQuery string = `
+		select pg_advisory_xact_lock(-$1::bigint);
+	`
+	LastNotePositionQuery string = `
+		select coalesce(max(position), '') from notes where ` + noteScope + `;
+	`
+	GetNotePositionQuery string = `
+		select position from notes where ` + noteScope + ` and id = $3;
+	`
+	NextNotePositionQuery string = `
+		select position from notes where ` + noteScope + ` and position > $3 and id <> $4
+		order by position limit 1;
+	`
+	PreviousNotePositionQuery string = `
+		select position from notes where ` + noteScope + ` and position < $3 and id <> $4
+		order by position desc limit 1;
+	`
+	UpdateNotePositionQuery string = `
+		update notes set position = $3 where ` + noteScope + ` and id = $4;
+	`
 	UpdateNoteQuery string = `
-		update notes set title = $1, content = coalesce($2, content), color = $3, updated_at = $4 where id = $5 and user_id = $6;
+		update notes set title = $3, content = coalesce($4, content), color = $5, due_at = $6, updated_at = $7
+		where ` + noteScope + ` and id = $8;
 	`
 	DeleteNoteQuery string = `
-		delete from notes where id = $1 and user_id = $2;
+		delete from notes where ` + noteManager + ` and id = $3;
+	`
+	GetNoteLockQuery string = `
+		select lock_password, lock_salt from notes where ` + noteScope + ` and id = $3 and locked = true;
+	`
+	LockNoteQuery string = `
+		update notes set locked = true, lock_password = $3, lock_salt = $4, content = $5, updated_at = now()
+		where ` + noteManager + ` and id = $6 and locked = false;
+	`
+	RemoveNoteLockQuery string = `
+		update notes set locked = false, lock_password = null, lock_salt = null, content = $3, updated_at = now()
+		where ` + noteManager + ` and id = $4 and locked = true;
 	`
 )
@@ -0,0 +1,34 @@
+package querys
+
+var (
+	GetProfileQuery string = `
+		select u.id, u.email, p.display_name,
+			coalesce(p.locale, $2), coalesce(p.timezone, $3),
+			exists (select 1 from user_avatars a where a.user_id = u.id),
+			p.updated_at
+		from users u left join user_profiles p on p.user_id = u.id
+		where u.id = $1;
+	`
+	UpsertProfileQuery string = `
+		insert into user_profiles (user_id, display_name, locale, timezone)
+		values ($1, nullif($2, ''), $3, $4)
+		on conflict (user_id) do update set
+			display_name = excluded.display_name,
+			locale = excluded.locale,
+			timezone = excluded.timezone,
+			updated_at = now();
+	`
+	TouchProfileQuery string = `
+		insert into user_profiles (user_id) values ($1)
+		on conflict (user_id) do update set updated_at = now();
+	`
+	DeleteAvatarQuery string = `
+		delete from user_avatars where user_id = $1;
+	`
+	CreateAvatarQuery string = `
+		insert into user_avatars (user_id, size, image) values ($1, $2, $3);
+	`
+	GetAvatarQuery string = `
+		select image from user_avatars where user_id = $1 and size = $2;
+	`
+)
@@ -0,0 +1,23 @@
+package querys
+
+// quotaNotes are the notes counted in the quota of user $1: their personal
+// notes and the notes of the workspaces they own.
+const quotaNotes string = `(user_id = $1 or workspace_id in (
+	select m.workspace_id from workspace_members m where m.user_id = $1 and m.role = 'owner'
+))`
+
+var (
+	QuotaUsageQuery string = `
+		select
+			(select count(*) from notes where ` + quotaNotes + `),
+			(select coalesce(sum(octet_length(title) + octet_length(coalesce(content, ''))), 0) from notes where ` + quotaNotes + `),
+			q.max_notes, q.max_note_size, q.max_storage
+		from (select 1) d left join user_quotas q on q.user_id = $1;
+	`
+	// The owner of workspace $2, as long as user $1 is a member of it.
+	WorkspaceQuotaUserQuery string = `
+		select o.user_id from workspace_members o
+		where o.workspace_id = $2 and o.role = 'owner'
+		and exists (select 1 from workspace_members m where m.workspace_id = $2 and m.user_id = $1);
+	`
+)
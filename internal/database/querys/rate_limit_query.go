@@ -0,0 +1,21 @@
+package querys
+
+var (
+	CreateRateLimitBucketQuery string = `
+		insert into rate_limit_buckets (key, tokens, updated_at)
+		values ($1, $2, now())
+		on conflict (key) do nothing;
+	`
+	GetRateLimitBucketQuery string = `
+		select tokens, extract(epoch from now() - updated_at)::double precision
+		from rate_limit_buckets
+		where key = $1
+		for update;
+	`
+	UpdateRateLimitBucketQuery string = `
+		update rate_limit_buckets set tokens = $1, updated_at = now() where key = $2;
+	`
+	DeleteIdleRateLimitBucketsQuery string = `
+		delete from rate_limit_buckets where updated_at < now() - make_interval(secs => $1);
+	`
+)
@@ -0,0 +1,49 @@
+package querys
+
+var (
+	CreateUserSessionQuery string = `
+		insert into user_sessions (token, user_id, ip, user_agent)
+		values ($1, $2, $3, $4)
+		on conflict (token) do update set
+			user_id = excluded.user_id,
+			ip = excluded.ip,
+			user_agent = excluded.user_agent,
+			last_seen_at = now();
+	`
+	UpdateLastLoginQuery string = `
+		update users set last_login_at = now() where id = $1;
+	`
+	TouchUserSessionQuery string = `
+		update user_sessions set ip = $2, last_seen_at = now() where token = $1;
+	`
+	ListUserSessionsQuery string = `
+		select us.id, us.ip, us.user_agent, us.token = $2, us.created_at, us.last_seen_at
+		from user_sessions us
+		inner join sessions s on s.token = us.token
+		where us.user_id = $1
+		and s.expiry > current_timestamp
+		order by us.last_seen_at desc;
+	`
+	DeleteExpiredUserSessionsQuery string = `
+		delete from user_sessions us
+		where us.created_at < now() - make_interval(secs => $1)
+		and not exists (select 1 from sessions s where s.token = us.token);
+	`
+	DeleteUserSessionQuery string = `
+		delete from user_sessions where token = $1;
+	`
+	DeleteSessionByIdQuery string = `
+		delete from sessions
+		where token = (select token from user_sessions where id = $1 and user_id = $2 and token <> $3);
+	`
+	DeleteUserSessionByIdQuery string = `
+		delete from user_sessions where id = $1 and user_id = $2 and token <> $3;
+	`
+	DeleteOtherSessionsQuery string = `
+		delete from sessions
+		where token in (select token from user_sessions where user_id = $1 and token <> $2);
+	`
+	DeleteOtherUserSessionsQuery string = `
+		delete from user_sessions where user_id = $1 and token <> $2;
+	`
+)
@@ -0,0 +1,28 @@
+package querys
+
+var (
+	EnableTwoFactorQuery string = `
+		update users set totp_secret = $1, totp_enabled = true, totp_last_step = $2, updated_at = now()
+		where id = $3;
+	`
+	DisableTwoFactorQuery string = `
+		update users set totp_secret = null, totp_enabled = false, totp_last_step = 0, updated_at = now()
+		where id = $1;
+	`
+	UseTOTPStepQuery string = `
+		update users set totp_last_step = $1 where id = $2 and totp_last_step < $1;
+	`
+	DeleteRecoveryCodesQuery string = `
+		delete from user_recovery_codes where user_id = $1;
+	`
+	CreateRecoveryCodeQuery string = `
+		insert into user_recovery_codes (user_id, code_hash) values ($1, $2);
+	`
+	UseRecoveryCodeQuery string = `
+		update user_recovery_codes set used_at = now()
+		where user_id = $1 and code_hash = $2 and used_at is null;
+	`
+	CountRecoveryCodesQuery string = `
+		select count(*) from user_recovery_codes where user_id = $1 and used_at is null;
+	`
+)
@@ -4,32 +4,77 @@ var (
 	CreateUserQuery string = `
 		insert into users (email, password)
 		values ($1, $2)
+		on conflict (email) do update set updated_at = now()
+		where users.active = false
 		returning id, created_at;
 	`
-	GetUserJoinUserTokenQuery string = `
-		select u.id u_id, t.id t_id from users u inner join users_confirmation_tokens t
-		on u.id = t.user_id
-		where u.active = false
-		and t.confirmed = false
-		and t.token = $1;
+	TouchUnconfirmedUserQuery string = `
+		update users set updated_at = now() where email = $1 and active = false
+		returning id;
+	`
+	DeleteUnconfirmedUsersQuery string = `
+		delete from users
+		where active = false
+		and coalesce(updated_at, created_at) < now() - make_interval(secs => $1);
 	`
 	UpdateUserConfirmedQuery string = `
-		update users set active = true, updated_at = now() where id = $1;
+		update users set active = true, password = coalesce($2, password), updated_at = now() where id = $1;
 	`
 	FindByEmailQuery string = `
-		select id, email, password, active from users where email = $1;
-	`
-	SelectPasswordByTokenQuery string = `
-		select u.id u_id, u.email, t.id t_id from users u inner join users_confirmation_tokens t
-		on u.id = t.user_id
-		where t.confirmed = false
-		and t.token = $1;
+		select id, email, password, active, deletion_requested_at, totp_secret, totp_enabled, role, disabled_at from users where email = $1;
 	`
-	UpdatePasswordByTokenQuery string = `
-		update users_confirmation_tokens set confirmed = true, updated_at = now()
-		where id = $1;
+	GetUserEmailQuery string = `
+		select email from users where id = $1;
 	`
 	UpdatePasswordQuery string = `
 		update users set password = $1, updated_at = now() where id = $2;
 	`
+	RehashPasswordQuery string = `
+		update users set password = $3 where id = $1 and password = $2;
+	`
+	HasInboxTokenQuery string = `
+		select inbox_token_hash is not null from users where id = $1;
+	`
+	UpdateInboxTokenQuery string = `
+		update users set inbox_token_hash = $1, updated_at = now() where id = $2;
+	`
+	GetUserIdByInboxTokenQuery string = `
+		select id from users
+		where inbox_token_hash = $1 and active = true
+		and disabled_at is null and deletion_requested_at is null;
+	`
+	FindUserByIdQuery string = `
+		select id, email, password, active, deletion_requested_at, totp_secret, totp_enabled, role, disabled_at from users where id = $1;
+	`
+	ExistsUserByEmailQuery string = `
+		select exists(select 1 from users where email = $1);
+	`
+	DeletePendingEmailChangesQuery string = `
+		delete from users_email_changes where user_id = $1 and confirmed = false;
+	`
+	CreateEmailChangeQuery string = `
+		insert into users_email_changes (user_id, new_email, token_hash)
+		values ($1, $2, $3);
+	`
+	GetPendingEmailChangeQuery string = `
+		select new_email from users_email_changes
+		where user_id = $1
+		and confirmed = false
+		and created_at > now() - make_interval(secs => $2)
+		order by created_at desc
+		limit 1;
+	`
+	GetEmailChangeByTokenQuery string = `
+		select id, user_id, new_email from users_email_changes
+		where token_hash = $1
+		and confirmed = false
+		and created_at > now() - make_interval(secs => $2)
+		for update;
+	`
+	UpdateEmailChangeConfirmedQuery string = `
+		update users_email_changes set confirmed = true, updated_at = now() where id = $1;
+	`
+	UpdateUserEmailQuery string = `
+		update users set email = $1, updated_at = now() where id = $2;
+	`
 )
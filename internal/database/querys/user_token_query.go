@@ -1,16 +1,44 @@
 package querys
 
 var (
-	CreateTokenQuery string = `
-		insert into users_confirmation_tokens (user_id, token)
-		values ($1, $2) 
-		returning id, created_at;
+	CreateUserTokenQuery string = `
+		insert into user_tokens (user_id, purpose, token_hash, expires_at, device_hash)
+		values ($1, $2, $3, now() + make_interval(secs => $4), nullif($5, ''));
 	`
-	UpdateTokenConfirmedQuery string = `
-		update users_confirmation_tokens set confirmed = true, updated_at = now() where id = $1;
+	CreateConfirmationTokenQuery string = `
+		insert into user_tokens (user_id, purpose, token_hash, expires_at, password)
+		values ($1, 'confirmation', $2, now() + make_interval(secs => $3), $4);
 	`
-	GetUserConfirmationByTokenQuery string = `
-		select id, user_id, token, confirmed, created_at, updated_at from users_confirmation_tokens
-		where token = $1;
+	GetPendingConfirmationPasswordQuery string = `
+		select password from user_tokens
+		where user_id = $1
+		and purpose = 'confirmation'
+		and used_at is null
+		order by created_at desc
+		limit 1;
+	`
+	GetUserTokenPasswordQuery string = `
+		select password from user_tokens where token_hash = $1;
+	`
+	GetUserTokenQuery string = `
+		select id, user_id, purpose, device_hash, expires_at, used_at, created_at from user_tokens
+		where token_hash = $1
+		and purpose = $2
+		and used_at is null
+		and expires_at > now();
+	`
+	UseUserTokenQuery string = `
+		update user_tokens set used_at = now()
+		where token_hash = $1
+		and purpose = $2
+		and used_at is null
+		and expires_at > now()
+		returning user_id;
+	`
+	DeleteAllUnusedUserTokensQuery string = `
+		delete from user_tokens where user_id = $1 and used_at is null;
+	`
+	DeleteUnusedUserTokensQuery string = `
+		delete from user_tokens where user_id = $1 and purpose = $2 and used_at is null;
 	`
 )
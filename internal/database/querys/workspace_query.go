@@ -0,0 +1,90 @@
+package querys
+
+var (
+	OwnsWorkspaceQuery string = `
+		select exists (select 1 from workspace_members where user_id = $1 and role = 'owner');
+	`
+	ListUserWorkspacesQuery string = `
+		select w.id, w.name, m.role, w.created_at
+		from workspaces w inner join workspace_members m on m.workspace_id = w.id
+		where m.user_id = $1
+		order by lower(w.name), w.id;
+	`
+	GetUserWorkspaceQuery string = `
+		select w.id, w.name, m.role, w.created_at
+		from workspaces w inner join workspace_members m on m.workspace_id = w.id
+		where m.user_id = $1 and w.id = $2;
+	`
+	CreateWorkspaceQuery string = `
+		insert into workspaces (name) values ($1) returning id, created_at;
+	`
+	CreateWorkspaceMemberQuery string = `
+		insert into workspace_members (workspace_id, user_id, role) values ($1, $2, $3)
+		on conflict (workspace_id, user_id) do nothing;
+	`
+	DeleteWorkspaceQuery string = `
+		delete from workspaces where id = $1;
+	`
+	ListWorkspaceMembersQuery string = `
+		select u.id, u.email, coalesce(p.display_name, u.email), m.role, m.created_at
+		from workspace_members m inner join users u on u.id = m.user_id
+			left join user_profiles p on p.user_id = u.id
+		where m.workspace_id = $1
+		order by m.created_at, u.id;
+	`
+	UpdateWorkspaceMemberRoleQuery string = `
+		update workspace_members set role = $3
+		where workspace_id = $1 and user_id = $2 and role <> 'owner';
+	`
+	DeleteWorkspaceMemberQuery string = `
+		delete from workspace_members
+		where workspace_id = $1 and user_id = $2 and role <> 'owner';
+	`
+	IsWorkspaceEmailMemberQuery string = `
+		select exists (
+			select 1 from workspace_members m inner join users u on u.id = m.user_id
+			where m.workspace_id = $1 and lower(u.email) = lower($2)
+		);
+	`
+	DeletePendingWorkspaceInvitationsQuery string = `
+		delete from user_tokens where used_at is null and id in (
+			select token_id from workspace_invitations
+			where workspace_id = $1 and lower(email) = lower($2)
+		);
+	`
+	CreateWorkspaceInvitationQuery string = `
+		with token as (
+			insert into user_tokens (user_id, purpose, token_hash, expires_at)
+			values ($1, 'workspace_invitation', $2, now() + make_interval(secs => $3))
+			returning id
+		)
+		insert into workspace_invitations (token_id, workspace_id, email, role)
+		select id, $4, $5, $6 from token;
+	`
+	ListWorkspaceInvitationsQuery string = `
+		select i.token_id, i.workspace_id, w.name, i.email, i.role, u.email, t.expires_at
+		from workspace_invitations i
+			inner join user_tokens t on t.id = i.token_id
+			inner join workspaces w on w.id = i.workspace_id
+			inner join users u on u.id = t.user_id
+		where i.workspace_id = $1 and t.used_at is null and t.expires_at > now()
+		order by t.created_at;
+	`
+	GetWorkspaceInvitationQuery string = `
+		select i.token_id, i.workspace_id, w.name, i.email, i.role, u.email, t.expires_at
+		from workspace_invitations i
+			inner join user_tokens t on t.id = i.token_id
+			inner join workspaces w on w.id = i.workspace_id
+			inner join users u on u.id = t.user_id
+		where t.token_hash = $1 and t.used_at is null and t.expires_at > now();
+	`
+	GetUsedWorkspaceInvitationQuery string = `
+		select i.workspace_id, i.email, i.role
+		from workspace_invitations i inner join user_tokens t on t.id = i.token_id
+		where t.token_hash = $1;
+	`
+	DeleteWorkspaceInvitationQuery string = `
+		delete from user_tokens
+		where id = (select token_id from workspace_invitations where workspace_id = $1 and token_id = $2);
+	`
+)
@@ -0,0 +1,90 @@
+package dtos
+
+import (
+	"go_pro/internal/models"
+	"go_pro/internal/validations"
+	"html/template"
+	"time"
+)
+
+type AccountResponse struct {
+	Email             string
+	PendingEmail      string
+	NewEmail          string
+	TwoFactorEnabled  bool
+	RecoveryCodesLeft int
+	HasPassword       bool
+	Reauthenticated   bool
+	SSOProvider       string
+	AccessTokens      []AccessTokenResponse
+	NewAccessToken    string
+	validations.FormValidator
+
+	AccessTokenExpiryOptions []AccessTokenExpiryOption
+}
+
+type AccessTokenResponse struct {
+	Id         int
+	Name       string
+	Scope      string
+	ExpiresAt  string
+	LastUsedAt string
+	Expired    bool
+}
+
+type AccessTokenExpiryOption struct {
+	Days  int
+	Label string
+}
+
+var AccessTokenExpiryOptions = []AccessTokenExpiryOption{
+	{Days: 7, Label: "7 dias"},
+	{Days: 30, Label: "30 dias"},
+	{Days: 90, Label: "90 dias"},
+	{Days: 365, Label: "1 ano"},
+	{Days: 0, Label: "Nunca"},
+}
+
+func IsValidAccessTokenExpiry(days int) bool {
+	for _, option := range AccessTokenExpiryOptions {
+		if option.Days == days {
+			return true
+		}
+	}
+
+	return false
+}
+
+func NewAccessTokenResponse(tokens []models.AccessToken) []AccessTokenResponse {
+	var list []AccessTokenResponse
+
+	for _, token := range tokens {
+		response := AccessTokenResponse{
+			Id:         int(token.Id.Int.Int64()),
+			Name:       token.Name.String,
+			Scope:      token.Scope.String,
+			ExpiresAt:  "nunca",
+			LastUsedAt: "nunca",
+		}
+
+		if token.ExpiresAt.Valid {
+			response.ExpiresAt = token.ExpiresAt.Time.Format("02/01/2006")
+			response.Expired = token.ExpiresAt.Time.Before(time.Now())
+		}
+
+		if token.LastUsedAt.Valid {
+			response.LastUsedAt = token.LastUsedAt.Time.Format("02/01/2006 15:04")
+		}
+
+		list = append(list, response)
+	}
+
+	return list
+}
+
+type TwoFactorSetupResponse struct {
+	QRCode template.URL
+	Secret string
+	Codes  []string
+	validations.FormValidator
+}
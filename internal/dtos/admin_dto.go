@@ -0,0 +1,109 @@
+package dtos
+
+import "go_pro/internal/models"
+
+var adminActionLabels = map[string]string{
+	models.AdminActionDisable:       "Desativou",
+	models.AdminActionEnable:        "Reativou",
+	models.AdminActionConfirm:       "Confirmou o cadastro de",
+	models.AdminActionPasswordReset: "Enviou redefinição de senha para",
+	models.AdminActionDelete:        "Excluiu",
+}
+
+// nextPage returns the number of the page after page, or zero when it is the
+// last one.
+func nextPage(page int, hasNext bool) int {
+	if !hasNext {
+		return 0
+	}
+
+	return page + 1
+}
+
+type AdminUserResponse struct {
+	Id          int
+	Email       string
+	Admin       bool
+	Confirmed   bool
+	Disabled    bool
+	Self        bool
+	Notes       int64
+	LastLoginAt string
+	CreatedAt   string
+}
+
+type AdminUsersResponse struct {
+	Search   string
+	Users    []AdminUserResponse
+	PrevPage int
+	NextPage int
+	Flash    string
+}
+
+func NewAdminUsersResponse(users []models.AdminUser, currentUserId int, search string, page int, hasNext bool) AdminUsersResponse {
+	response := AdminUsersResponse{
+		Search:   search,
+		PrevPage: page - 1,
+		NextPage: nextPage(page, hasNext),
+	}
+
+	for _, user := range users {
+		id := int(user.Id.Int.Int64())
+		lastLoginAt := "Nunca"
+
+		if user.LastLoginAt.Valid {
+			lastLoginAt = user.LastLoginAt.Time.Format("02/01/2006 15:04")
+		}
+
+		response.Users = append(response.Users, AdminUserResponse{
+			Id:          id,
+			Email:       user.Email.String,
+			Admin:       user.Role.String == models.RoleAdmin,
+			Confirmed:   user.Active.Bool,
+			Disabled:    user.DisabledAt.Valid,
+			Self:        id == currentUserId,
+			Notes:       user.Notes.Int64,
+			LastLoginAt: lastLoginAt,
+			CreatedAt:   user.CreatedAt.Time.Format("02/01/2006 15:04"),
+		})
+	}
+
+	return response
+}
+
+type AdminAuditEntryResponse struct {
+	Admin     string
+	Action    string
+	Target    string
+	CreatedAt string
+}
+
+type AdminAuditResponse struct {
+	Entries  []AdminAuditEntryResponse
+	PrevPage int
+	NextPage int
+}
+
+func NewAdminAuditResponse(entries []models.AdminAuditEntry, page int, hasNext bool) AdminAuditResponse {
+	response := AdminAuditResponse{
+		PrevPage: page - 1,
+		NextPage: nextPage(page, hasNext),
+	}
+
+	for _, entry := range entries {
+		action, ok := adminActionLabels[entry.Action.String]
+
+		if !ok {
+			action = entry.Action.String
+		}
+
+		response.Entries = append(response.Entries, AdminAuditEntryResponse{
+			Admin:     entry.AdminEmail.String,
+			Action:    action,
+			Target:    entry.TargetEmail.String,
+			CreatedAt: entry.CreatedAt.Time.Format("02/01/2006 15:04"),
+		})
+	}
+
+	return response
+}
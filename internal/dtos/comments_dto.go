@@ -0,0 +1,49 @@
+package dtos
+
+import (
+	"go_pro/internal/models"
+	"go_pro/internal/validations"
+	"time"
+)
+
+type CommentResponse struct {
+	Id          int
+	AuthorEmail string
+	AuthorName  string
+	Body        string
+	CreatedAt   time.Time
+	Edited      bool
+	CanEdit     bool
+	CanDelete   bool
+}
+
+type NoteViewResponse struct {
+	NoteResponse
+	Comments    []CommentResponse
+	CommentBody string
+	CanManage   bool
+	validations.FormValidator
+}
+
+// NewNoteViewResponse builds the note page. Comment authors can edit and
+// delete their comments, and the note owner can delete any of them.
+func NewNoteViewResponse(note *models.Note, comments []models.Comment, userId int, isOwner bool) (res NoteViewResponse) {
+	res.NoteResponse = NewNoteResponseFromNote(note)
+
+	for _, comment := range comments {
+		isAuthor := int(comment.UserId.Int.Int64()) == userId
+
+		res.Comments = append(res.Comments, CommentResponse{
+			Id:          int(comment.Id.Int.Int64()),
+			AuthorEmail: comment.AuthorEmail.String,
+			AuthorName:  comment.AuthorName.String,
+			Body:        comment.Body.String,
+			CreatedAt:   comment.CreatedAt.Time,
+			Edited:      comment.UpdatedAt.Valid,
+			CanEdit:     isAuthor,
+			CanDelete:   isAuthor || isOwner,
+		})
+	}
+
+	return
+}
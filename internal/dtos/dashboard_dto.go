@@ -0,0 +1,117 @@
+package dtos
+
+import (
+	"go_pro/internal/models"
+)
+
+const (
+	chartWidth    = 600
+	chartHeight   = 200
+	chartLabelGap = 20
+)
+
+type ColorStatResponse struct {
+	Color   string
+	Total   int
+	Percent int
+}
+
+type WeekBarResponse struct {
+	Label  string
+	Total  int
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+type LongestNoteResponse struct {
+	Id     int
+	Title  string
+	Length int
+}
+
+type StaleNoteResponse struct {
+	Id          int
+	Title       string
+	LastTouched string
+}
+
+type DashboardResponse struct {
+	Total       int
+	ByColor     []ColorStatResponse
+	Weeks       []WeekBarResponse
+	ChartWidth  int
+	ChartHeight int
+	Longest     []LongestNoteResponse
+	Stale       []StaleNoteResponse
+}
+
+func NewDashboardResponse(dashboard *models.NoteDashboard) (res DashboardResponse) {
+	res.Total = int(dashboard.Total.Int64)
+	res.ChartWidth = chartWidth
+	res.ChartHeight = chartHeight + chartLabelGap
+
+	for _, stat := range dashboard.ByColor {
+		color := ColorStatResponse{Color: stat.Color.String, Total: int(stat.Total.Int64)}
+
+		if res.Total > 0 {
+			color.Percent = color.Total * 100 / res.Total
+		}
+
+		res.ByColor = append(res.ByColor, color)
+	}
+
+	res.Weeks = newWeekBars(dashboard.PerWeek)
+
+	for _, stat := range dashboard.Longest {
+		res.Longest = append(res.Longest, LongestNoteResponse{
+			Id:     int(stat.Id.Int.Int64()),
+			Title:  stat.Title.String,
+			Length: int(stat.Length.Int64),
+		})
+	}
+
+	for _, stat := range dashboard.Stale {
+		res.Stale = append(res.Stale, StaleNoteResponse{
+			Id:          int(stat.Id.Int.Int64()),
+			Title:       stat.Title.String,
+			LastTouched: stat.LastTouched.Time.Format("02/01/2006"),
+		})
+	}
+
+	return
+}
+
+// newWeekBars turns the weekly counts into bar coordinates for the SVG chart,
+// scaled so the busiest week fills the chart height.
+func newWeekBars(weeks []models.NoteWeekStat) (bars []WeekBarResponse) {
+	if len(weeks) == 0 {
+		return
+	}
+
+	max := int64(1)
+
+	for _, week := range weeks {
+		if week.Total.Int64 > max {
+			max = week.Total.Int64
+		}
+	}
+
+	slot := chartWidth / len(weeks)
+
+	for i, week := range weeks {
+		height := int(week.Total.Int64 * chartHeight / max)
+
+		bars = append(bars, WeekBarResponse{
+			Label:  week.Week.Time.Format("02/01"),
+			Total:  int(week.Total.Int64),
+			X:      i*slot + slot/8,
+			Y:      chartHeight - height,
+			Width:  slot - slot/4,
+			Height: height,
+		})
+	}
+
+	return
+}
@@ -0,0 +1,15 @@
+package dtos
+
+import "go_pro/internal/validations"
+
+// IntegrationsResponse is the data of the integrations page. CalendarURL and
+// InboxAddress are only set right after a new address is generated: the
+// tokens are stored hashed and cannot be shown again.
+type IntegrationsResponse struct {
+	CalendarActive bool
+	CalendarURL    string
+	InboxEnabled   bool
+	InboxActive    bool
+	InboxAddress   string
+	validations.FormValidator
+}
@@ -5,13 +5,39 @@ import (
 	"go_pro/internal/models"
 	"go_pro/internal/validations"
 	"html/template"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+const DueAtLayout = "2006-01-02T15:04"
+
 type NoteResponse struct {
-	Id      int
-	Title   string
-	Content string
-	Color   string
+	Id        int
+	Title     string
+	Content   string
+	Color     string
+	DueAt     time.Time
+	UpdatedAt time.Time
+	Locked    bool
+}
+
+type NoteSortOption struct {
+	Value string
+	Label string
+}
+
+type NoteListResponse struct {
+	Notes []NoteResponse
+	Sort  string
+	Sorts []NoteSortOption
+}
+
+var noteSorts = []NoteSortOption{
+	{Value: models.NoteSortCreated, Label: "Mais antigas"},
+	{Value: models.NoteSortRecent, Label: "Alteradas recentemente"},
+	{Value: models.NoteSortTitle, Label: "Título"},
+	{Value: models.NoteSortCustom, Label: "Ordem personalizada"},
 }
 
 type NoteRequest struct {
@@ -19,6 +45,8 @@ type NoteRequest struct {
 	Title     string
 	Content   string
 	Color     string
+	DueAt     string
+	Locked    bool
 	Colors    []string
 	CSRFField template.HTML
 	validations.FormValidator
@@ -34,6 +62,11 @@ func NewNoteRequest(note *models.Note) (req NoteRequest) {
 		req.Title = note.Title.String
 		req.Color = note.Color.String
 		req.Content = note.Content.String
+		req.Locked = note.Locked.Bool
+
+		if note.DueAt.Valid {
+			req.DueAt = note.DueAt.Time.Format(DueAtLayout)
+		}
 	} else {
 		req.Color = "color1"
 	}
@@ -46,10 +79,37 @@ func NewNoteResponseFromNote(note *models.Note) (res NoteResponse) {
 	res.Title = note.Title.String
 	res.Content = note.Content.String
 	res.Color = note.Color.String
+	res.Locked = note.Locked.Bool
+
+	// the content of a locked note is encrypted, only the title is shown
+	if res.Locked {
+		res.Content = ""
+	}
+
+	if note.DueAt.Valid {
+		res.DueAt = note.DueAt.Time
+	}
+
+	if note.UpdatedAt.Valid {
+		res.UpdatedAt = note.UpdatedAt.Time
+	} else {
+		res.UpdatedAt = note.CreatedAt.Time
+	}
 
 	return
 }
 
+// Excerpt returns the first size characters of the content on a single line.
+func (nr NoteResponse) Excerpt(size int) string {
+	excerpt := strings.Join(strings.Fields(nr.Content), " ")
+
+	if utf8.RuneCountInString(excerpt) <= size {
+		return excerpt
+	}
+
+	return string([]rune(excerpt)[:size]) + "..."
+}
+
 func NewNoteResponseFromNoteList(notes []models.Note) (res []NoteResponse) {
 	for _, note := range notes {
 		res = append(res, NewNoteResponseFromNote(&note))
@@ -57,3 +117,21 @@ func NewNoteResponseFromNoteList(notes []models.Note) (res []NoteResponse) {
 
 	return
 }
+
+func IsValidNoteSort(sort string) bool {
+	for _, option := range noteSorts {
+		if option.Value == sort {
+			return true
+		}
+	}
+
+	return false
+}
+
+func NewNoteListResponse(notes []models.Note, sort string) (res NoteListResponse) {
+	res.Notes = NewNoteResponseFromNoteList(notes)
+	res.Sort = sort
+	res.Sorts = noteSorts
+
+	return
+}
@@ -0,0 +1,67 @@
+package dtos
+
+import (
+	"go_pro/internal/i18n"
+	"go_pro/internal/models"
+	"go_pro/internal/validations"
+)
+
+// ProfileResponse is the profile shown in the header of every page.
+type ProfileResponse struct {
+	Name          string
+	HasAvatar     bool
+	AvatarVersion int64
+	Locale        string
+	Timezone      string
+}
+
+func NewProfileResponse(profile *models.Profile) *ProfileResponse {
+	response := &ProfileResponse{
+		Name:      profile.Email.String,
+		HasAvatar: profile.HasAvatar.Bool,
+		Locale:    profile.Locale.String,
+		Timezone:  profile.Timezone.String,
+	}
+
+	if profile.DisplayName.Valid {
+		response.Name = profile.DisplayName.String
+	}
+
+	if profile.UpdatedAt.Valid {
+		response.AvatarVersion = profile.UpdatedAt.Time.Unix()
+	}
+
+	return response
+}
+
+type ProfileFormResponse struct {
+	DisplayName string
+	Locale      string
+	Timezone    string
+	HasAvatar   bool
+	Locales     []i18n.Locale
+	Timezones   []string
+	validations.FormValidator
+}
+
+// Timezones are suggested in the profile form. Any other IANA name is
+// accepted too.
+var Timezones = []string{
+	"America/Sao_Paulo",
+	"America/Manaus",
+	"America/Belem",
+	"America/Fortaleza",
+	"America/Recife",
+	"America/Cuiaba",
+	"America/Porto_Velho",
+	"America/Rio_Branco",
+	"America/Noronha",
+	"America/New_York",
+	"America/Chicago",
+	"America/Los_Angeles",
+	"Europe/Lisbon",
+	"Europe/London",
+	"Europe/Berlin",
+	"Asia/Tokyo",
+	"UTC",
+}
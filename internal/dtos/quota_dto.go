@@ -0,0 +1,45 @@
+package dtos
+
+import (
+	"fmt"
+	"go_pro/internal/models"
+)
+
+type QuotaUsageResponse struct {
+	Notes    int64
+	MaxNotes int64
+	Label    string
+	Used     int64
+	Max      int64
+}
+
+// NewQuotaUsageResponse describes the usage meter of the header. The meter
+// follows the storage quota, or the note count when storage is unlimited.
+func NewQuotaUsageResponse(usage *models.QuotaUsage) *QuotaUsageResponse {
+	response := &QuotaUsageResponse{
+		Notes:    usage.Notes,
+		MaxNotes: usage.MaxNotes,
+		Label:    fmt.Sprintf("%s de %s", formatBytes(usage.Storage), formatBytes(usage.MaxStorage)),
+		Used:     usage.Storage,
+		Max:      usage.MaxStorage,
+	}
+
+	if usage.MaxStorage == 0 {
+		response.Label = fmt.Sprintf("%d de %d anotações", usage.Notes, usage.MaxNotes)
+		response.Used = usage.Notes
+		response.Max = usage.MaxNotes
+	}
+
+	return response
+}
+
+func formatBytes(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
@@ -0,0 +1,105 @@
+package dtos
+
+import (
+	"go_pro/internal/models"
+	"go_pro/tools"
+)
+
+type SecurityResponse struct {
+	FailedAttempts int
+	LockedUntil    string
+	Devices        []DeviceResponse
+	FailedLogins   []FailedLoginResponse
+}
+
+type DeviceResponse struct {
+	Browser    string
+	IP         string
+	Current    bool
+	CreatedAt  string
+	LastSeenAt string
+}
+
+type FailedLoginResponse struct {
+	Browser   string
+	IP        string
+	CreatedAt string
+}
+
+// DescribeUserAgent names the browser and system of a User-Agent header, as
+// in "Firefox em Linux".
+func DescribeUserAgent(userAgent string) string {
+	browser, platform := tools.ParseUserAgent(userAgent)
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " em " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return "Navegador em " + platform
+	default:
+		return "Navegador desconhecido"
+	}
+}
+
+func NewSecurityResponse(lockout *models.Lockout, devices []models.Device, failedLogins []models.FailedLogin) SecurityResponse {
+	response := SecurityResponse{
+		FailedAttempts: lockout.FailedAttempts,
+	}
+
+	if lockout.Remaining > 0 {
+		response.LockedUntil = lockout.LockedUntil.Time.Format("02/01/2006 15:04")
+	}
+
+	for _, device := range devices {
+		response.Devices = append(response.Devices, DeviceResponse{
+			Browser:    DescribeUserAgent(device.UserAgent.String),
+			IP:         device.IP.String,
+			Current:    device.Current.Bool,
+			CreatedAt:  device.CreatedAt.Time.Format("02/01/2006 15:04"),
+			LastSeenAt: device.LastSeenAt.Time.Format("02/01/2006 15:04"),
+		})
+	}
+
+	for _, failedLogin := range failedLogins {
+		response.FailedLogins = append(response.FailedLogins, FailedLoginResponse{
+			Browser:   DescribeUserAgent(failedLogin.UserAgent.String),
+			IP:        failedLogin.IP.String,
+			CreatedAt: failedLogin.CreatedAt.Time.Format("02/01/2006 15:04"),
+		})
+	}
+
+	return response
+}
+
+type SessionResponse struct {
+	Id         int
+	Browser    string
+	IP         string
+	Current    bool
+	CreatedAt  string
+	LastSeenAt string
+}
+
+type SessionsResponse struct {
+	Sessions []SessionResponse
+	Flash    string
+}
+
+func NewSessionsResponse(sessions []models.UserSession) SessionsResponse {
+	var response SessionsResponse
+
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, SessionResponse{
+			Id:         int(session.Id.Int.Int64()),
+			Browser:    DescribeUserAgent(session.UserAgent.String),
+			IP:         session.IP.String,
+			Current:    session.Current.Bool,
+			CreatedAt:  session.CreatedAt.Time.Format("02/01/2006 15:04"),
+			LastSeenAt: session.LastSeenAt.Time.Format("02/01/2006 15:04"),
+		})
+	}
+
+	return response
+}
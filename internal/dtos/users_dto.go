@@ -3,8 +3,10 @@ package dtos
 import "go_pro/internal/validations"
 
 type UserRequest struct {
-	Email    string
-	Password string
+	Email       string
+	Password    string
+	SSOProvider string
+	Unconfirmed bool
 	validations.FormValidator
 }
 
@@ -14,3 +16,8 @@ func NewUserRequest(email, password string) (req UserRequest) {
 
 	return
 }
+
+type ResetPasswordRequest struct {
+	Token string
+	validations.FormValidator
+}
@@ -0,0 +1,157 @@
+package dtos
+
+import (
+	"go_pro/internal/models"
+	"go_pro/internal/validations"
+)
+
+var workspaceRoleLabels = map[string]string{
+	models.WorkspaceRoleOwner:  "Dono",
+	models.WorkspaceRoleAdmin:  "Administrador",
+	models.WorkspaceRoleMember: "Membro",
+}
+
+type WorkspaceRoleOption struct {
+	Value string
+	Label string
+}
+
+// WorkspaceInviteRoles are the roles a member can be invited with or given.
+// A workspace has a single owner, the member who created it.
+var WorkspaceInviteRoles = []WorkspaceRoleOption{
+	{Value: models.WorkspaceRoleMember, Label: "Membro"},
+	{Value: models.WorkspaceRoleAdmin, Label: "Administrador"},
+}
+
+func IsValidWorkspaceInviteRole(role string) bool {
+	for _, option := range WorkspaceInviteRoles {
+		if option.Value == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+type WorkspaceResponse struct {
+	Id        int
+	Name      string
+	Role      string
+	RoleLabel string
+	CanManage bool
+	IsOwner   bool
+}
+
+func NewWorkspaceResponse(workspace *models.Workspace) WorkspaceResponse {
+	return WorkspaceResponse{
+		Id:        int(workspace.Id.Int.Int64()),
+		Name:      workspace.Name.String,
+		Role:      workspace.Role.String,
+		RoleLabel: workspaceRoleLabels[workspace.Role.String],
+		CanManage: workspace.CanManage(),
+		IsOwner:   workspace.Role.String == models.WorkspaceRoleOwner,
+	}
+}
+
+func NewWorkspaceListResponse(workspaces []models.Workspace) []WorkspaceResponse {
+	var list []WorkspaceResponse
+
+	for _, workspace := range workspaces {
+		list = append(list, NewWorkspaceResponse(&workspace))
+	}
+
+	return list
+}
+
+// WorkspaceSwitcherResponse fills the workspace switcher of the header. An
+// ActiveId of zero stands for the personal notes.
+type WorkspaceSwitcherResponse struct {
+	ActiveId   int
+	Workspaces []WorkspaceResponse
+}
+
+type WorkspacesResponse struct {
+	Workspaces []WorkspaceResponse
+	Name       string
+	validations.FormValidator
+}
+
+type WorkspaceMemberResponse struct {
+	UserId    int
+	Email     string
+	Name      string
+	Role      string
+	RoleLabel string
+	IsSelf    bool
+}
+
+type WorkspaceInvitationResponse struct {
+	Id        int
+	Email     string
+	RoleLabel string
+	InvitedBy string
+	ExpiresAt string
+}
+
+type WorkspaceViewResponse struct {
+	Workspace   WorkspaceResponse
+	Members     []WorkspaceMemberResponse
+	Invitations []WorkspaceInvitationResponse
+	Roles       []WorkspaceRoleOption
+	Email       string
+	Role        string
+	validations.FormValidator
+}
+
+func NewWorkspaceViewResponse(workspace *models.Workspace, members []models.WorkspaceMember, invitations []models.WorkspaceInvitation, userId int) *WorkspaceViewResponse {
+	res := &WorkspaceViewResponse{
+		Workspace: NewWorkspaceResponse(workspace),
+		Roles:     WorkspaceInviteRoles,
+		Role:      models.WorkspaceRoleMember,
+	}
+
+	for _, member := range members {
+		res.Members = append(res.Members, WorkspaceMemberResponse{
+			UserId:    int(member.UserId.Int.Int64()),
+			Email:     member.Email.String,
+			Name:      member.Name.String,
+			Role:      member.Role.String,
+			RoleLabel: workspaceRoleLabels[member.Role.String],
+			IsSelf:    int(member.UserId.Int.Int64()) == userId,
+		})
+	}
+
+	for _, invitation := range invitations {
+		res.Invitations = append(res.Invitations, WorkspaceInvitationResponse{
+			Id:        int(invitation.Id.Int.Int64()),
+			Email:     invitation.Email.String,
+			RoleLabel: workspaceRoleLabels[invitation.Role.String],
+			InvitedBy: invitation.InvitedBy.String,
+			ExpiresAt: invitation.ExpiresAt.Time.Format("02/01/2006 15:04"),
+		})
+	}
+
+	return res
+}
+
+// WorkspaceJoinResponse is the page an invitation link opens.
+type WorkspaceJoinResponse struct {
+	Token         string
+	WorkspaceName string
+	Email         string
+	RoleLabel     string
+	InvitedBy     string
+	// CanAccept tells if the user is signed in with the invited email.
+	CanAccept bool
+	validations.FormValidator
+}
+
+func NewWorkspaceJoinResponse(token string, invitation *models.WorkspaceInvitation) *WorkspaceJoinResponse {
+	return &WorkspaceJoinResponse{
+		Token:         token,
+		WorkspaceName: invitation.WorkspaceName.String,
+		Email:         invitation.Email.String,
+		RoleLabel:     workspaceRoleLabels[invitation.Role.String],
+		InvitedBy:     invitation.InvitedBy.String,
+	}
+}
@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"go_pro/internal/apperrors"
+	"go_pro/internal/dtos"
+	"go_pro/internal/mailers"
+	"go_pro/internal/models"
+	"go_pro/internal/passwords"
+	"go_pro/internal/render"
+	"go_pro/internal/repositories"
+	"go_pro/internal/validations"
+	"go_pro/tools"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+type accountHandler struct {
+	render         *render.RenderTemplate
+	session        *scs.SessionManager
+	repo           repositories.UserRepository
+	accountRepo    repositories.AccountRepository
+	twoFactor      repositories.TwoFactorRepository
+	accessTokens   repositories.AccessTokenRepository
+	security       repositories.LoginSecurityRepository
+	sessions       repositories.SessionRepository
+	profiles       repositories.ProfileRepository
+	mail           mailers.MailService
+	passwords      *passwords.Checker
+	hasher         *passwords.Hasher
+	sso            *SSOProvider
+	emailChangeTTL time.Duration
+	deletionGrace  time.Duration
+}
+
+func NewAccountHandler(render *render.RenderTemplate, session *scs.SessionManager, repo repositories.UserRepository, accountRepo repositories.AccountRepository, twoFactor repositories.TwoFactorRepository, accessTokens repositories.AccessTokenRepository, security repositories.LoginSecurityRepository, sessions repositories.SessionRepository, profiles repositories.ProfileRepository, mail mailers.MailService, passwords *passwords.Checker, hasher *passwords.Hasher, sso *SSOProvider, emailChangeTTL time.Duration, deletionGrace time.Duration) *accountHandler {
+	return &accountHandler{render: render, session: session, repo: repo, accountRepo: accountRepo, twoFactor: twoFactor, accessTokens: accessTokens, security: security, sessions: sessions, profiles: profiles, mail: mail, passwords: passwords, hasher: hasher, sso: sso, emailChangeTTL: emailChangeTTL, deletionGrace: deletionGrace}
+}
+
+func (ah *accountHandler) getUserIdFromSession(r *http.Request) int64 {
+	return currentUserId(ah.session, r)
+}
+
+func (ah *accountHandler) newAccountResponse(r *http.Request) (*dtos.AccountResponse, error) {
+	userId := int(ah.getUserIdFromSession(r))
+
+	user, err := ah.repo.FindById(r.Context(), userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := ah.repo.GetPendingEmailChange(r.Context(), userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data := &dtos.AccountResponse{Email: user.Email.String, PendingEmail: pending, TwoFactorEnabled: user.TOTPEnabled.Bool, HasPassword: repositories.HasPassword(user)}
+
+	if !data.HasPassword {
+		data.Reauthenticated = reauthenticated(ah.session, r, user.Id.Int.Int64())
+
+		if ah.sso != nil {
+			data.SSOProvider = ah.sso.Name
+		}
+	}
+
+	if data.TwoFactorEnabled {
+		if data.RecoveryCodesLeft, err = ah.twoFactor.CountRecoveryCodes(r.Context(), userId); err != nil {
+			return nil, err
+		}
+	}
+
+	tokens, err := ah.accessTokens.List(r.Context(), userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data.AccessTokens = dtos.NewAccessTokenResponse(tokens)
+	data.AccessTokenExpiryOptions = dtos.AccessTokenExpiryOptions
+
+	return data, nil
+}
+
+func (ah *accountHandler) Account(w http.ResponseWriter, r *http.Request) error {
+	data, err := ah.newAccountResponse(r)
+
+	if err != nil {
+		return err
+	}
+
+	data.Flash = ah.session.PopString(r.Context(), "flash")
+
+	return ah.render.RenderPage(w, r, "account.html", data, http.StatusOK)
+}
+
+func (ah *accountHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	data, err := ah.newAccountResponse(r)
+
+	if err != nil {
+		return err
+	}
+
+	data.NewEmail = strings.TrimSpace(r.PostFormValue("email"))
+	password := r.PostFormValue("password")
+
+	if err := tools.ValidateEmail(data.NewEmail); err != nil {
+		data.AddFieldError("email", "Email é inválido")
+	} else if strings.EqualFold(data.NewEmail, data.Email) {
+		data.AddFieldError("email", "O novo email precisa ser diferente do atual")
+	}
+
+	user, err := ah.repo.FindById(r.Context(), int(ah.getUserIdFromSession(r)))
+
+	if err != nil {
+		return err
+	}
+
+	ah.confirmIdentity(r, &data.FormValidator, user, "password", password)
+
+	if !data.Valid() {
+		return ah.render.RenderPage(w, r, "account.html", data, http.StatusUnprocessableEntity)
+	}
+
+	token := tools.GenerateToken()
+
+	err = ah.repo.CreateEmailChange(r.Context(), int(user.Id.Int.Int64()), data.NewEmail, tools.HashToken(token))
+
+	if err == repositories.ErrDuplicateEmail {
+		data.AddFieldError("email", "email já está cadastrado")
+		return ah.render.RenderPage(w, r, "account.html", data, http.StatusUnprocessableEntity)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	body, err := ah.render.RenderMailBody(r, "email-change.html", map[string]string{
+		"token":    token,
+		"validFor": formatRetryAfter(int(ah.emailChangeTTL.Seconds())),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := ah.mail.Send(mailers.MailMessage{
+		To:      []string{data.NewEmail},
+		Subject: "Confirmação de novo email",
+		IsHTML:  true,
+		Body:    body,
+	}); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+
+	ah.notifyEmailChange(r, data.Email, data.NewEmail)
+
+	ah.session.Put(r.Context(), "flash", "Enviamos um link de confirmação para "+data.NewEmail+". O email só será alterado após a confirmação.")
+
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+	return nil
+}
+
+// confirmIdentity asks for the password before a sensitive change. Users
+// without a password, who sign in through the identity provider, must have
+// signed in there again a moment ago instead.
+func (ah *accountHandler) confirmIdentity(r *http.Request, form *validations.FormValidator, user *models.User, field, password string) {
+	if !repositories.HasPassword(user) {
+		if !reauthenticated(ah.session, r, user.Id.Int.Int64()) {
+			form.AddFieldError(field, "Confirme a sua identidade antes de continuar")
+		}
+
+		return
+	}
+
+	if ok, _ := ah.hasher.Verify(password, user.Password.String); !ok {
+		form.AddFieldError(field, "Senha inválida")
+	}
+}
+
+// notifyEmailChange warns the current address that a change was requested,
+// so the owner notices if someone else is using the account. A failure to
+// send the notice is only logged.
+func (ah *accountHandler) notifyEmailChange(r *http.Request, email, newEmail string) {
+	body, err := ah.render.RenderMailBody(r, "email-change-notice.html", map[string]string{"newEmail": newEmail})
+
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	if err := ah.mail.Send(mailers.MailMessage{
+		To:      []string{email},
+		Subject: "Alteração de email solicitada",
+		IsHTML:  true,
+		Body:    body,
+	}); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+func (ah *accountHandler) ChangePassword(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	data, err := ah.newAccountResponse(r)
+
+	if err != nil {
+		return err
+	}
+
+	current := r.PostFormValue("current-password")
+	password := r.PostFormValue("new-password")
+	confirm := r.PostFormValue("new-password-confirm")
+
+	user, err := ah.repo.FindById(r.Context(), int(ah.getUserIdFromSession(r)))
+
+	if err != nil {
+		return err
+	}
+
+	ah.confirmIdentity(r, &data.FormValidator, user, "current-password", current)
+
+	if password != confirm {
+		data.AddFieldError("new-password", "As senhas não conferem")
+	} else {
+		ah.passwords.Validate(&data.FormValidator, "new-password", password, user.Email.String)
+	}
+
+	if !data.Valid() {
+		return ah.render.RenderPage(w, r, "account.html", data, http.StatusUnprocessableEntity)
+	}
+
+	hash, err := ah.hasher.Hash(password)
+
+	if err != nil {
+		return err
+	}
+
+	if err := ah.repo.UpdatePassword(r.Context(), int(user.Id.Int.Int64()), hash, ah.session.Token(r.Context())); err != nil {
+		return err
+	}
+
+	if err := ah.mail.Send(mailers.MailMessage{
+		To:      []string{user.Email.String},
+		Subject: "Sua senha foi atualizada",
+		Body:    []byte("A senha da sua conta foi alterada, as outras sessões abertas foram encerradas e os tokens de acesso foram revogados."),
+		IsHTML:  false,
+	}); err != nil {
+		slog.Error(err.Error())
+	}
+
+	ah.session.Put(r.Context(), "flash", "Sua senha foi alterada. As outras sessões foram encerradas e os tokens de acesso foram revogados.")
+
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+	return nil
+}
+
+func (ah *accountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	data, err := ah.newAccountResponse(r)
+
+	if err != nil {
+		return err
+	}
+
+	user, err := ah.repo.FindById(r.Context(), int(ah.getUserIdFromSession(r)))
+
+	if err != nil {
+		return err
+	}
+
+	ah.confirmIdentity(r, &data.FormValidator, user, "delete-password", r.PostFormValue("delete-password"))
+
+	if !data.Valid() {
+		return ah.render.RenderPage(w, r, "account.html", data, http.StatusUnprocessableEntity)
+	}
+
+	token := tools.GenerateToken()
+	days := strconv.Itoa(int(ah.deletionGrace.Hours() / 24))
+
+	err = ah.accountRepo.RequestDeletion(r.Context(), int(user.Id.Int.Int64()), tools.HashToken(token), ah.deletionGrace)
+
+	if err == repositories.ErrOwnsWorkspace {
+		data.AddFieldError("delete-password", "Exclua os workspaces dos quais você é dono antes de excluir a sua conta")
+		return ah.render.RenderPage(w, r, "account.html", data, http.StatusUnprocessableEntity)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	body, err := ah.render.RenderMailBody(r, "account-deletion.html", map[string]string{"token": token, "days": days})
+
+	if err != nil {
+		return err
+	}
+
+	if err := ah.mail.Send(mailers.MailMessage{
+		To:      []string{user.Email.String},
+		Subject: "Exclusão da sua conta",
+		IsHTML:  true,
+		Body:    body,
+	}); err != nil {
+		slog.Error(err.Error())
+	}
+
+	if err := ah.session.RenewToken(r.Context()); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+
+	ah.session.Remove(r.Context(), "userId")
+	ah.session.Remove(r.Context(), "userEmail")
+	ah.session.Remove(r.Context(), "userRole")
+	ah.session.Remove(r.Context(), "workspaceId")
+
+	msg := "Sua conta será excluída em " + days + " dias. Enviamos para o seu email um link com a cópia dos seus dados. Para cancelar a exclusão, basta entrar no sistema antes desse prazo."
+
+	return ah.render.RenderPage(w, r, "generic-success.html", msg, http.StatusOK)
+}
+
+func (ah *accountHandler) Export(w http.ResponseWriter, r *http.Request) error {
+	data, err := ah.accountRepo.GetExport(r.Context(), tools.HashToken(r.PathValue("token")))
+
+	if err == repositories.ErrAccountExportNotFound {
+		return apperrors.ErrorNotFound("export not found")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="quicknotes-export.json"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(data)
+
+	return nil
+}
+
+func (ah *accountHandler) ConfirmEmail(w http.ResponseWriter, r *http.Request) error {
+	user, err := ah.repo.ConfirmEmailChange(r.Context(), tools.HashToken(r.PathValue("token")))
+
+	if err == repositories.ErrEmailChangeNotFound {
+		return ah.render.RenderPage(w, r, "generic-error.html", "Link de confirmação inválido ou expirado", http.StatusOK)
+	}
+
+	if err == repositories.ErrDuplicateEmail {
+		return ah.render.RenderPage(w, r, "generic-error.html", "Esse email já está cadastrado em outra conta", http.StatusOK)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if ah.getUserIdFromSession(r) == user.Id.Int.Int64() {
+		ah.session.Put(r.Context(), "userEmail", user.Email.String)
+		ah.session.Put(r.Context(), "flash", "Seu email foi alterado.")
+
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+		return nil
+	}
+
+	return ah.render.RenderPage(w, r, "generic-success.html", "Seu email foi alterado. Use o novo email para entrar no sistema.", http.StatusOK)
+}
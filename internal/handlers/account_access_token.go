@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"go_pro/internal/apperrors"
+	"go_pro/internal/dtos"
+	"go_pro/internal/models"
+	"go_pro/internal/repositories"
+	"go_pro/tools"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	accessTokenPrefix      = "qnp_"
+	maxAccessTokenNameSize = 100
+)
+
+func (ah *accountHandler) CreateAccessToken(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	data, err := ah.newAccountResponse(r)
+
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(r.PostFormValue("token-name"))
+	scope := r.PostFormValue("token-scope")
+	days, err := strconv.Atoi(r.PostFormValue("token-expiry"))
+
+	if name == "" || utf8.RuneCountInString(name) > maxAccessTokenNameSize {
+		data.AddFieldError("token-name", "O nome do token é obrigatório e pode ter no máximo 100 caracteres")
+	}
+
+	if scope != models.AccessTokenScopeRead && scope != models.AccessTokenScopeWrite {
+		data.AddFieldError("token-scope", "Permissão inválida")
+	}
+
+	if err != nil || !dtos.IsValidAccessTokenExpiry(days) {
+		data.AddFieldError("token-expiry", "Validade inválida")
+	}
+
+	if !data.Valid() {
+		return ah.render.RenderPage(w, r, "account.html", data, http.StatusUnprocessableEntity)
+	}
+
+	token := accessTokenPrefix + strings.TrimRight(tools.GenerateToken(), "=")
+
+	if err := ah.accessTokens.Create(r.Context(), int(ah.getUserIdFromSession(r)), name, tools.HashToken(token), scope, days); err != nil {
+		return err
+	}
+
+	// the token is only shown now, it is not stored and cannot be recovered
+	if data, err = ah.newAccountResponse(r); err != nil {
+		return err
+	}
+
+	data.NewAccessToken = token
+
+	return ah.render.RenderPage(w, r, "account.html", data, http.StatusOK)
+}
+
+func (ah *accountHandler) RevokeAccessToken(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+
+	if err != nil {
+		return apperrors.ErrorNotFound("access token not found")
+	}
+
+	err = ah.accessTokens.Delete(r.Context(), int(ah.getUserIdFromSession(r)), id)
+
+	if err == repositories.ErrAccessTokenNotFound {
+		return apperrors.ErrorNotFound("access token not found")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	ah.session.Put(r.Context(), "flash", "O token de acesso foi revogado.")
+
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"go_pro/internal/apperrors"
+	"go_pro/internal/dtos"
+	"go_pro/internal/i18n"
+	"go_pro/internal/models"
+	"go_pro/internal/repositories"
+	"go_pro/tools"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	displayNameMaxLength = 50
+	// avatarMaxUpload is the largest image accepted, before it is resized.
+	avatarMaxUpload = 2 << 20
+)
+
+func (ah *accountHandler) newProfileResponse(r *http.Request) (*dtos.ProfileFormResponse, error) {
+	profile, err := ah.profiles.Get(r.Context(), int(ah.getUserIdFromSession(r)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.ProfileFormResponse{
+		DisplayName: profile.DisplayName.String,
+		Locale:      profile.Locale.String,
+		Timezone:    profile.Timezone.String,
+		HasAvatar:   profile.HasAvatar.Bool,
+		Locales:     i18n.Locales,
+		Timezones:   dtos.Timezones,
+	}, nil
+}
+
+func (ah *accountHandler) Profile(w http.ResponseWriter, r *http.Request) error {
+	data, err := ah.newProfileResponse(r)
+
+	if err != nil {
+		return err
+	}
+
+	data.Flash = ah.session.PopString(r.Context(), "flash")
+
+	return ah.render.RenderPage(w, r, "account-profile.html", data, http.StatusOK)
+}
+
+func (ah *accountHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	data, err := ah.newProfileResponse(r)
+
+	if err != nil {
+		return err
+	}
+
+	data.DisplayName = strings.TrimSpace(r.PostFormValue("display-name"))
+	data.Locale = r.PostFormValue("locale")
+	data.Timezone = strings.TrimSpace(r.PostFormValue("timezone"))
+
+	if utf8.RuneCountInString(data.DisplayName) > displayNameMaxLength {
+		data.AddFieldError("display-name", "O nome pode ter no máximo "+strconv.Itoa(displayNameMaxLength)+" caracteres")
+	}
+
+	if !i18n.IsSupported(data.Locale) {
+		data.AddFieldError("locale", "Idioma inválido")
+	}
+
+	if _, err := time.LoadLocation(data.Timezone); data.Timezone == "" || err != nil {
+		data.AddFieldError("timezone", "Fuso horário inválido")
+	}
+
+	if !data.Valid() {
+		return ah.render.RenderPage(w, r, "account-profile.html", data, http.StatusUnprocessableEntity)
+	}
+
+	if err := ah.profiles.Update(r.Context(), int(ah.getUserIdFromSession(r)), data.DisplayName, data.Locale, data.Timezone); err != nil {
+		return err
+	}
+
+	ah.session.Put(r.Context(), "flash", "Perfil atualizado")
+
+	http.Redirect(w, r, "/account/profile", http.StatusSeeOther)
+	return nil
+}
+
+func (ah *accountHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) error {
+	data, err := ah.newProfileResponse(r)
+
+	if err != nil {
+		return err
+	}
+
+	file, header, err := r.FormFile("avatar")
+
+	if err != nil {
+		data.AddFieldError("avatar", "Escolha uma imagem de até 2 MB")
+		return ah.render.RenderPage(w, r, "account-profile.html", data, http.StatusUnprocessableEntity)
+	}
+
+	defer file.Close()
+
+	if header.Size > avatarMaxUpload {
+		data.AddFieldError("avatar", "Escolha uma imagem de até 2 MB")
+		return ah.render.RenderPage(w, r, "account-profile.html", data, http.StatusUnprocessableEntity)
+	}
+
+	images, err := tools.ResizeAvatar(file, models.AvatarSizes)
+
+	if err == tools.ErrInvalidImage {
+		data.AddFieldError("avatar", "A imagem precisa ser PNG, JPEG ou GIF")
+		return ah.render.RenderPage(w, r, "account-profile.html", data, http.StatusUnprocessableEntity)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := ah.profiles.SaveAvatar(r.Context(), int(ah.getUserIdFromSession(r)), images); err != nil {
+		return err
+	}
+
+	ah.session.Put(r.Context(), "flash", "Foto atualizada")
+
+	http.Redirect(w, r, "/account/profile", http.StatusSeeOther)
+	return nil
+}
+
+func (ah *accountHandler) DeleteAvatar(w http.ResponseWriter, r *http.Request) error {
+	if err := ah.profiles.DeleteAvatar(r.Context(), int(ah.getUserIdFromSession(r))); err != nil {
+		return err
+	}
+
+	ah.session.Put(r.Context(), "flash", "Foto removida")
+
+	http.Redirect(w, r, "/account/profile", http.StatusSeeOther)
+	return nil
+}
+
+// Avatar serves the avatar of the signed in user. Its address carries the
+// version of the profile, so it can be cached for long.
+func (ah *accountHandler) Avatar(w http.ResponseWriter, r *http.Request) error {
+	size, err := strconv.Atoi(r.PathValue("size"))
+
+	if err != nil || !slices.Contains(models.AvatarSizes, size) {
+		return apperrors.ErrorNotFound("avatar not found")
+	}
+
+	image, err := ah.profiles.GetAvatar(r.Context(), int(ah.getUserIdFromSession(r)), size)
+
+	if err == repositories.ErrAvatarNotFound {
+		return apperrors.ErrorNotFound("avatar not found")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "private, max-age=31536000")
+	w.Write(image)
+
+	return nil
+}
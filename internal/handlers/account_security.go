@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"go_pro/internal/dtos"
+	"go_pro/tools"
+	"net/http"
+)
+
+// failedLoginsShown is how many of the latest failed sign ins are listed.
+const failedLoginsShown = 20
+
+func (ah *accountHandler) Security(w http.ResponseWriter, r *http.Request) error {
+	userId := int(ah.getUserIdFromSession(r))
+
+	lockout, err := ah.security.GetLockout(r.Context(), userId)
+
+	if err != nil {
+		return err
+	}
+
+	devices, err := ah.security.ListDevices(r.Context(), userId, tools.HashToken(currentDeviceToken(r)))
+
+	if err != nil {
+		return err
+	}
+
+	failedLogins, err := ah.security.ListFailedLogins(r.Context(), userId, failedLoginsShown)
+
+	if err != nil {
+		return err
+	}
+
+	return ah.render.RenderPage(w, r, "account-security.html", dtos.NewSecurityResponse(lockout, devices, failedLogins), http.StatusOK)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"go_pro/internal/apperrors"
+	"go_pro/internal/dtos"
+	"go_pro/internal/repositories"
+	"net/http"
+	"strconv"
+)
+
+func (ah *accountHandler) Sessions(w http.ResponseWriter, r *http.Request) error {
+	sessions, err := ah.sessions.List(r.Context(), int(ah.getUserIdFromSession(r)), ah.session.Token(r.Context()))
+
+	if err != nil {
+		return err
+	}
+
+	data := dtos.NewSessionsResponse(sessions)
+	data.Flash = ah.session.PopString(r.Context(), "flash")
+
+	return ah.render.RenderPage(w, r, "account-sessions.html", data, http.StatusOK)
+}
+
+func (ah *accountHandler) RevokeSession(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+
+	if err != nil {
+		return apperrors.ErrorNotFound("session not found")
+	}
+
+	err = ah.sessions.Revoke(r.Context(), int(ah.getUserIdFromSession(r)), id, ah.session.Token(r.Context()))
+
+	if err == repositories.ErrSessionNotFound {
+		return apperrors.ErrorNotFound("session not found")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	ah.session.Put(r.Context(), "flash", "O dispositivo foi desconectado.")
+
+	http.Redirect(w, r, "/account/sessions", http.StatusSeeOther)
+	return nil
+}
+
+func (ah *accountHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) error {
+	if err := ah.sessions.RevokeOthers(r.Context(), int(ah.getUserIdFromSession(r)), ah.session.Token(r.Context())); err != nil {
+		return err
+	}
+
+	ah.session.Put(r.Context(), "flash", "Todos os outros dispositivos foram desconectados.")
+
+	http.Redirect(w, r, "/account/sessions", http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"go_pro/internal/dtos"
+	"go_pro/tools"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	totpIssuer         = "Quicknotes"
+	recoveryCodesCount = 10
+)
+
+// newTwoFactorSetup builds the enrollment page for the secret waiting for
+// confirmation in the session, generating one on the first visit.
+func (ah *accountHandler) newTwoFactorSetup(r *http.Request) (*dtos.TwoFactorSetupResponse, error) {
+	secret := ah.session.GetString(r.Context(), "totpSecret")
+
+	if secret == "" {
+		secret = tools.GenerateTOTPSecret()
+		ah.session.Put(r.Context(), "totpSecret", secret)
+	}
+
+	uri := tools.TOTPURI(totpIssuer, ah.session.GetString(r.Context(), "userEmail"), secret)
+
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.TwoFactorSetupResponse{
+		QRCode: template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(png)),
+		Secret: secret,
+	}, nil
+}
+
+func (ah *accountHandler) TwoFactorSetup(w http.ResponseWriter, r *http.Request) error {
+	user, err := ah.repo.FindById(r.Context(), int(ah.getUserIdFromSession(r)))
+
+	if err != nil {
+		return err
+	}
+
+	if user.TOTPEnabled.Bool {
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+		return nil
+	}
+
+	data, err := ah.newTwoFactorSetup(r)
+
+	if err != nil {
+		return err
+	}
+
+	return ah.render.RenderPage(w, r, "account-2fa.html", data, http.StatusOK)
+}
+
+func (ah *accountHandler) TwoFactorEnable(w http.ResponseWriter, r *http.Request) error {
+	if ah.session.GetString(r.Context(), "totpSecret") == "" {
+		http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+		return nil
+	}
+
+	data, err := ah.newTwoFactorSetup(r)
+
+	if err != nil {
+		return err
+	}
+
+	step, ok := tools.ValidateTOTP(data.Secret, r.PostFormValue("code"), time.Now())
+
+	if !ok {
+		data.AddFieldError("code", "Código inválido. Confira o horário do seu celular e tente novamente")
+		return ah.render.RenderPage(w, r, "account-2fa.html", data, http.StatusUnprocessableEntity)
+	}
+
+	codes, hashes := newRecoveryCodes()
+
+	if err := ah.twoFactor.Enable(r.Context(), int(ah.getUserIdFromSession(r)), data.Secret, step, hashes); err != nil {
+		return err
+	}
+
+	ah.session.Remove(r.Context(), "totpSecret")
+
+	return ah.render.RenderPage(w, r, "account-2fa-codes.html", dtos.TwoFactorSetupResponse{Codes: codes}, http.StatusOK)
+}
+
+func (ah *accountHandler) TwoFactorRecoveryCodes(w http.ResponseWriter, r *http.Request) error {
+	ok, err := ah.checkTwoFactorPassword(w, r)
+
+	if err != nil || !ok {
+		return err
+	}
+
+	codes, hashes := newRecoveryCodes()
+
+	if err := ah.twoFactor.RegenerateRecoveryCodes(r.Context(), int(ah.getUserIdFromSession(r)), hashes); err != nil {
+		return err
+	}
+
+	return ah.render.RenderPage(w, r, "account-2fa-codes.html", dtos.TwoFactorSetupResponse{Codes: codes}, http.StatusOK)
+}
+
+func (ah *accountHandler) TwoFactorDisable(w http.ResponseWriter, r *http.Request) error {
+	ok, err := ah.checkTwoFactorPassword(w, r)
+
+	if err != nil || !ok {
+		return err
+	}
+
+	if err := ah.twoFactor.Disable(r.Context(), int(ah.getUserIdFromSession(r))); err != nil {
+		return err
+	}
+
+	ah.session.Put(r.Context(), "flash", "A verificação em duas etapas foi desativada.")
+
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+	return nil
+}
+
+// checkTwoFactorPassword asks for the password again before changing the two
+// factor settings. When the identity is not confirmed it renders the account
+// page and returns false.
+func (ah *accountHandler) checkTwoFactorPassword(w http.ResponseWriter, r *http.Request) (bool, error) {
+	user, err := ah.repo.FindById(r.Context(), int(ah.getUserIdFromSession(r)))
+
+	if err != nil {
+		return false, err
+	}
+
+	data, err := ah.newAccountResponse(r)
+
+	if err != nil {
+		return false, err
+	}
+
+	ah.confirmIdentity(r, &data.FormValidator, user, "2fa-password", r.PostFormValue("2fa-password"))
+
+	if data.Valid() {
+		return true, nil
+	}
+
+	return false, ah.render.RenderPage(w, r, "account.html", data, http.StatusUnprocessableEntity)
+}
+
+func newRecoveryCodes() (codes []string, hashes []string) {
+	codes = tools.GenerateRecoveryCodes(recoveryCodesCount)
+
+	for _, code := range codes {
+		hashes = append(hashes, tools.HashRecoveryCode(code))
+	}
+
+	return codes, hashes
+}
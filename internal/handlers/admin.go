@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/dtos"
+	"go_pro/internal/mailers"
+	"go_pro/internal/models"
+	"go_pro/internal/render"
+	"go_pro/internal/repositories"
+	"go_pro/tools"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// adminPageSize is how many rows the admin pages list at a time.
+const adminPageSize = 50
+
+type adminHandler struct {
+	render  *render.RenderTemplate
+	session *scs.SessionManager
+	repo    repositories.AdminRepository
+	users   repositories.UserRepository
+	mail    mailers.MailService
+}
+
+func NewAdminHandler(render *render.RenderTemplate, session *scs.SessionManager, repo repositories.AdminRepository, users repositories.UserRepository, mail mailers.MailService) *adminHandler {
+	return &adminHandler{render: render, session: session, repo: repo, users: users, mail: mail}
+}
+
+func (ah *adminHandler) getUserIdFromSession(r *http.Request) int64 {
+	return currentUserId(ah.session, r)
+}
+
+// page reads the page number of the query string, starting at 1.
+func page(r *http.Request) int {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+
+	if err != nil || page < 1 {
+		return 1
+	}
+
+	return page
+}
+
+func (ah *adminHandler) Users(w http.ResponseWriter, r *http.Request) error {
+	search := strings.TrimSpace(r.URL.Query().Get("q"))
+	current := page(r)
+
+	// one extra row tells if there is a next page
+	users, err := ah.repo.ListUsers(r.Context(), search, adminPageSize+1, (current-1)*adminPageSize)
+
+	if err != nil {
+		return err
+	}
+
+	hasNext := len(users) > adminPageSize
+
+	if hasNext {
+		users = users[:adminPageSize]
+	}
+
+	data := dtos.NewAdminUsersResponse(users, int(ah.getUserIdFromSession(r)), search, current, hasNext)
+	data.Flash = ah.session.PopString(r.Context(), "flash")
+
+	return ah.render.RenderPage(w, r, "admin-users.html", data, http.StatusOK)
+}
+
+func (ah *adminHandler) DisableUser(w http.ResponseWriter, r *http.Request) error {
+	return ah.setDisabled(w, r, true)
+}
+
+func (ah *adminHandler) EnableUser(w http.ResponseWriter, r *http.Request) error {
+	return ah.setDisabled(w, r, false)
+}
+
+func (ah *adminHandler) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) error {
+	userId, err := ah.targetUserId(r)
+
+	if err != nil {
+		return err
+	}
+
+	if disabled && userId == int(ah.getUserIdFromSession(r)) {
+		return apperrors.NewWithStatus(errors.New("você não pode desativar a sua própria conta"), http.StatusUnprocessableEntity)
+	}
+
+	if err := ah.handleRepoError(ah.repo.SetDisabled(r.Context(), int(ah.getUserIdFromSession(r)), userId, disabled)); err != nil {
+		return err
+	}
+
+	msg := "O usuário foi reativado."
+
+	if disabled {
+		msg = "O usuário foi desativado e desconectado de todos os dispositivos."
+	}
+
+	return ah.done(w, r, msg)
+}
+
+func (ah *adminHandler) ConfirmUser(w http.ResponseWriter, r *http.Request) error {
+	userId, err := ah.targetUserId(r)
+
+	if err != nil {
+		return err
+	}
+
+	if err := ah.handleRepoError(ah.repo.Confirm(r.Context(), int(ah.getUserIdFromSession(r)), userId)); err != nil {
+		return err
+	}
+
+	return ah.done(w, r, "O cadastro do usuário foi confirmado.")
+}
+
+// ResetUserPassword sends the user the same email they get when they forget
+// their password.
+func (ah *adminHandler) ResetUserPassword(w http.ResponseWriter, r *http.Request) error {
+	userId, err := ah.targetUserId(r)
+
+	if err != nil {
+		return err
+	}
+
+	user, err := ah.users.FindById(r.Context(), userId)
+
+	if err != nil {
+		return apperrors.ErrorNotFound("user not found")
+	}
+
+	token := tools.GenerateToken()
+
+	if err := ah.users.CreateResetPasswordToken(r.Context(), user.Email.String, tools.HashToken(token)); err != nil {
+		return apperrors.NewWithStatus(errors.New("o usuário ainda não confirmou o cadastro"), http.StatusUnprocessableEntity)
+	}
+
+	body, err := ah.render.RenderMailBody(r, "forgetpassword.html", map[string]string{"token": token})
+
+	if err != nil {
+		return err
+	}
+
+	err = ah.mail.Send(mailers.MailMessage{
+		To:      []string{user.Email.String},
+		Subject: "Resetar senha",
+		IsHTML:  true,
+		Body:    body,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := ah.handleRepoError(ah.repo.Audit(r.Context(), int(ah.getUserIdFromSession(r)), userId, models.AdminActionPasswordReset)); err != nil {
+		return err
+	}
+
+	return ah.done(w, r, "Foi enviado ao usuário um email para redefinir a senha.")
+}
+
+func (ah *adminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) error {
+	userId, err := ah.targetUserId(r)
+
+	if err != nil {
+		return err
+	}
+
+	if userId == int(ah.getUserIdFromSession(r)) {
+		return apperrors.NewWithStatus(errors.New("você não pode excluir a sua própria conta"), http.StatusUnprocessableEntity)
+	}
+
+	if err := ah.handleRepoError(ah.repo.Delete(r.Context(), int(ah.getUserIdFromSession(r)), userId)); err != nil {
+		return err
+	}
+
+	return ah.done(w, r, "O usuário foi excluído.")
+}
+
+func (ah *adminHandler) Audit(w http.ResponseWriter, r *http.Request) error {
+	current := page(r)
+
+	entries, err := ah.repo.ListAudit(r.Context(), adminPageSize+1, (current-1)*adminPageSize)
+
+	if err != nil {
+		return err
+	}
+
+	hasNext := len(entries) > adminPageSize
+
+	if hasNext {
+		entries = entries[:adminPageSize]
+	}
+
+	return ah.render.RenderPage(w, r, "admin-audit.html", dtos.NewAdminAuditResponse(entries, current, hasNext), http.StatusOK)
+}
+
+func (ah *adminHandler) targetUserId(r *http.Request) (int, error) {
+	userId, err := strconv.Atoi(r.PathValue("id"))
+
+	if err != nil {
+		return 0, apperrors.ErrorNotFound("user not found")
+	}
+
+	return userId, nil
+}
+
+func (ah *adminHandler) handleRepoError(err error) error {
+	if err == repositories.ErrAdminUserNotFound {
+		return apperrors.ErrorNotFound("user not found")
+	}
+
+	if err == repositories.ErrOwnsWorkspace {
+		return apperrors.NewWithStatus(errors.New("o usuário é dono de um workspace e não pode ser excluído"), http.StatusUnprocessableEntity)
+	}
+
+	return err
+}
+
+// done goes back to the list the action was taken from, keeping its search.
+func (ah *adminHandler) done(w http.ResponseWriter, r *http.Request, msg string) error {
+	ah.session.Put(r.Context(), "flash", msg)
+
+	target := "/admin/users"
+
+	if search := r.PostFormValue("q"); search != "" {
+		target += "?q=" + url.QueryEscape(search)
+	}
+
+	http.Redirect(w, r, target, http.StatusSeeOther)
+	return nil
+}
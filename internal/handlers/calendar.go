@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/calendar"
+	"go_pro/internal/dtos"
+	"go_pro/internal/models"
+	"go_pro/internal/repositories"
+	"go_pro/tools"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	calendarExcerptSize   = 200
+	calendarEventDuration = 30 * time.Minute
+)
+
+type calendarHandler struct {
+	repo      repositories.CalendarRepository
+	noteRepo  repositories.NoteRepository
+	baseURL   string
+	uidDomain string
+}
+
+// NewCalendarHandler builds the handler of the calendar feed. The events link
+// to the notes under baseURL, and their UIDs use its host so they stay the
+// same whatever address the feed is fetched from.
+func NewCalendarHandler(repo repositories.CalendarRepository, noteRepo repositories.NoteRepository, baseURL string) *calendarHandler {
+	uidDomain := "quicknotes"
+
+	if u, err := url.Parse(baseURL); err == nil && u.Hostname() != "" {
+		uidDomain = u.Hostname()
+	}
+
+	return &calendarHandler{repo: repo, noteRepo: noteRepo, baseURL: baseURL, uidDomain: uidDomain}
+}
+
+func (ch *calendarHandler) Feed(w http.ResponseWriter, r *http.Request) error {
+	token, ok := strings.CutSuffix(r.PathValue("file"), ".ics")
+
+	if !ok || token == "" {
+		return apperrors.ErrorNotFound("calendar not found")
+	}
+
+	userId, err := ch.repo.FindUserIdByToken(r.Context(), tools.HashToken(token))
+
+	if err == repositories.ErrCalendarTokenNotFound {
+		return apperrors.ErrorNotFound("calendar not found")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	notes, err := ch.noteRepo.ListWithDueDate(r.Context(), models.NoteScope{UserId: userId})
+
+	if err != nil {
+		return err
+	}
+
+	var events []calendar.Event
+
+	for _, note := range dtos.NewNoteResponseFromNoteList(notes) {
+		events = append(events, calendar.Event{
+			UID:          fmt.Sprintf("note-%d@%s", note.Id, ch.uidDomain),
+			Summary:      note.Title,
+			Description:  note.Excerpt(calendarExcerptSize),
+			URL:          fmt.Sprintf("%s/notes/%d", ch.baseURL, note.Id),
+			Start:        note.DueAt,
+			Duration:     calendarEventDuration,
+			LastModified: note.UpdatedAt,
+		})
+	}
+
+	body := calendar.Build("Quicknotes", events)
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="quicknotes.ics"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+
+	return nil
+}
+
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"fmt"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/mailers"
+	"go_pro/internal/models"
+	"go_pro/internal/render"
+	"go_pro/internal/repositories"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+const maxCommentSize = 5000
+
+type commentHandler struct {
+	render      *render.RenderTemplate
+	session     *scs.SessionManager
+	noteRepo    repositories.NoteRepository
+	commentRepo repositories.CommentRepository
+	mail        mailers.MailService
+}
+
+func NewCommentHandler(render *render.RenderTemplate, session *scs.SessionManager, noteRepo repositories.NoteRepository, commentRepo repositories.CommentRepository, mail mailers.MailService) *commentHandler {
+	return &commentHandler{render: render, session: session, noteRepo: noteRepo, commentRepo: commentRepo, mail: mail}
+}
+
+func (ch *commentHandler) getUserIdFromSession(r *http.Request) int64 {
+	return currentUserId(ch.session, r)
+}
+
+func (ch *commentHandler) getNoteScope(r *http.Request) models.NoteScope {
+	return currentNoteScope(ch.session, r)
+}
+
+// getNoteId returns the id of the note in the path, as long as the current
+// user is allowed to see it.
+func (ch *commentHandler) getNoteId(r *http.Request) (int, error) {
+	noteId, err := strconv.Atoi(r.PathValue("id"))
+
+	if err != nil {
+		return 0, apperrors.ErrorNotFound("note not found")
+	}
+
+	if _, err := ch.noteRepo.GetById(r.Context(), ch.getNoteScope(r), noteId); err != nil {
+		return 0, apperrors.ErrorNotFound("note not found")
+	}
+
+	return noteId, nil
+}
+
+func (ch *commentHandler) validateBody(body string) string {
+	if body == "" {
+		return "O comentário não pode ser vazio"
+	}
+
+	if utf8.RuneCountInString(body) > maxCommentSize {
+		return fmt.Sprintf("O comentário pode ter no máximo %d caracteres", maxCommentSize)
+	}
+
+	return ""
+}
+
+func (ch *commentHandler) CommentCreate(w http.ResponseWriter, r *http.Request) error {
+	noteId, err := ch.getNoteId(r)
+
+	if err != nil {
+		return err
+	}
+
+	userId := int(ch.getUserIdFromSession(r))
+	body := strings.TrimSpace(r.PostFormValue("body"))
+
+	if msg := ch.validateBody(body); msg != "" {
+		ch.session.Put(r.Context(), "commentError", msg)
+		ch.session.Put(r.Context(), "commentBody", body)
+		http.Redirect(w, r, fmt.Sprintf("/notes/%d#comments", noteId), http.StatusSeeOther)
+		return nil
+	}
+
+	comment, err := ch.commentRepo.Create(r.Context(), noteId, userId, body)
+
+	if err != nil {
+		return err
+	}
+
+	ch.notifyOwner(r, noteId, int(comment.Id.Int.Int64()), userId)
+
+	http.Redirect(w, r, fmt.Sprintf("/notes/%d#comment-%d", noteId, comment.Id.Int.Int64()), http.StatusSeeOther)
+	return nil
+}
+
+func (ch *commentHandler) CommentUpdate(w http.ResponseWriter, r *http.Request) error {
+	noteId, err := ch.getNoteId(r)
+
+	if err != nil {
+		return err
+	}
+
+	commentId, err := strconv.Atoi(r.PathValue("commentId"))
+
+	if err != nil {
+		return apperrors.ErrorNotFound("comment not found")
+	}
+
+	body := strings.TrimSpace(r.PostFormValue("body"))
+
+	if msg := ch.validateBody(body); msg != "" {
+		ch.session.Put(r.Context(), "commentError", msg)
+		http.Redirect(w, r, fmt.Sprintf("/notes/%d#comment-%d", noteId, commentId), http.StatusSeeOther)
+		return nil
+	}
+
+	err = ch.commentRepo.Update(r.Context(), noteId, int(ch.getUserIdFromSession(r)), commentId, body)
+
+	if err == repositories.ErrCommentNotFound {
+		return apperrors.ErrorForbidden("only the author can edit this comment")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/notes/%d#comment-%d", noteId, commentId), http.StatusSeeOther)
+	return nil
+}
+
+func (ch *commentHandler) CommentDelete(w http.ResponseWriter, r *http.Request) error {
+	noteId, err := ch.getNoteId(r)
+
+	if err != nil {
+		return err
+	}
+
+	commentId, err := strconv.Atoi(r.PathValue("commentId"))
+
+	if err != nil {
+		return apperrors.ErrorNotFound("comment not found")
+	}
+
+	comment, err := ch.commentRepo.GetById(r.Context(), noteId, commentId)
+
+	if err == repositories.ErrCommentNotFound {
+		return apperrors.ErrorNotFound("comment not found")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	owner, err := ch.commentRepo.GetNoteOwner(r.Context(), noteId)
+
+	if err != nil && err != repositories.ErrNoteOwnerNotFound {
+		return err
+	}
+
+	userId := ch.getUserIdFromSession(r)
+	isOwner := owner != nil && owner.Id.Int.Int64() == userId
+
+	if comment.UserId.Int.Int64() != userId && !isOwner {
+		return apperrors.ErrorForbidden("only the author or the note owner can delete this comment")
+	}
+
+	if err := ch.commentRepo.Delete(r.Context(), commentId); err != nil {
+		return apperrors.ErrorInternalServer("Error deleting comment")
+	}
+
+	return nil
+}
+
+// notifyOwner emails the note owner about a new comment. Only the comments
+// of other members of a workspace reach it: a personal note is only seen by
+// its owner. A failure to send the email is only logged, the comment is
+// already saved.
+func (ch *commentHandler) notifyOwner(r *http.Request, noteId, commentId, authorId int) {
+	owner, err := ch.commentRepo.GetNoteOwner(r.Context(), noteId)
+
+	if err == repositories.ErrNoteOwnerNotFound {
+		return
+	}
+
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	if int(owner.Id.Int.Int64()) == authorId {
+		return
+	}
+
+	comment, err := ch.commentRepo.GetById(r.Context(), noteId, commentId)
+
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	note, err := ch.noteRepo.GetById(r.Context(), ch.getNoteScope(r), noteId)
+
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	mailBody, err := ch.render.RenderMailBody(r, "comment.html", map[string]string{
+		"noteId":    strconv.Itoa(noteId),
+		"noteTitle": note.Title.String,
+		"author":    comment.AuthorName.String,
+		"body":      comment.Body.String,
+	})
+
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	if err := ch.mail.Send(mailers.MailMessage{
+		To:      []string{owner.Email.String},
+		Subject: "Novo comentário na sua anotação",
+		IsHTML:  true,
+		Body:    mailBody,
+	}); err != nil {
+		slog.Error(err.Error())
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"go_pro/internal/dtos"
+	"go_pro/internal/models"
+	"go_pro/internal/render"
+	"go_pro/internal/repositories"
+	"net/http"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+type dashboardHandler struct {
+	render  *render.RenderTemplate
+	session *scs.SessionManager
+	repo    repositories.DashboardRepository
+}
+
+func NewDashboardHandler(render *render.RenderTemplate, session *scs.SessionManager, repo repositories.DashboardRepository) *dashboardHandler {
+	return &dashboardHandler{render: render, session: session, repo: repo}
+}
+
+func (dh *dashboardHandler) getNoteScope(r *http.Request) models.NoteScope {
+	return currentNoteScope(dh.session, r)
+}
+
+func (dh *dashboardHandler) Dashboard(w http.ResponseWriter, r *http.Request) error {
+	dashboard, err := dh.repo.Stats(r.Context(), dh.getNoteScope(r))
+
+	if err != nil {
+		return err
+	}
+
+	return dh.render.RenderPage(w, r, "dashboard.html", dtos.NewDashboardResponse(dashboard), http.StatusOK)
+}
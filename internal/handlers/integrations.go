@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"go_pro/internal/dtos"
+	"go_pro/internal/render"
+	"go_pro/internal/repositories"
+	"go_pro/tools"
+	"net/http"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+type integrationsHandler struct {
+	render       *render.RenderTemplate
+	session      *scs.SessionManager
+	calendarRepo repositories.CalendarRepository
+	userRepo     repositories.UserRepository
+	inboxDomain  string
+	baseURL      string
+}
+
+// NewIntegrationsHandler builds the handler of the integrations page. An empty
+// inboxDomain means the mail inbox is disabled and is hidden from the page.
+// The address of the calendar feed is built from baseURL.
+func NewIntegrationsHandler(render *render.RenderTemplate, session *scs.SessionManager, calendarRepo repositories.CalendarRepository, userRepo repositories.UserRepository, inboxDomain, baseURL string) *integrationsHandler {
+	return &integrationsHandler{render: render, session: session, calendarRepo: calendarRepo, userRepo: userRepo, inboxDomain: inboxDomain, baseURL: baseURL}
+}
+
+func (ih *integrationsHandler) getUserIdFromSession(r *http.Request) int64 {
+	return currentUserId(ih.session, r)
+}
+
+func (ih *integrationsHandler) newIntegrationsResponse(r *http.Request) (dtos.IntegrationsResponse, error) {
+	var err error
+
+	userId := int(ih.getUserIdFromSession(r))
+	data := dtos.IntegrationsResponse{InboxEnabled: ih.inboxDomain != ""}
+
+	if data.CalendarActive, err = ih.calendarRepo.HasToken(r.Context(), userId); err != nil {
+		return data, err
+	}
+
+	if data.InboxEnabled {
+		if data.InboxActive, err = ih.userRepo.HasInboxToken(r.Context(), userId); err != nil {
+			return data, err
+		}
+	}
+
+	return data, nil
+}
+
+func (ih *integrationsHandler) Integrations(w http.ResponseWriter, r *http.Request) error {
+	data, err := ih.newIntegrationsResponse(r)
+
+	if err != nil {
+		return err
+	}
+
+	data.Flash = ih.session.PopString(r.Context(), "flash")
+
+	return ih.render.RenderPage(w, r, "integrations.html", data, http.StatusOK)
+}
+
+func (ih *integrationsHandler) RegenerateCalendarToken(w http.ResponseWriter, r *http.Request) error {
+	token := tools.GenerateToken()
+
+	if err := ih.calendarRepo.RegenerateToken(r.Context(), int(ih.getUserIdFromSession(r)), tools.HashToken(token)); err != nil {
+		return err
+	}
+
+	// the address is only shown now, the token is stored hashed
+	data, err := ih.newIntegrationsResponse(r)
+
+	if err != nil {
+		return err
+	}
+
+	data.CalendarURL = fmt.Sprintf("%s/cal/%s.ics", ih.baseURL, token)
+	data.Flash = "Um novo endereço foi gerado. O endereço anterior deixou de funcionar. Copie-o agora: ele não será mostrado de novo."
+
+	return ih.render.RenderPage(w, r, "integrations.html", data, http.StatusOK)
+}
+
+func (ih *integrationsHandler) RevokeCalendarToken(w http.ResponseWriter, r *http.Request) error {
+	if err := ih.calendarRepo.RevokeToken(r.Context(), int(ih.getUserIdFromSession(r))); err != nil {
+		return err
+	}
+
+	ih.session.Put(r.Context(), "flash", "O endereço do calendário foi revogado.")
+
+	http.Redirect(w, r, "/integrations", http.StatusSeeOther)
+	return nil
+}
+
+func (ih *integrationsHandler) RegenerateInboxToken(w http.ResponseWriter, r *http.Request) error {
+	token := tools.GenerateHexToken()
+
+	if err := ih.userRepo.UpdateInboxToken(r.Context(), int(ih.getUserIdFromSession(r)), tools.HashToken(token)); err != nil {
+		return err
+	}
+
+	// the address is only shown now, the token is stored hashed
+	data, err := ih.newIntegrationsResponse(r)
+
+	if err != nil {
+		return err
+	}
+
+	data.InboxAddress = fmt.Sprintf("%s@%s", token, ih.inboxDomain)
+	data.Flash = "Um novo endereço de email foi gerado. O endereço anterior deixou de funcionar. Copie-o agora: ele não será mostrado de novo."
+
+	return ih.render.RenderPage(w, r, "integrations.html", data, http.StatusOK)
+}
+
+func (ih *integrationsHandler) RevokeInboxToken(w http.ResponseWriter, r *http.Request) error {
+	if err := ih.userRepo.UpdateInboxToken(r.Context(), int(ih.getUserIdFromSession(r)), ""); err != nil {
+		return err
+	}
+
+	ih.session.Put(r.Context(), "flash", "O endereço de email foi desativado.")
+
+	http.Redirect(w, r, "/integrations", http.StatusSeeOther)
+	return nil
+}
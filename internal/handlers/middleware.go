@@ -1,17 +1,52 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"go_pro/internal/apperrors"
+	"go_pro/internal/models"
 	"go_pro/internal/render"
+	"go_pro/internal/repositories"
+	"go_pro/tools"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/alexedwards/scs/v2"
+	"github.com/gorilla/csrf"
 )
 
+type contextKey string
+
+const (
+	tokenUserIdContextKey contextKey = "tokenUserId"
+	tokenScopeContextKey  contextKey = "tokenScope"
+)
+
+// sessionTouchInterval is how often the last use of a session is recorded.
+const sessionTouchInterval = time.Minute
+
 type authMiddleware struct {
-	session *scs.SessionManager
+	session     *scs.SessionManager
+	tokens      repositories.AccessTokenRepository
+	sessions    repositories.SessionRepository
+	users       repositories.UserRepository
+	trustProxy  bool
+	idleTimeout time.Duration
+}
+
+type rateLimitMiddleware struct {
+	render     *render.RenderTemplate
+	limits     repositories.RateLimitRepository
+	trustProxy bool
+}
+
+type rateLimitBucket struct {
+	key   string
+	limit models.RateLimit
 }
 
 type errorHandlerMiddleware struct {
@@ -22,12 +57,48 @@ func NewErrorHandlerMiddleware(render *render.RenderTemplate) *errorHandlerMiddl
 	return &errorHandlerMiddleware{render: render}
 }
 
-func NewAuthMiddleware(session *scs.SessionManager) *authMiddleware {
-	return &authMiddleware{session: session}
+// NewAuthMiddleware builds the authentication middlewares. Sessions that are
+// not remembered end after idleTimeout without use.
+func NewAuthMiddleware(session *scs.SessionManager, tokens repositories.AccessTokenRepository, sessions repositories.SessionRepository, users repositories.UserRepository, trustProxy bool, idleTimeout time.Duration) *authMiddleware {
+	return &authMiddleware{session: session, tokens: tokens, sessions: sessions, users: users, trustProxy: trustProxy, idleTimeout: idleTimeout}
 }
 
+func NewRateLimitMiddleware(render *render.RenderTemplate, limits repositories.RateLimitRepository, trustProxy bool) *rateLimitMiddleware {
+	return &rateLimitMiddleware{render: render, limits: limits, trustProxy: trustProxy}
+}
+
+// currentUserId returns the user of the request: the owner of the access
+// token when the request carries one, the user of the session otherwise.
+func currentUserId(session *scs.SessionManager, r *http.Request) int64 {
+	if userId, ok := r.Context().Value(tokenUserIdContextKey).(int64); ok {
+		return userId
+	}
+
+	return session.GetInt64(r.Context(), "userId")
+}
+
+// currentNoteScope returns the notes a request works on, the ones of the
+// workspace kept in the session or the personal ones. Requests made with an
+// access token only reach personal notes.
+func currentNoteScope(session *scs.SessionManager, r *http.Request) models.NoteScope {
+	scope := models.NoteScope{UserId: int(currentUserId(session, r))}
+
+	if _, ok := r.Context().Value(tokenUserIdContextKey).(int64); !ok {
+		scope.WorkspaceId = int(session.GetInt64(r.Context(), "workspaceId"))
+	}
+
+	return scope
+}
+
+// RequireAuth only accepts signed in users. Requests made with an access
+// token are refused, the pages behind it manage the account itself.
 func (ah *authMiddleware) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(tokenUserIdContextKey).(int64); ok {
+			http.Error(w, "access tokens are not allowed here", http.StatusForbidden)
+			return
+		}
+
 		userId := ah.session.GetInt64(r.Context(), "userId")
 
 		if userId == 0 {
@@ -36,10 +107,250 @@ func (ah *authMiddleware) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		if ah.idleExpired(r) {
+			if err := ah.endSession(r); err != nil {
+				slog.Error(err.Error())
+			}
+
+			ah.session.Put(r.Context(), "flash", "Sua sessão expirou por inatividade. Entre novamente.")
+			http.Redirect(w, r, "/user/signin", http.StatusSeeOther)
+			return
+		}
+
+		ah.touchSession(r)
+
 		next.ServeHTTP(w, r)
 	})
 }
 
+// RequireRole only accepts signed in users with the given role. The role is
+// read from the database on every request, so a demotion applies at once.
+func (ah *authMiddleware) RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return ah.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := ah.users.FindById(r.Context(), int(ah.session.GetInt64(r.Context(), "userId")))
+
+			if err != nil {
+				slog.Error(err.Error())
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			if user.Role.String != role {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// idleExpired tells if a session that is not remembered went unused for
+// longer than the idle timeout.
+func (ah *authMiddleware) idleExpired(r *http.Request) bool {
+	if ah.session.GetBool(r.Context(), "remember") {
+		return false
+	}
+
+	seenAt := ah.session.GetInt64(r.Context(), "sessionSeenAt")
+
+	return seenAt > 0 && time.Since(time.Unix(seenAt, 0)) > ah.idleTimeout
+}
+
+func (ah *authMiddleware) endSession(r *http.Request) error {
+	if err := ah.sessions.Remove(r.Context(), ah.session.Token(r.Context())); err != nil {
+		return err
+	}
+
+	if err := ah.session.RenewToken(r.Context()); err != nil {
+		return err
+	}
+
+	ah.session.Remove(r.Context(), "userId")
+	ah.session.Remove(r.Context(), "userEmail")
+	ah.session.Remove(r.Context(), "userRole")
+	ah.session.Remove(r.Context(), "workspaceId")
+	ah.session.Remove(r.Context(), "sessionSeenAt")
+
+	return nil
+}
+
+// touchSession updates when and from where the session was last used, at
+// most once per sessionTouchInterval. A failure is only logged.
+func (ah *authMiddleware) touchSession(r *http.Request) {
+	now := time.Now()
+
+	if now.Unix()-ah.session.GetInt64(r.Context(), "sessionSeenAt") < int64(sessionTouchInterval.Seconds()) {
+		return
+	}
+
+	ah.session.Put(r.Context(), "sessionSeenAt", now.Unix())
+
+	if err := ah.sessions.Touch(r.Context(), ah.session.Token(r.Context()), tools.ClientIP(r, ah.trustProxy)); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+// RequireAuthOrToken accepts signed in users and requests authenticated by
+// AuthenticateToken. A read token only reaches the safe methods.
+func (ah *authMiddleware) RequireAuthOrToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := r.Context().Value(tokenScopeContextKey).(string)
+
+		if !ok {
+			ah.RequireAuth(next).ServeHTTP(w, r)
+			return
+		}
+
+		if scope != models.AccessTokenScopeWrite && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="write"`)
+			http.Error(w, "the access token does not have the write scope", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AuthenticateToken authenticates requests carrying an "Authorization: Bearer"
+// personal access token. It must wrap the CSRF middleware: the header cannot
+// be sent by a cross-site form, so those requests skip the CSRF check.
+func (ah *authMiddleware) AuthenticateToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+
+		if authorization == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, found := strings.CutPrefix(authorization, "Bearer ")
+
+		if !found || strings.TrimSpace(token) == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_request"`)
+			http.Error(w, "invalid authorization header", http.StatusUnauthorized)
+			return
+		}
+
+		userId, scope, err := ah.tokens.Use(r.Context(), tools.HashToken(strings.TrimSpace(token)))
+
+		if err == repositories.ErrAccessTokenNotFound {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, "invalid or expired access token", http.StatusUnauthorized)
+			return
+		}
+
+		if err != nil {
+			slog.Error(err.Error())
+			http.Error(w, "an error occurred while executing this operation", http.StatusInternalServerError)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), tokenUserIdContextKey, int64(userId))
+		ctx = context.WithValue(ctx, tokenScopeContextKey, scope)
+
+		next.ServeHTTP(w, csrf.UnsafeSkipCheck(r.WithContext(ctx)))
+	})
+}
+
+// Limit throttles the requests of a form by client address and by the email
+// it was sent with, so neither one address nor one account can be hammered.
+// The buckets of each form are kept apart by name.
+func (rl *rateLimitMiddleware) Limit(name string, limits models.FormRateLimit) func(http.Handler) http.Handler {
+	return rl.LimitBy(name, limits, func(r *http.Request) string {
+		if email := strings.ToLower(strings.TrimSpace(r.PostFormValue("email"))); email != "" {
+			return "email:" + email
+		}
+
+		return ""
+	})
+}
+
+// LimitBy works like Limit, with the account of the request told by account
+// instead of the email field. The ByEmail limit applies to it. An empty
+// account, or a zero ByEmail limit, only counts by address.
+func (rl *rateLimitMiddleware) LimitBy(name string, limits models.FormRateLimit, account func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buckets := []rateLimitBucket{
+				{key: name + ":ip:" + tools.ClientIP(r, rl.trustProxy), limit: limits.ByIP},
+			}
+
+			if key := account(r); key != "" && limits.ByEmail.Requests > 0 {
+				buckets = append(buckets, rateLimitBucket{key: name + ":" + key, limit: limits.ByEmail})
+			}
+
+			for _, b := range buckets {
+				allowed, retryAfter, err := rl.limits.Take(r.Context(), b.key, b.limit)
+
+				// Failing open: the forms keep working when the
+				// buckets cannot be read.
+				if err != nil {
+					slog.Error(err.Error())
+					break
+				}
+
+				if !allowed {
+					slog.Warn("rate limit exceeded", "key", b.key)
+					rl.tooManyRequests(w, r, retryAfter)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (rl *rateLimitMiddleware) tooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	msg := "Muitas tentativas em pouco tempo. Tente novamente em " + formatRetryAfter(seconds) + "."
+	rl.render.RenderPage(w, r, "generic-error.html", msg, http.StatusTooManyRequests)
+}
+
+func formatRetryAfter(seconds int) string {
+	if seconds == 1 {
+		return "1 segundo"
+	}
+
+	if seconds < 60 {
+		return strconv.Itoa(seconds) + " segundos"
+	}
+
+	minutes := (seconds + 59) / 60
+
+	if minutes == 1 {
+		return "1 minuto"
+	}
+
+	return strconv.Itoa(minutes) + " minutos"
+}
+
+// LimitRequestBody refuses bodies larger than max bytes before anything
+// reads them. The csrf check parses the whole form, uploads included, ahead
+// of the handlers.
+func LimitRequestBody(max int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > max {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, max)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (ehm *errorHandlerMiddleware) HandlerError(next func(w http.ResponseWriter, r *http.Request) error) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if err := next(w, r); err != nil {
@@ -52,6 +363,12 @@ func (ehm *errorHandlerMiddleware) HandlerError(next func(w http.ResponseWriter,
 					ehm.render.RenderPage(w, r, "404.html", nil, http.StatusNotFound)
 					return
 				}
+
+				if statusError.HTTPStatus() < http.StatusInternalServerError {
+					slog.Warn(err.Error())
+					ehm.render.RenderPage(w, r, "generic-error.html", err.Error(), statusError.HTTPStatus())
+					return
+				}
 			}
 
 			if errors.As(err, &repoError) {
@@ -1,42 +1,68 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"go_pro/internal/apperrors"
 	"go_pro/internal/dtos"
 	"go_pro/internal/models"
+	"go_pro/internal/passwords"
 	"go_pro/internal/render"
 	"go_pro/internal/repositories"
+	"go_pro/tools"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/gorilla/csrf"
 )
 
 type noteHandler struct {
-	render  *render.RenderTemplate
-	session *scs.SessionManager
-	repo    repositories.NoteRepository
+	render       *render.RenderTemplate
+	session      *scs.SessionManager
+	repo         repositories.NoteRepository
+	commentRepo  repositories.CommentRepository
+	quotaRepo    repositories.QuotaRepository
+	passwords    *passwords.Checker
+	hasher       *passwords.Hasher
+	unlockWindow time.Duration
+	sessionKey   []byte
 }
 
-func NewNoteHandler(render *render.RenderTemplate, session *scs.SessionManager, repo repositories.NoteRepository) *noteHandler {
-	return &noteHandler{repo: repo, render: render, session: session}
+// NewNoteHandler builds the handler of the notes. sessionKey seals the keys
+// of unlocked notes kept in the session.
+func NewNoteHandler(render *render.RenderTemplate, session *scs.SessionManager, repo repositories.NoteRepository, commentRepo repositories.CommentRepository, quotaRepo repositories.QuotaRepository, checker *passwords.Checker, hasher *passwords.Hasher, unlockWindow time.Duration, sessionKey []byte) *noteHandler {
+	return &noteHandler{repo: repo, render: render, session: session, commentRepo: commentRepo, quotaRepo: quotaRepo, passwords: checker, hasher: hasher, unlockWindow: unlockWindow, sessionKey: sessionKey}
 }
 
 func (nh *noteHandler) getUserIdFromSession(r *http.Request) int64 {
-	return nh.session.GetInt64(r.Context(), "userId")
+	return currentUserId(nh.session, r)
+}
+
+func (nh *noteHandler) getNoteScope(r *http.Request) models.NoteScope {
+	return currentNoteScope(nh.session, r)
 }
 
 func (nh *noteHandler) NoteList(w http.ResponseWriter, r *http.Request) error {
-	notes, err := nh.repo.List(r.Context(), int(nh.getUserIdFromSession(r)))
+	if sort := r.URL.Query().Get("sort"); dtos.IsValidNoteSort(sort) {
+		nh.session.Put(r.Context(), "noteSort", sort)
+	}
+
+	sort := nh.session.GetString(r.Context(), "noteSort")
+
+	if !dtos.IsValidNoteSort(sort) {
+		sort = models.NoteSortCreated
+	}
+
+	notes, err := nh.repo.List(r.Context(), nh.getNoteScope(r), sort)
 
 	if err != nil {
 		return err
 	}
 
-	if err = nh.render.RenderPage(w, r, "note-home.html", dtos.NewNoteResponseFromNoteList(notes), http.StatusOK); err != nil {
+	if err = nh.render.RenderPage(w, r, "note-home.html", dtos.NewNoteListResponse(notes, sort), http.StatusOK); err != nil {
 		return err
 	}
 
@@ -56,13 +82,66 @@ func (nh *noteHandler) NoteView(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	note, err := nh.repo.GetById(r.Context(), int(nh.getUserIdFromSession(r)), id)
+	userId := int(nh.getUserIdFromSession(r))
+	note, err := nh.repo.GetById(r.Context(), nh.getNoteScope(r), id)
+
+	if err != nil {
+		return err
+	}
+
+	var content string
+
+	if note.Locked.Bool {
+		var ok bool
+
+		if content, ok = nh.decryptNote(r, note); !ok {
+			data := dtos.NewNoteViewResponse(note, nil, userId, true)
+			data.Flash = nh.session.PopString(r.Context(), "flash")
+
+			if msg := nh.session.PopString(r.Context(), "lockError"); msg != "" {
+				data.AddFieldError("password", msg)
+			}
+
+			return nh.render.RenderPage(w, r, "note-unlock.html", data, http.StatusOK)
+		}
+	}
+
+	comments, err := nh.commentRepo.ListByNote(r.Context(), id)
 
 	if err != nil {
 		return err
 	}
 
-	if err = nh.render.RenderPage(w, r, "note-view.html", dtos.NewNoteResponseFromNote(note), http.StatusOK); err != nil {
+	owner, err := nh.commentRepo.GetNoteOwner(r.Context(), id)
+
+	if err != nil && err != repositories.ErrNoteOwnerNotFound {
+		return err
+	}
+
+	access, err := nh.repo.GetAccess(r.Context(), nh.getNoteScope(r), id)
+
+	if err != nil {
+		return err
+	}
+
+	data := dtos.NewNoteViewResponse(note, comments, userId, owner != nil && int(owner.Id.Int.Int64()) == userId)
+	data.CanManage = access.CanManage()
+	data.CommentBody = nh.session.PopString(r.Context(), "commentBody")
+	data.Flash = nh.session.PopString(r.Context(), "flash")
+
+	if note.Locked.Bool {
+		data.Content = content
+	}
+
+	if msg := nh.session.PopString(r.Context(), "lockError"); msg != "" {
+		data.AddFieldError("lock", msg)
+	}
+
+	if msg := nh.session.PopString(r.Context(), "commentError"); msg != "" {
+		data.AddFieldError("comment", msg)
+	}
+
+	if err = nh.render.RenderPage(w, r, "note-view.html", data, http.StatusOK); err != nil {
 		return err
 	}
 
@@ -91,23 +170,39 @@ func (nh *noteHandler) NoteSave(w http.ResponseWriter, r *http.Request) error {
 	title := r.PostForm.Get("title")
 	content := r.PostForm.Get("content")
 	color := r.PostForm.Get("color")
+	dueAtParam := r.PostForm.Get("due_at")
 	data := dtos.NewNoteRequest(nil)
 
 	data.Color = color
 	data.Content = content
 	data.Title = title
 	data.Id = id
+	data.DueAt = dueAtParam
 
 	if strings.TrimSpace(content) == "" {
 		data.AddFieldError("content", "content is required")
 	}
 
-	if !data.Valid() {
-		if id > 0 {
-			nh.render.RenderPage(w, r, "note-edit.html", data, http.StatusUnprocessableEntity)
+	var dueAt *time.Time
+
+	if dueAtParam != "" {
+		parsed, err := time.Parse(dtos.DueAtLayout, dueAtParam)
+
+		if err != nil {
+			data.AddFieldError("due_at", "invalid due date")
 		} else {
-			nh.render.RenderPage(w, r, "note-new.html", data, http.StatusUnprocessableEntity)
+			dueAt = &parsed
 		}
+	}
+
+	form := "note-new.html"
+
+	if id > 0 {
+		form = "note-edit.html"
+	}
+
+	if !data.Valid() {
+		nh.render.RenderPage(w, r, form, data, http.StatusUnprocessableEntity)
 
 		return nil
 	}
@@ -116,9 +211,42 @@ func (nh *noteHandler) NoteSave(w http.ResponseWriter, r *http.Request) error {
 	var err error
 
 	if id > 0 {
-		note, err = nh.repo.Update(r.Context(), int(nh.getUserIdFromSession(r)), id, title, content, color)
+		var current *models.Note
+
+		if current, err = nh.repo.GetById(r.Context(), nh.getNoteScope(r), id); err != nil {
+			return err
+		}
+
+		if current.Locked.Bool {
+			key := nh.unlockedKey(r, id)
+
+			if key == nil {
+				return apperrors.ErrorForbidden("unlock the note before editing it")
+			}
+
+			if content, err = tools.Encrypt(key, content); err != nil {
+				return err
+			}
+		}
+
+		if err = nh.checkUpdateQuota(r, current, title, content); err == nil {
+			note, err = nh.repo.Update(r.Context(), nh.getNoteScope(r), id, title, content, color, dueAt)
+		}
 	} else {
-		note, err = nh.repo.Create(r.Context(), int(nh.getUserIdFromSession(r)), title, content, color)
+		note, err = nh.repo.Create(r.Context(), nh.getNoteScope(r), title, content, color, dueAt)
+
+		if err == repositories.ErrNoteNotFound {
+			return apperrors.ErrorNotFound("workspace not found")
+		}
+	}
+
+	if repositories.IsQuotaError(err) {
+		var statusError apperrors.StatusError
+
+		errors.As(err, &statusError)
+		data.AddFieldError("content", err.Error())
+
+		return nh.render.RenderPage(w, r, form, data, statusError.HTTPStatus())
 	}
 
 	if err != nil {
@@ -130,6 +258,24 @@ func (nh *noteHandler) NoteSave(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// checkUpdateQuota validates an edit against the quota the note counts in. New notes
+// are checked by the repository, together with the note count.
+func (nh *noteHandler) checkUpdateQuota(r *http.Request, current *models.Note, title, content string) error {
+	usage, err := nh.quotaRepo.Usage(r.Context(), nh.getNoteScope(r))
+
+	if err != nil {
+		return err
+	}
+
+	if title == "" {
+		title = current.Title.String
+	}
+
+	oldSize := models.NoteSize(current.Title.String, current.Content.String)
+
+	return repositories.CheckQuota(usage, false, oldSize, models.NoteSize(title, content))
+}
+
 func (nh *noteHandler) NoteDelete(w http.ResponseWriter, r *http.Request) error {
 	idParam := r.PathValue("id")
 
@@ -143,7 +289,11 @@ func (nh *noteHandler) NoteDelete(w http.ResponseWriter, r *http.Request) error
 		return err
 	}
 
-	err = nh.repo.Delete(r.Context(), int(nh.getUserIdFromSession(r)), id)
+	if err := nh.requireManage(r, id); err != nil {
+		return err
+	}
+
+	err = nh.repo.Delete(r.Context(), nh.getNoteScope(r), id)
 
 	if err != nil {
 		return apperrors.ErrorInternalServer("Error deleting note")
@@ -165,15 +315,52 @@ func (nh *noteHandler) NoteEdit(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	note, err := nh.repo.GetById(r.Context(), int(nh.getUserIdFromSession(r)), id)
+	note, err := nh.repo.GetById(r.Context(), nh.getNoteScope(r), id)
 
 	if err != nil {
 		return err
 	}
 
-	if err = nh.render.RenderPage(w, r, "note-edit.html", dtos.NewNoteRequest(note), http.StatusOK); err != nil {
+	data := dtos.NewNoteRequest(note)
+
+	if note.Locked.Bool {
+		content, ok := nh.decryptNote(r, note)
+
+		if !ok {
+			http.Redirect(w, r, fmt.Sprintf("/notes/%d", id), http.StatusSeeOther)
+			return nil
+		}
+
+		data.Content = content
+	}
+
+	if err = nh.render.RenderPage(w, r, "note-edit.html", data, http.StatusOK); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (nh *noteHandler) NoteMove(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+
+	if err != nil {
+		return apperrors.ErrorNotFound("note not found")
+	}
+
+	afterId, _ := strconv.Atoi(r.PostFormValue("after"))
+	beforeId, _ := strconv.Atoi(r.PostFormValue("before"))
+
+	err = nh.repo.Move(r.Context(), nh.getNoteScope(r), id, afterId, beforeId)
+
+	if err == repositories.ErrNoteNotFound {
+		return apperrors.ErrorNotFound("note not found")
+	}
+
+	if err != nil {
 		return err
 	}
 
+	w.WriteHeader(http.StatusNoContent)
 	return nil
 }
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/models"
+	"go_pro/internal/repositories"
+	"go_pro/internal/validations"
+	"go_pro/tools"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// NoteUnlockAccount keys the rate limit of the unlock form by user and note,
+// so the passwords tried on a note are counted whatever address they come
+// from.
+func NoteUnlockAccount(session *scs.SessionManager) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if userId := currentUserId(session, r); userId != 0 {
+			return fmt.Sprintf("user:%d:note:%s", userId, r.PathValue("id"))
+		}
+
+		return ""
+	}
+}
+
+func noteKeySessionKey(id int) string {
+	return fmt.Sprintf("noteKey:%d", id)
+}
+
+func noteUnlockedUntilSessionKey(id int) string {
+	return fmt.Sprintf("noteUnlockedUntil:%d", id)
+}
+
+// unlockedKey returns the key of a locked note while its unlock window is
+// still open in the current session, or nil otherwise.
+func (nh *noteHandler) unlockedKey(r *http.Request, id int) []byte {
+	until := nh.session.GetInt64(r.Context(), noteUnlockedUntilSessionKey(id))
+
+	if until == 0 {
+		return nil
+	}
+
+	if time.Now().Unix() > until {
+		nh.forgetKey(r, id)
+		return nil
+	}
+
+	sealed, err := tools.Decrypt(nh.sessionKey, nh.session.GetString(r.Context(), noteKeySessionKey(id)))
+
+	if err != nil {
+		nh.forgetKey(r, id)
+		return nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(sealed)
+
+	if err != nil {
+		nh.forgetKey(r, id)
+		return nil
+	}
+
+	return key
+}
+
+// rememberKey keeps the key of the note in the session until the unlock
+// window ends. The key is sealed with the session key of the server, so the
+// sessions table alone does not open the notes stored next to it.
+func (nh *noteHandler) rememberKey(r *http.Request, id int, key []byte) error {
+	sealed, err := tools.Encrypt(nh.sessionKey, base64.StdEncoding.EncodeToString(key))
+
+	if err != nil {
+		return err
+	}
+
+	nh.session.Put(r.Context(), noteKeySessionKey(id), sealed)
+	nh.session.Put(r.Context(), noteUnlockedUntilSessionKey(id), time.Now().Add(nh.unlockWindow).Unix())
+
+	return nil
+}
+
+func (nh *noteHandler) forgetKey(r *http.Request, id int) {
+	nh.session.Remove(r.Context(), noteKeySessionKey(id))
+	nh.session.Remove(r.Context(), noteUnlockedUntilSessionKey(id))
+}
+
+func (nh *noteHandler) decryptNote(r *http.Request, note *models.Note) (string, bool) {
+	id := int(note.Id.Int.Int64())
+	key := nh.unlockedKey(r, id)
+
+	if key == nil {
+		return "", false
+	}
+
+	content, err := tools.Decrypt(key, note.Content.String)
+
+	if err != nil {
+		nh.forgetKey(r, id)
+		return "", false
+	}
+
+	return content, true
+}
+
+func (nh *noteHandler) getNote(r *http.Request) (*models.Note, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+
+	if err != nil {
+		return nil, apperrors.ErrorNotFound("note not found")
+	}
+
+	note, err := nh.repo.GetById(r.Context(), nh.getNoteScope(r), id)
+
+	if err != nil {
+		return nil, apperrors.ErrorNotFound("note not found")
+	}
+
+	return note, nil
+}
+
+// requireManage refuses the request unless the current user may manage the
+// note: see models.NoteAccess.CanManage.
+func (nh *noteHandler) requireManage(r *http.Request, id int) error {
+	access, err := nh.repo.GetAccess(r.Context(), nh.getNoteScope(r), id)
+
+	if err == repositories.ErrNoteNotFound {
+		return apperrors.ErrorNotFound("note not found")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !access.CanManage() {
+		return apperrors.ErrorForbidden("only the author or an admin of the workspace can do this")
+	}
+
+	return nil
+}
+
+func (nh *noteHandler) NoteUnlock(w http.ResponseWriter, r *http.Request) error {
+	note, err := nh.getNote(r)
+
+	if err != nil {
+		return err
+	}
+
+	id := int(note.Id.Int.Int64())
+	password := r.PostFormValue("password")
+
+	lock, err := nh.repo.GetLock(r.Context(), nh.getNoteScope(r), id)
+
+	if err != nil {
+		return err
+	}
+
+	if ok, _ := nh.hasher.Verify(password, lock.Password.String); !ok {
+		nh.session.Put(r.Context(), "lockError", "Senha da anotação inválida")
+		http.Redirect(w, r, fmt.Sprintf("/notes/%d", id), http.StatusSeeOther)
+		return nil
+	}
+
+	if err := nh.rememberKey(r, id, tools.DeriveKey(password, lock.Salt)); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/notes/%d", id), http.StatusSeeOther)
+	return nil
+}
+
+func (nh *noteHandler) NoteLock(w http.ResponseWriter, r *http.Request) error {
+	note, err := nh.getNote(r)
+
+	if err != nil {
+		return err
+	}
+
+	id := int(note.Id.Int.Int64())
+	password := r.PostFormValue("password")
+	confirm := r.PostFormValue("password-confirm")
+
+	if note.Locked.Bool {
+		return apperrors.ErrorBadRequest("note is already locked")
+	}
+
+	if err := nh.requireManage(r, id); err != nil {
+		return err
+	}
+
+	// the note password gets the same policy as the account one: a locked
+	// note can be attacked offline by anyone who reads the database
+	var form validations.FormValidator
+
+	if password != confirm {
+		form.AddFieldError("lock", "As senhas precisam ser iguais")
+	} else {
+		nh.passwords.Validate(&form, "lock", password, nh.session.GetString(r.Context(), "userEmail"))
+	}
+
+	if !form.Valid() {
+		nh.session.Put(r.Context(), "lockError", form.FieldErrors["lock"])
+		http.Redirect(w, r, fmt.Sprintf("/notes/%d", id), http.StatusSeeOther)
+		return nil
+	}
+
+	hash, err := nh.hasher.Hash(password)
+
+	if err != nil {
+		return err
+	}
+
+	salt := tools.GenerateSalt()
+	key := tools.DeriveKey(password, salt)
+	content, err := tools.Encrypt(key, note.Content.String)
+
+	if err != nil {
+		return err
+	}
+
+	if err := nh.repo.Lock(r.Context(), nh.getNoteScope(r), id, hash, salt, content); err != nil {
+		return err
+	}
+
+	if err := nh.rememberKey(r, id, key); err != nil {
+		return err
+	}
+	nh.session.Put(r.Context(), "flash", "Anotação bloqueada.")
+
+	http.Redirect(w, r, fmt.Sprintf("/notes/%d", id), http.StatusSeeOther)
+	return nil
+}
+
+func (nh *noteHandler) NoteRemoveLock(w http.ResponseWriter, r *http.Request) error {
+	note, err := nh.getNote(r)
+
+	if err != nil {
+		return err
+	}
+
+	id := int(note.Id.Int.Int64())
+
+	if !note.Locked.Bool {
+		return apperrors.ErrorBadRequest("note is not locked")
+	}
+
+	if err := nh.requireManage(r, id); err != nil {
+		return err
+	}
+
+	content, ok := nh.decryptNote(r, note)
+
+	if !ok {
+		return apperrors.ErrorForbidden("unlock the note before removing the lock")
+	}
+
+	if err := nh.repo.RemoveLock(r.Context(), nh.getNoteScope(r), id, content); err != nil {
+		return err
+	}
+
+	nh.forgetKey(r, id)
+	nh.session.Put(r.Context(), "flash", "O bloqueio da anotação foi removido.")
+
+	http.Redirect(w, r, fmt.Sprintf("/notes/%d", id), http.StatusSeeOther)
+	return nil
+}
+
+func (nh *noteHandler) NoteRelock(w http.ResponseWriter, r *http.Request) error {
+	note, err := nh.getNote(r)
+
+	if err != nil {
+		return err
+	}
+
+	id := int(note.Id.Int.Int64())
+	nh.forgetKey(r, id)
+
+	http.Redirect(w, r, fmt.Sprintf("/notes/%d", id), http.StatusSeeOther)
+	return nil
+}
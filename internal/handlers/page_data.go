@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"go_pro/internal/dtos"
+	"go_pro/internal/render"
+	"go_pro/internal/repositories"
+	"log/slog"
+	"net/http"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+type pageDataMiddleware struct {
+	session    *scs.SessionManager
+	quotas     repositories.QuotaRepository
+	profiles   repositories.ProfileRepository
+	workspaces repositories.WorkspaceRepository
+}
+
+func NewPageDataMiddleware(session *scs.SessionManager, quotas repositories.QuotaRepository, profiles repositories.ProfileRepository, workspaces repositories.WorkspaceRepository) *pageDataMiddleware {
+	return &pageDataMiddleware{session: session, quotas: quotas, profiles: profiles, workspaces: workspaces}
+}
+
+// Load gives every request the loader of what the layout shows of the signed
+// in user. The queries only run when a page is rendered.
+func (pm *pageDataMiddleware) Load(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, render.WithPageData(r, func() *render.PageData {
+			return pm.load(r)
+		}))
+	})
+}
+
+// load reads the profile, the quota meter and the workspace switcher of the
+// signed in user. A part that could not be read is left out: none of them is
+// worth an error page.
+func (pm *pageDataMiddleware) load(r *http.Request) *render.PageData {
+	data := &render.PageData{}
+	userId := int(pm.session.GetInt64(r.Context(), "userId"))
+
+	if userId == 0 {
+		return data
+	}
+
+	if profile, err := pm.profiles.Get(r.Context(), userId); err != nil {
+		slog.Error(err.Error())
+	} else {
+		data.Profile = dtos.NewProfileResponse(profile)
+	}
+
+	// a workspace the user left has no quota to show
+	if usage, err := pm.quotas.Usage(r.Context(), currentNoteScope(pm.session, r)); err == nil {
+		data.Quota = dtos.NewQuotaUsageResponse(usage)
+	} else if err != repositories.ErrNoteNotFound {
+		slog.Error(err.Error())
+	}
+
+	workspaces, err := pm.workspaces.ListForUser(r.Context(), userId)
+
+	if err != nil {
+		slog.Error(err.Error())
+		return data
+	}
+
+	data.Switcher = &dtos.WorkspaceSwitcherResponse{Workspaces: dtos.NewWorkspaceListResponse(workspaces)}
+	active := int(pm.session.GetInt64(r.Context(), "workspaceId"))
+
+	// a workspace the user left is not shown as active
+	for _, workspace := range data.Switcher.Workspaces {
+		if workspace.Id == active {
+			data.Switcher.ActiveId = active
+		}
+	}
+
+	return data
+}
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"fmt"
+	"go_pro/internal/apperrors"
 	"go_pro/internal/dtos"
 	"go_pro/internal/mailers"
+	"go_pro/internal/models"
+	"go_pro/internal/passwords"
 	"go_pro/internal/render"
 	"go_pro/internal/repositories"
 	"go_pro/tools"
@@ -16,14 +19,35 @@ import (
 )
 
 type userHandler struct {
-	render  *render.RenderTemplate
-	session *scs.SessionManager
-	repo    repositories.UserRepository
-	mail    mailers.MailService
+	render      *render.RenderTemplate
+	session     *scs.SessionManager
+	repo        repositories.UserRepository
+	sessionRepo repositories.SessionRepository
+	accountRepo repositories.AccountRepository
+	twoFactor   repositories.TwoFactorRepository
+	security    repositories.LoginSecurityRepository
+	sso         *SSOProvider
+	mail        mailers.MailService
+	passwords   *passwords.Checker
+	hasher      *passwords.Hasher
+	lockout     models.LockoutPolicy
+	magicLink   models.MagicLinkPolicy
+	sessions    models.SessionPolicy
+	trustProxy  bool
 }
 
-func NewUserHandler(render *render.RenderTemplate, session *scs.SessionManager, repo repositories.UserRepository, mail mailers.MailService) *userHandler {
-	return &userHandler{repo: repo, session: session, render: render, mail: mail}
+// NewUserHandler builds the sign up and sign in handlers. A nil sso disables
+// the sign in with an external provider.
+func NewUserHandler(render *render.RenderTemplate, session *scs.SessionManager, repo repositories.UserRepository, sessionRepo repositories.SessionRepository, accountRepo repositories.AccountRepository, twoFactor repositories.TwoFactorRepository, security repositories.LoginSecurityRepository, sso *SSOProvider, mail mailers.MailService, passwords *passwords.Checker, hasher *passwords.Hasher, lockout models.LockoutPolicy, magicLink models.MagicLinkPolicy, sessions models.SessionPolicy, trustProxy bool) *userHandler {
+	return &userHandler{repo: repo, session: session, sessionRepo: sessionRepo, accountRepo: accountRepo, twoFactor: twoFactor, security: security, sso: sso, render: render, mail: mail, passwords: passwords, hasher: hasher, lockout: lockout, magicLink: magicLink, sessions: sessions, trustProxy: trustProxy}
+}
+
+func (uh *userHandler) renderSignin(w http.ResponseWriter, r *http.Request, data dtos.UserRequest, status int) error {
+	if uh.sso != nil {
+		data.SSOProvider = uh.sso.Name
+	}
+
+	return uh.render.RenderPage(w, r, "user-signin.html", data, status)
 }
 
 func (uh *userHandler) SigninForm(w http.ResponseWriter, r *http.Request) error {
@@ -31,7 +55,10 @@ func (uh *userHandler) SigninForm(w http.ResponseWriter, r *http.Request) error
 
 	fmt.Println("USER ID: ", userId)
 
-	return uh.render.RenderPage(w, r, "user-signin.html", nil, http.StatusOK)
+	data := dtos.UserRequest{}
+	data.Flash = uh.session.PopString(r.Context(), "flash")
+
+	return uh.renderSignin(w, r, data, http.StatusOK)
 }
 
 func (uh *userHandler) Signin(w http.ResponseWriter, r *http.Request) error {
@@ -53,7 +80,7 @@ func (uh *userHandler) Signin(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	if !user.Valid() {
-		uh.render.RenderPage(w, r, "user-signin.html", user, http.StatusUnprocessableEntity)
+		uh.renderSignin(w, r, user, http.StatusUnprocessableEntity)
 		return nil
 	}
 
@@ -61,31 +88,131 @@ func (uh *userHandler) Signin(w http.ResponseWriter, r *http.Request) error {
 
 	if err != nil {
 		user.AddFieldError("validation", "invalid credentials")
-		return uh.render.RenderPage(w, r, "user-signin.html", user, http.StatusUnprocessableEntity)
+		return uh.renderSignin(w, r, user, http.StatusUnprocessableEntity)
 	}
 
 	if !data.Active.Bool {
+		user.Unconfirmed = true
 		user.AddFieldError("validation", "user did not confirm registration")
-		return uh.render.RenderPage(w, r, "user-signin.html", user, http.StatusUnprocessableEntity)
+		return uh.renderSignin(w, r, user, http.StatusUnprocessableEntity)
 	}
 
-	if !tools.ValidatePassword(user.Password, data.Password.String) {
+	lockout, err := uh.security.GetLockout(r.Context(), int(data.Id.Int.Int64()))
+
+	if err != nil {
+		return err
+	}
+
+	if lockout.Remaining > 0 {
+		return uh.renderLocked(w, r, user, lockout.Remaining)
+	}
+
+	ok, rehash := uh.hasher.Verify(user.Password, data.Password.String)
+
+	if !ok {
+		if err := uh.recordFailedSignin(r, data); err != nil {
+			return err
+		}
+
 		user.AddFieldError("validation", "invalid credentials")
-		return uh.render.RenderPage(w, r, "user-signin.html", user, http.StatusUnprocessableEntity)
+		return uh.renderSignin(w, r, user, http.StatusUnprocessableEntity)
 	}
 
-	if err := uh.session.RenewToken(r.Context()); err != nil {
+	if rehash {
+		uh.rehashPassword(r, data, user.Password)
+	}
+
+	if data.DisabledAt.Valid {
+		user.AddFieldError("validation", "account disabled")
+		return uh.renderSignin(w, r, user, http.StatusForbidden)
+	}
+
+	remember := r.PostFormValue("remember") != ""
+
+	if data.TOTPEnabled.Bool {
+		return uh.startTwoFactor(w, r, data, remember)
+	}
+
+	return uh.finishSignin(w, r, data, remember)
+}
+
+// rehashPassword replaces the stored hash of the user, a bcrypt one or one
+// with outdated costs, by a hash of the current kind. The sign in goes on if
+// it fails, the next one tries again.
+func (uh *userHandler) rehashPassword(r *http.Request, user *models.User, password string) {
+	hash, err := uh.hasher.Hash(password)
+
+	if err == nil {
+		err = uh.repo.RehashPassword(r.Context(), int(user.Id.Int.Int64()), user.Password.String, hash)
+	}
+
+	if err != nil {
 		slog.Error(err.Error())
+	}
+}
+
+// finishSignin signs the user in once every check passed and sends them to
+// their notes.
+func (uh *userHandler) finishSignin(w http.ResponseWriter, r *http.Request, user *models.User, remember bool) error {
+	if user.DisabledAt.Valid {
+		return apperrors.ErrorForbidden("esta conta foi desativada")
+	}
+
+	if err := uh.completeSignin(r, user, remember); err != nil {
+		return err
+	}
+
+	if err := uh.security.ResetLockout(r.Context(), int(user.Id.Int.Int64())); err != nil {
+		return err
+	}
+
+	if err := uh.registerDevice(w, r, user); err != nil {
 		return err
 	}
 
-	uh.session.Put(r.Context(), "userId", data.Id.Int.Int64())
-	uh.session.Put(r.Context(), "userEmail", data.Email.String)
+	// signing in during the grace period cancels a requested account deletion
+	if user.DeletionRequestedAt.Valid {
+		if err := uh.accountRepo.CancelDeletion(r.Context(), int(user.Id.Int.Int64())); err != nil {
+			return err
+		}
+
+		uh.session.Put(r.Context(), "flash", "A exclusão da sua conta foi cancelada.")
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+		return nil
+	}
 
 	http.Redirect(w, r, "/notes", http.StatusSeeOther)
 	return nil
 }
 
+// completeSignin starts an authenticated session for the user and records it
+// as one of their sessions, so it can be ended when the password changes. A
+// remembered session keeps its cookie when the browser is closed and lasts
+// longer.
+func (uh *userHandler) completeSignin(r *http.Request, user *models.User, remember bool) error {
+	if err := uh.session.RenewToken(r.Context()); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+
+	lifetime := uh.sessions.Lifetime
+
+	if remember {
+		lifetime = uh.sessions.RememberLifetime
+	}
+
+	uh.session.RememberMe(r.Context(), remember)
+	uh.session.SetDeadline(r.Context(), time.Now().Add(lifetime))
+	uh.session.Put(r.Context(), "remember", remember)
+	uh.session.Put(r.Context(), "sessionSeenAt", time.Now().Unix())
+
+	uh.session.Put(r.Context(), "userId", user.Id.Int.Int64())
+	uh.session.Put(r.Context(), "userEmail", user.Email.String)
+	uh.session.Put(r.Context(), "userRole", user.Role.String)
+
+	return uh.sessionRepo.Register(r.Context(), int(user.Id.Int.Int64()), uh.session.Token(r.Context()), tools.ClientIP(r, uh.trustProxy), r.UserAgent())
+}
+
 func (uh *userHandler) Me(w http.ResponseWriter, r *http.Request) error {
 	cookie, err := r.Cookie("session")
 
@@ -115,13 +242,7 @@ func (uh *userHandler) Signup(w http.ResponseWriter, r *http.Request) error {
 
 	user := dtos.NewUserRequest(email, password)
 
-	if strings.TrimSpace(user.Password) == "" {
-		user.AddFieldError("password", "Senha é obrigatória")
-	}
-
-	if len(strings.TrimSpace(user.Password)) < 6 {
-		user.AddFieldError("password", "Senha precisa ter no mínimo 6 caracteres")
-	}
+	uh.passwords.Validate(&user.FormValidator, "password", user.Password, user.Email)
 
 	if err := tools.ValidateEmail(user.Email); err != nil {
 		user.AddFieldError("email", "Email é inválido")
@@ -132,15 +253,15 @@ func (uh *userHandler) Signup(w http.ResponseWriter, r *http.Request) error {
 		return nil
 	}
 
-	hash, err := tools.HashPassword(user.Password)
+	hash, err := uh.hasher.Hash(user.Password)
 
 	if err != nil {
 		return err
 	}
 
-	hashKey := tools.GenerateToken()
+	token := tools.GenerateToken()
 
-	_, token, err := uh.repo.Create(r.Context(), user.Email, hash, hashKey)
+	_, err = uh.repo.Create(r.Context(), user.Email, hash, tools.HashToken(token))
 
 	if err == repositories.ErrDuplicateEmail {
 		user.AddFieldError("email", "email já está cadastrado")
@@ -151,31 +272,19 @@ func (uh *userHandler) Signup(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	body, err := uh.render.RenderMailBody(r, "confirmation.html", map[string]string{"token": token})
-
-	if err != nil {
-		return err
-	}
-
-	if err := uh.mail.Send(mailers.MailMessage{
-		To:      []string{user.Email},
-		Subject: "Confirmação de Cadastro",
-		IsHTML:  true,
-		Body:    body,
-	}); err != nil {
-		slog.Error(err.Error())
+	if err := uh.sendConfirmation(r, user.Email, token); err != nil {
 		return err
 	}
 
-	return uh.render.RenderPage(w, r, "user-signup-success.html", token, http.StatusOK)
+	return uh.render.RenderPage(w, r, "user-signup-success.html", nil, http.StatusOK)
 }
 
 func (uh *userHandler) Confirm(w http.ResponseWriter, r *http.Request) error {
 	token := r.PathValue("token")
 	msg := "Seu cadastro foi confirmado. Agora você já pode fazer o login no sistema."
 
-	if err := uh.repo.ConfirmUserByToken(r.Context(), token); err != nil {
-		msg = "Esse cadastro já foi confirmado ou o token é inválid"
+	if err := uh.repo.ConfirmUserByToken(r.Context(), tools.HashToken(token)); err != nil {
+		msg = "Esse cadastro já foi confirmado ou o link é inválido ou expirou"
 	}
 
 	return uh.render.RenderPage(w, r, "user-confirm.html", msg, http.StatusOK)
@@ -187,11 +296,9 @@ func (uh *userHandler) ForgetPasswordForm(w http.ResponseWriter, r *http.Request
 
 func (uh *userHandler) ForgetPassword(w http.ResponseWriter, r *http.Request) error {
 	email := r.PostFormValue("email")
-	hashToken := tools.GenerateToken()
-
-	token, err := uh.repo.CreateResetPasswordToken(r.Context(), email, hashToken)
+	token := tools.GenerateToken()
 
-	if err != nil {
+	if err := uh.repo.CreateResetPasswordToken(r.Context(), email, tools.HashToken(token)); err != nil {
 		data := dtos.UserRequest{}
 		data.Email = email
 		data.AddFieldError("email", "Email não possui cadastro válido no sistema")
@@ -221,12 +328,20 @@ func (uh *userHandler) ForgetPassword(w http.ResponseWriter, r *http.Request) er
 }
 
 func (uh *userHandler) Signout(w http.ResponseWriter, r *http.Request) error {
+	if err := uh.sessionRepo.Remove(r.Context(), uh.session.Token(r.Context())); err != nil {
+		return err
+	}
+
 	if err := uh.session.RenewToken(r.Context()); err != nil {
 		slog.Error(err.Error())
 		return err
 	}
 
 	uh.session.Remove(r.Context(), "userId")
+	uh.session.Remove(r.Context(), "userRole")
+	uh.session.Remove(r.Context(), "workspaceId")
+	uh.session.Remove(r.Context(), "remember")
+	uh.session.RememberMe(r.Context(), false)
 
 	http.Redirect(w, r, "/user/signin", http.StatusSeeOther)
 	return nil
@@ -235,52 +350,55 @@ func (uh *userHandler) Signout(w http.ResponseWriter, r *http.Request) error {
 func (uh *userHandler) ResetPasswordForm(w http.ResponseWriter, r *http.Request) error {
 	token := r.PathValue("token")
 
-	userToken, err := uh.repo.GetUserConfirmationByToken(r.Context(), token)
-	elapsedTime := time.Since(userToken.CreatedAt.Time).Hours()
-
-	if err != nil || userToken.Confirmed.Bool || elapsedTime > 4 {
+	if _, err := uh.repo.FindValidToken(r.Context(), models.TokenPurposePasswordReset, tools.HashToken(token)); err != nil {
 		msg := "Token inválido ou expirado"
 		return uh.render.RenderPage(w, r, "generic-error.html", msg, http.StatusOK)
 	}
 
-	data := struct {
-		Token  string
-		Errors []string
-	}{
-		Token: token,
-	}
-
-	return uh.render.RenderPage(w, r, "user-reset-password.html", data, http.StatusOK)
+	return uh.render.RenderPage(w, r, "user-reset-password.html", dtos.ResetPasswordRequest{Token: token}, http.StatusOK)
 }
 
 func (uh *userHandler) ResetPassword(w http.ResponseWriter, r *http.Request) error {
 	pass := r.PostFormValue("password")
+	confirm := r.PostFormValue("password-confirm")
 	token := r.PostFormValue("token")
 
-	hashedPass, err := tools.HashPassword(pass)
+	data := dtos.ResetPasswordRequest{Token: token}
+
+	userToken, err := uh.repo.FindValidToken(r.Context(), models.TokenPurposePasswordReset, tools.HashToken(token))
 
 	if err != nil {
-		data := struct {
-			Token  string
-			Errors []string
-		}{
-			Token:  token,
-			Errors: []string{"não foi possível alterar a senha"},
-		}
-		return uh.render.RenderPage(w, r, "user-reset-password.html", data, http.StatusOK)
+		data.AddFieldError("token", "não foi possível alterar a senha. Solicite uma nova")
+		return uh.render.RenderPage(w, r, "user-reset-password.html", data, http.StatusUnprocessableEntity)
 	}
 
-	email, err := uh.repo.UpdatePasswordByToken(r.Context(), hashedPass, token)
+	user, err := uh.repo.FindById(r.Context(), int(userToken.UserId.Int.Int64()))
 
 	if err != nil {
-		data := struct {
-			Token  string
-			Errors []string
-		}{
-			Token:  token,
-			Errors: []string{"não foi possível alterar a senha. Solicite uma nova"},
-		}
+		return err
+	}
 
+	if pass != confirm {
+		data.AddFieldError("password", "As senhas não conferem")
+	} else {
+		uh.passwords.Validate(&data.FormValidator, "password", pass, user.Email.String)
+	}
+
+	if !data.Valid() {
+		return uh.render.RenderPage(w, r, "user-reset-password.html", data, http.StatusUnprocessableEntity)
+	}
+
+	hashedPass, err := uh.hasher.Hash(pass)
+
+	if err != nil {
+		data.AddFieldError("password", "não foi possível alterar a senha")
+		return uh.render.RenderPage(w, r, "user-reset-password.html", data, http.StatusOK)
+	}
+
+	email, err := uh.repo.UpdatePasswordByToken(r.Context(), hashedPass, tools.HashToken(token))
+
+	if err != nil {
+		data.AddFieldError("token", "não foi possível alterar a senha. Solicite uma nova")
 		return uh.render.RenderPage(w, r, "user-reset-password.html", data, http.StatusOK)
 	}
 
@@ -293,6 +411,6 @@ func (uh *userHandler) ResetPassword(w http.ResponseWriter, r *http.Request) err
 		IsHTML:  false,
 	})
 
-	http.Redirect(w, r, "user/signin", http.StatusSeeOther)
+	http.Redirect(w, r, "/user/signin", http.StatusSeeOther)
 	return nil
 }
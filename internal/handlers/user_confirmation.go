@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"go_pro/internal/dtos"
+	"go_pro/internal/mailers"
+	"go_pro/internal/repositories"
+	"go_pro/tools"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+func (uh *userHandler) sendConfirmation(r *http.Request, email, token string) error {
+	body, err := uh.render.RenderMailBody(r, "confirmation.html", map[string]string{"token": token})
+
+	if err != nil {
+		return err
+	}
+
+	if err := uh.mail.Send(mailers.MailMessage{
+		To:      []string{email},
+		Subject: "Confirmação de Cadastro",
+		IsHTML:  true,
+		Body:    body,
+	}); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (uh *userHandler) ResendConfirmationForm(w http.ResponseWriter, r *http.Request) error {
+	data := dtos.UserRequest{Email: r.URL.Query().Get("email")}
+
+	return uh.render.RenderPage(w, r, "user-resend-confirmation.html", data, http.StatusOK)
+}
+
+// ResendConfirmation sends a new confirmation link, which replaces the
+// previous ones. The answer is the same whether or not the email waits for
+// a confirmation, so it does not tell which emails have an account.
+func (uh *userHandler) ResendConfirmation(w http.ResponseWriter, r *http.Request) error {
+	data := dtos.UserRequest{Email: strings.TrimSpace(r.PostFormValue("email"))}
+
+	if err := tools.ValidateEmail(data.Email); err != nil {
+		data.AddFieldError("email", "Email é inválido")
+		return uh.render.RenderPage(w, r, "user-resend-confirmation.html", data, http.StatusUnprocessableEntity)
+	}
+
+	token := tools.GenerateToken()
+	err := uh.repo.CreateConfirmationToken(r.Context(), data.Email, tools.HashToken(token))
+
+	if err == nil {
+		err = uh.sendConfirmation(r, data.Email, token)
+	}
+
+	if err != nil && err != repositories.ErrEmailNotFound {
+		return err
+	}
+
+	message := "Se houver um cadastro aguardando confirmação para " + data.Email + ", enviamos um novo link de confirmação. Os links anteriores deixam de valer."
+
+	return uh.render.RenderPage(w, r, "generic-success.html", message, http.StatusOK)
+}
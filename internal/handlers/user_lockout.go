@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"go_pro/internal/dtos"
+	"go_pro/internal/mailers"
+	"go_pro/internal/models"
+	"go_pro/internal/repositories"
+	"go_pro/tools"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// deviceCookieName names the cookie that tells the browsers of a user apart.
+// It outlives the sessions, so a device is recognized on its next sign in.
+const deviceCookieName = "device"
+
+const deviceCookieMaxAge = 365 * 24 * time.Hour
+
+// deviceToken returns the token of the browser, issuing one when it has none
+// yet. The cookie is renewed on every sign in.
+func deviceToken(w http.ResponseWriter, r *http.Request) string {
+	token := currentDeviceToken(r)
+
+	if token == "" {
+		token = tools.GenerateToken()
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     deviceCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(deviceCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return token
+}
+
+func currentDeviceToken(r *http.Request) string {
+	cookie, err := r.Cookie(deviceCookieName)
+
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// renderLocked refuses a sign in while the account is locked, without
+// checking the password.
+func (uh *userHandler) renderLocked(w http.ResponseWriter, r *http.Request, data dtos.UserRequest, remaining time.Duration) error {
+	seconds := int(math.Ceil(remaining.Seconds()))
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	data.AddFieldError("validation", "Conta bloqueada por excesso de tentativas. Tente novamente em "+formatRetryAfter(seconds)+" ou use o link de desbloqueio enviado para o seu email.")
+
+	return uh.renderSignin(w, r, data, http.StatusTooManyRequests)
+}
+
+// recordFailedSignin counts a wrong password or second factor code for the
+// user and, when that locks the account, emails them a link to unlock it.
+func (uh *userHandler) recordFailedSignin(r *http.Request, user *models.User) error {
+	token := tools.GenerateToken()
+	ip := tools.ClientIP(r, uh.trustProxy)
+
+	lockedFor, err := uh.security.RecordFailure(r.Context(), int(user.Id.Int.Int64()), ip, r.UserAgent(), uh.lockout, tools.HashToken(token))
+
+	if err != nil {
+		return err
+	}
+
+	if lockedFor == 0 {
+		return nil
+	}
+
+	slog.Warn("account locked", "userId", user.Id.Int.Int64(), "ip", ip)
+
+	body, err := uh.render.RenderMailBody(r, "account-locked.html", map[string]string{
+		"token":    token,
+		"duration": formatRetryAfter(int(math.Ceil(lockedFor.Seconds()))),
+		"ip":       ip,
+	})
+
+	if err != nil {
+		slog.Error(err.Error())
+		return nil
+	}
+
+	if err := uh.mail.Send(mailers.MailMessage{
+		To:      []string{user.Email.String},
+		Subject: "Sua conta foi bloqueada temporariamente",
+		IsHTML:  true,
+		Body:    body,
+	}); err != nil {
+		slog.Error(err.Error())
+	}
+
+	return nil
+}
+
+// registerDevice records the browser the user signed in from, and warns them
+// when it was never seen before. A failure to send the warning is only
+// logged.
+func (uh *userHandler) registerDevice(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	ip := tools.ClientIP(r, uh.trustProxy)
+	hash := tools.HashToken(deviceToken(w, r))
+
+	isNew, err := uh.security.RegisterDevice(r.Context(), int(user.Id.Int.Int64()), hash, ip, r.UserAgent())
+
+	if err != nil || !isNew {
+		return err
+	}
+
+	body, err := uh.render.RenderMailBody(r, "new-signin.html", map[string]string{
+		"device": dtos.DescribeUserAgent(r.UserAgent()),
+		"ip":     ip,
+		"time":   time.Now().Format("02/01/2006 15:04"),
+	})
+
+	if err != nil {
+		slog.Error(err.Error())
+		return nil
+	}
+
+	if err := uh.mail.Send(mailers.MailMessage{
+		To:      []string{user.Email.String},
+		Subject: "Novo acesso à sua conta",
+		IsHTML:  true,
+		Body:    body,
+	}); err != nil {
+		slog.Error(err.Error())
+	}
+
+	return nil
+}
+
+func (uh *userHandler) Unlock(w http.ResponseWriter, r *http.Request) error {
+	err := uh.security.Unlock(r.Context(), tools.HashToken(r.PathValue("token")))
+
+	if err == repositories.ErrUnlockTokenNotFound {
+		return uh.render.RenderPage(w, r, "generic-error.html", "Link de desbloqueio inválido ou já utilizado", http.StatusOK)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	uh.session.Put(r.Context(), "flash", "Sua conta foi desbloqueada. Agora você pode fazer o login.")
+
+	http.Redirect(w, r, "/user/signin", http.StatusSeeOther)
+	return nil
+}
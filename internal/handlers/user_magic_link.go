@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"go_pro/internal/dtos"
+	"go_pro/internal/mailers"
+	"go_pro/internal/models"
+	"go_pro/internal/repositories"
+	"go_pro/tools"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+func (uh *userHandler) sendMagicLink(r *http.Request, email, token string) error {
+	body, err := uh.render.RenderMailBody(r, "magic-link.html", map[string]string{
+		"token":    token,
+		"validFor": formatRetryAfter(int(uh.magicLink.TTL.Seconds())),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := uh.mail.Send(mailers.MailMessage{
+		To:      []string{email},
+		Subject: "Seu link de acesso",
+		IsHTML:  true,
+		Body:    body,
+	}); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// SendMagicLink emails a single use link that signs the user in without the
+// password. The link is tied to the device cookie of the browser, which only
+// matters when the policy asks for the same browser. The answer does not
+// tell which emails have an account.
+func (uh *userHandler) SendMagicLink(w http.ResponseWriter, r *http.Request) error {
+	data := dtos.UserRequest{Email: strings.TrimSpace(r.PostFormValue("email"))}
+
+	if err := tools.ValidateEmail(data.Email); err != nil {
+		data.AddFieldError("email", "Email é inválido")
+		return uh.renderSignin(w, r, data, http.StatusUnprocessableEntity)
+	}
+
+	token := tools.GenerateToken()
+	err := uh.repo.CreateMagicLinkToken(r.Context(), data.Email, tools.HashToken(token), tools.HashToken(deviceToken(w, r)))
+
+	if err == nil {
+		err = uh.sendMagicLink(r, data.Email, token)
+	}
+
+	if err != nil && err != repositories.ErrEmailNotFound {
+		return err
+	}
+
+	message := "Se houver uma conta ativa para " + data.Email + ", enviamos um link de acesso. Ele vale por " + formatRetryAfter(int(uh.magicLink.TTL.Seconds())) + " e só pode ser usado uma vez."
+
+	if uh.magicLink.SameBrowser {
+		message += " Abra o link neste mesmo navegador."
+	}
+
+	return uh.render.RenderPage(w, r, "generic-success.html", message, http.StatusOK)
+}
+
+// MagicLinkForm asks for a click before signing in, so mail scanners that
+// follow the links of a message do not use it up.
+func (uh *userHandler) MagicLinkForm(w http.ResponseWriter, r *http.Request) error {
+	token := r.PathValue("token")
+
+	if msg, status := uh.checkMagicLink(r, token); msg != "" {
+		return uh.render.RenderPage(w, r, "generic-error.html", msg, status)
+	}
+
+	return uh.render.RenderPage(w, r, "user-magic-link.html", token, http.StatusOK)
+}
+
+// MagicLinkSignin uses the link and signs the user in like Signin does after
+// the password, going through the second factor when it is enabled.
+func (uh *userHandler) MagicLinkSignin(w http.ResponseWriter, r *http.Request) error {
+	token := r.PathValue("token")
+
+	if msg, status := uh.checkMagicLink(r, token); msg != "" {
+		return uh.render.RenderPage(w, r, "generic-error.html", msg, status)
+	}
+
+	user, err := uh.repo.UseMagicLinkToken(r.Context(), tools.HashToken(token))
+
+	if err == repositories.ErrInvalidToken {
+		return uh.render.RenderPage(w, r, "generic-error.html", "Link de acesso inválido ou expirado", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if user.TOTPEnabled.Bool && !user.DisabledAt.Valid {
+		return uh.startTwoFactor(w, r, user, false)
+	}
+
+	return uh.finishSignin(w, r, user, false)
+}
+
+// checkMagicLink returns the message and status to refuse the link with, or
+// an empty message when it can be used from this browser.
+func (uh *userHandler) checkMagicLink(r *http.Request, token string) (string, int) {
+	userToken, err := uh.repo.FindValidToken(r.Context(), models.TokenPurposeMagicLink, tools.HashToken(token))
+
+	if err != nil {
+		return "Link de acesso inválido ou expirado", http.StatusNotFound
+	}
+
+	if uh.magicLink.SameBrowser && userToken.DeviceHash.String != tools.HashToken(currentDeviceToken(r)) {
+		return "Esse link de acesso só funciona no navegador em que ele foi pedido.", http.StatusForbidden
+	}
+
+	return "", 0
+}
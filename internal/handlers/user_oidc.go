@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"go_pro/internal/oidc"
+	"go_pro/internal/repositories"
+	"go_pro/tools"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// reauthWindow is how long a new sign in at the provider lets a user without
+// a password change the settings that otherwise ask for it.
+const reauthWindow = 5 * time.Minute
+
+// SSOProvider is the external OpenID Connect provider users may sign in with.
+type SSOProvider struct {
+	Name       string
+	Provider   *oidc.Provider
+	Identities repositories.IdentityRepository
+}
+
+func (uh *userHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) error {
+	if uh.sso == nil {
+		http.NotFound(w, r)
+		return nil
+	}
+
+	uh.session.Remove(r.Context(), "oidcReauthUserId")
+
+	return uh.startOIDC(w, r, uh.sso.Provider.AuthCodeURL)
+}
+
+// OIDCReauth sends a signed in user back to the provider to sign in again,
+// which confirms their identity when the account has no password to ask for.
+func (uh *userHandler) OIDCReauth(w http.ResponseWriter, r *http.Request) error {
+	if uh.sso == nil {
+		http.NotFound(w, r)
+		return nil
+	}
+
+	uh.session.Put(r.Context(), "oidcReauthUserId", currentUserId(uh.session, r))
+
+	return uh.startOIDC(w, r, uh.sso.Provider.ReauthCodeURL)
+}
+
+func (uh *userHandler) startOIDC(w http.ResponseWriter, r *http.Request, authCodeURL func(ctx context.Context, state, nonce, verifier string) (string, error)) error {
+	state := tools.GenerateToken()
+	nonce := tools.GenerateToken()
+	verifier := oidc.GenerateVerifier()
+
+	url, err := authCodeURL(r.Context(), state, nonce, verifier)
+
+	if err != nil {
+		slog.Error(err.Error())
+		return uh.render.RenderPage(w, r, "generic-error.html", "Não foi possível falar com o provedor de login", http.StatusBadGateway)
+	}
+
+	uh.session.Put(r.Context(), "oidcState", state)
+	uh.session.Put(r.Context(), "oidcNonce", nonce)
+	uh.session.Put(r.Context(), "oidcVerifier", verifier)
+
+	http.Redirect(w, r, url, http.StatusSeeOther)
+	return nil
+}
+
+func (uh *userHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) error {
+	if uh.sso == nil {
+		http.NotFound(w, r)
+		return nil
+	}
+
+	state := uh.session.PopString(r.Context(), "oidcState")
+	nonce := uh.session.PopString(r.Context(), "oidcNonce")
+	verifier := uh.session.PopString(r.Context(), "oidcVerifier")
+	reauthUserId := uh.session.GetInt64(r.Context(), "oidcReauthUserId")
+	uh.session.Remove(r.Context(), "oidcReauthUserId")
+	query := r.URL.Query()
+
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		return uh.render.RenderPage(w, r, "generic-error.html", "O login expirou. Tente novamente.", http.StatusBadRequest)
+	}
+
+	if errorCode := query.Get("error"); errorCode != "" {
+		slog.Warn("login externo recusado", "error", errorCode, "description", query.Get("error_description"))
+		return uh.render.RenderPage(w, r, "generic-error.html", "O login foi cancelado no provedor.", http.StatusUnauthorized)
+	}
+
+	claims, err := uh.sso.Provider.Exchange(r.Context(), query.Get("code"), verifier, nonce)
+
+	if err != nil {
+		slog.Error(err.Error())
+		return uh.render.RenderPage(w, r, "generic-error.html", "Não foi possível validar o login no provedor.", http.StatusUnauthorized)
+	}
+
+	if reauthUserId != 0 {
+		return uh.finishReauth(w, r, reauthUserId, claims)
+	}
+
+	user, err := uh.sso.Identities.SignIn(r.Context(), uh.sso.Provider.Issuer(), claims.Subject, claims.Email, claims.EmailVerified)
+
+	if err == repositories.ErrIdentityEmailNotVerified {
+		return uh.render.RenderPage(w, r, "generic-error.html", "O seu email não foi verificado pelo provedor de login.", http.StatusForbidden)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// the provider keeps its own login, the session here is not remembered
+	if user.TOTPEnabled.Bool {
+		return uh.startTwoFactor(w, r, user, false)
+	}
+
+	return uh.finishSignin(w, r, user, false)
+}
+
+// finishReauth accepts the new sign in only when it happened just now, for an
+// identity already linked to the user who asked for it.
+func (uh *userHandler) finishReauth(w http.ResponseWriter, r *http.Request, userId int64, claims *oidc.Claims) error {
+	if userId != currentUserId(uh.session, r) {
+		return uh.render.RenderPage(w, r, "generic-error.html", "A confirmação expirou. Tente novamente.", http.StatusBadRequest)
+	}
+
+	if claims.AuthTime < time.Now().Add(-reauthWindow).Unix() {
+		return uh.render.RenderPage(w, r, "generic-error.html", "O provedor de login não pediu para você entrar novamente. Tente outra vez.", http.StatusUnauthorized)
+	}
+
+	linked, err := uh.sso.Identities.IsLinked(r.Context(), userId, uh.sso.Provider.Issuer(), claims.Subject)
+
+	if err != nil {
+		return err
+	}
+
+	if !linked {
+		return uh.render.RenderPage(w, r, "generic-error.html", "Essa identidade não está ligada à sua conta.", http.StatusForbidden)
+	}
+
+	uh.session.Put(r.Context(), reauthKey(userId), time.Now().Add(reauthWindow).Unix())
+	uh.session.Put(r.Context(), "flash", "Identidade confirmada. Conclua a alteração nos próximos minutos.")
+
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+	return nil
+}
+
+func reauthKey(userId int64) string {
+	return fmt.Sprintf("reauthUntil:%d", userId)
+}
+
+// reauthenticated tells whether the user signed in again at the provider
+// within the last reauthWindow.
+func reauthenticated(session *scs.SessionManager, r *http.Request, userId int64) bool {
+	return session.GetInt64(r.Context(), reauthKey(userId)) > time.Now().Unix()
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"go_pro/internal/dtos"
+	"go_pro/internal/models"
+	"go_pro/tools"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+const (
+	// twoFactorWindow is how long the second step may take after the password
+	// was accepted.
+	twoFactorWindow      = 5 * time.Minute
+	maxTwoFactorAttempts = 5
+)
+
+// startTwoFactor leaves the session half authenticated: it knows which user
+// passed the password check, but "userId" is only set after the code.
+func (uh *userHandler) startTwoFactor(w http.ResponseWriter, r *http.Request, user *models.User, remember bool) error {
+	if err := uh.session.RenewToken(r.Context()); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+
+	uh.session.Put(r.Context(), "pendingUserId", user.Id.Int.Int64())
+	uh.session.Put(r.Context(), "pendingUntil", time.Now().Add(twoFactorWindow).Unix())
+	uh.session.Put(r.Context(), "pendingAttempts", 0)
+	uh.session.Put(r.Context(), "pendingRemember", remember)
+
+	http.Redirect(w, r, "/user/signin/2fa", http.StatusSeeOther)
+	return nil
+}
+
+// PendingUserAccount tells the rate limiter which user the second step of a
+// sign in is for, so starting new half authenticated sessions does not buy
+// more guesses of their code.
+func PendingUserAccount(session *scs.SessionManager) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if userId := session.GetInt64(r.Context(), "pendingUserId"); userId != 0 {
+			return "user:" + strconv.FormatInt(userId, 10)
+		}
+
+		return ""
+	}
+}
+
+func (uh *userHandler) clearTwoFactor(r *http.Request) {
+	uh.session.Remove(r.Context(), "pendingUserId")
+	uh.session.Remove(r.Context(), "pendingUntil")
+	uh.session.Remove(r.Context(), "pendingAttempts")
+	uh.session.Remove(r.Context(), "pendingRemember")
+}
+
+// pendingUserId returns the user waiting for the second step, or zero when
+// there is none or its window is over.
+func (uh *userHandler) pendingUserId(r *http.Request) int64 {
+	userId := uh.session.GetInt64(r.Context(), "pendingUserId")
+
+	if userId == 0 {
+		return 0
+	}
+
+	if time.Now().Unix() > uh.session.GetInt64(r.Context(), "pendingUntil") {
+		uh.clearTwoFactor(r)
+		return 0
+	}
+
+	return userId
+}
+
+func (uh *userHandler) TwoFactorForm(w http.ResponseWriter, r *http.Request) error {
+	if uh.pendingUserId(r) == 0 {
+		http.Redirect(w, r, "/user/signin", http.StatusSeeOther)
+		return nil
+	}
+
+	return uh.render.RenderPage(w, r, "user-signin-2fa.html", dtos.UserRequest{}, http.StatusOK)
+}
+
+func (uh *userHandler) TwoFactor(w http.ResponseWriter, r *http.Request) error {
+	userId := uh.pendingUserId(r)
+
+	if userId == 0 {
+		http.Redirect(w, r, "/user/signin", http.StatusSeeOther)
+		return nil
+	}
+
+	user, err := uh.repo.FindById(r.Context(), int(userId))
+
+	if err != nil {
+		return err
+	}
+
+	lockout, err := uh.security.GetLockout(r.Context(), int(userId))
+
+	if err != nil {
+		return err
+	}
+
+	if lockout.Remaining > 0 {
+		uh.clearTwoFactor(r)
+		return uh.renderLocked(w, r, dtos.UserRequest{}, lockout.Remaining)
+	}
+
+	ok, usedRecoveryCode, err := uh.checkSecondFactor(r, user, r.PostFormValue("code"))
+
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		if err := uh.recordFailedSignin(r, user); err != nil {
+			return err
+		}
+
+		attempts := uh.session.GetInt(r.Context(), "pendingAttempts") + 1
+
+		if attempts >= maxTwoFactorAttempts {
+			uh.clearTwoFactor(r)
+			uh.session.Put(r.Context(), "flash", "Muitas tentativas inválidas. Entre novamente.")
+			http.Redirect(w, r, "/user/signin", http.StatusSeeOther)
+			return nil
+		}
+
+		uh.session.Put(r.Context(), "pendingAttempts", attempts)
+
+		data := dtos.UserRequest{}
+		data.AddFieldError("code", "Código inválido")
+
+		return uh.render.RenderPage(w, r, "user-signin-2fa.html", data, http.StatusUnprocessableEntity)
+	}
+
+	remember := uh.session.GetBool(r.Context(), "pendingRemember")
+	uh.clearTwoFactor(r)
+
+	if usedRecoveryCode {
+		uh.session.Put(r.Context(), "flash", "Você entrou com um código de recuperação, que não poderá ser usado de novo.")
+	}
+
+	return uh.finishSignin(w, r, user, remember)
+}
+
+// checkSecondFactor accepts either a code of the authenticator app or one of
+// the recovery codes, which are longer and contain a dash.
+func (uh *userHandler) checkSecondFactor(r *http.Request, user *models.User, code string) (ok bool, usedRecoveryCode bool, err error) {
+	userId := int(user.Id.Int.Int64())
+
+	if strings.Contains(code, "-") || len(strings.TrimSpace(code)) > 6 {
+		ok, err = uh.twoFactor.UseRecoveryCode(r.Context(), userId, tools.HashRecoveryCode(code))
+		return ok, ok, err
+	}
+
+	step, valid := tools.ValidateTOTP(user.TOTPSecret.String, code, time.Now())
+
+	if !valid {
+		return false, false, nil
+	}
+
+	ok, err = uh.twoFactor.UseStep(r.Context(), userId, step)
+
+	return ok, false, err
+}
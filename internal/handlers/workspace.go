@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/dtos"
+	"go_pro/internal/mailers"
+	"go_pro/internal/render"
+	"go_pro/internal/repositories"
+	"go_pro/tools"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+const workspaceNameMaxLength = 50
+
+type workspaceHandler struct {
+	render        *render.RenderTemplate
+	session       *scs.SessionManager
+	repo          repositories.WorkspaceRepository
+	mail          mailers.MailService
+	invitationTTL time.Duration
+}
+
+func NewWorkspaceHandler(render *render.RenderTemplate, session *scs.SessionManager, repo repositories.WorkspaceRepository, mail mailers.MailService, invitationTTL time.Duration) *workspaceHandler {
+	return &workspaceHandler{render: render, session: session, repo: repo, mail: mail, invitationTTL: invitationTTL}
+}
+
+func (wh *workspaceHandler) getUserIdFromSession(r *http.Request) int64 {
+	return currentUserId(wh.session, r)
+}
+
+func (wh *workspaceHandler) Workspaces(w http.ResponseWriter, r *http.Request) error {
+	workspaces, err := wh.repo.ListForUser(r.Context(), int(wh.getUserIdFromSession(r)))
+
+	if err != nil {
+		return err
+	}
+
+	data := dtos.WorkspacesResponse{Workspaces: dtos.NewWorkspaceListResponse(workspaces)}
+	data.Flash = wh.session.PopString(r.Context(), "flash")
+
+	return wh.render.RenderPage(w, r, "workspaces.html", data, http.StatusOK)
+}
+
+// WorkspaceCreate adds a workspace owned by the user and switches to it.
+func (wh *workspaceHandler) WorkspaceCreate(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	userId := int(wh.getUserIdFromSession(r))
+	data := dtos.WorkspacesResponse{Name: strings.TrimSpace(r.PostFormValue("name"))}
+
+	if data.Name == "" {
+		data.AddFieldError("name", "O nome não pode ser vazio")
+	} else if utf8.RuneCountInString(data.Name) > workspaceNameMaxLength {
+		data.AddFieldError("name", fmt.Sprintf("O nome pode ter no máximo %d caracteres", workspaceNameMaxLength))
+	}
+
+	if !data.Valid() {
+		workspaces, err := wh.repo.ListForUser(r.Context(), userId)
+
+		if err != nil {
+			return err
+		}
+
+		data.Workspaces = dtos.NewWorkspaceListResponse(workspaces)
+
+		return wh.render.RenderPage(w, r, "workspaces.html", data, http.StatusUnprocessableEntity)
+	}
+
+	workspace, err := wh.repo.Create(r.Context(), userId, data.Name)
+
+	if err != nil {
+		return err
+	}
+
+	wh.session.Put(r.Context(), "workspaceId", workspace.Id.Int.Int64())
+	wh.session.Put(r.Context(), "flash", "O espaço "+data.Name+" foi criado. Convide a sua equipe.")
+
+	http.Redirect(w, r, fmt.Sprintf("/workspaces/%d", workspace.Id.Int.Int64()), http.StatusSeeOther)
+	return nil
+}
+
+// WorkspaceSwitch changes the workspace whose notes the user works on. Zero
+// switches back to the personal notes.
+func (wh *workspaceHandler) WorkspaceSwitch(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	workspaceId, err := strconv.Atoi(r.PostFormValue("workspace"))
+
+	if err != nil {
+		return apperrors.ErrorNotFound("workspace not found")
+	}
+
+	if workspaceId == 0 {
+		wh.session.Remove(r.Context(), "workspaceId")
+	} else {
+		if _, err := wh.repo.Get(r.Context(), int(wh.getUserIdFromSession(r)), workspaceId); err != nil {
+			return wh.handleRepoError(err)
+		}
+
+		wh.session.Put(r.Context(), "workspaceId", int64(workspaceId))
+	}
+
+	http.Redirect(w, r, "/notes", http.StatusSeeOther)
+	return nil
+}
+
+func (wh *workspaceHandler) WorkspaceView(w http.ResponseWriter, r *http.Request) error {
+	data, err := wh.newWorkspaceViewResponse(r)
+
+	if err != nil {
+		return err
+	}
+
+	data.Flash = wh.session.PopString(r.Context(), "flash")
+
+	return wh.render.RenderPage(w, r, "workspace-view.html", data, http.StatusOK)
+}
+
+// WorkspaceInvite emails an invitation to join the workspace. Only owners and
+// admins can invite.
+func (wh *workspaceHandler) WorkspaceInvite(w http.ResponseWriter, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	data, err := wh.newWorkspaceViewResponse(r)
+
+	if err != nil {
+		return err
+	}
+
+	if !data.Workspace.CanManage {
+		return apperrors.ErrorForbidden("only owners and admins can invite members")
+	}
+
+	data.Email = strings.TrimSpace(r.PostFormValue("email"))
+	data.Role = r.PostFormValue("role")
+
+	if err := tools.ValidateEmail(data.Email); err != nil {
+		data.AddFieldError("email", "Email é inválido")
+	}
+
+	if !dtos.IsValidWorkspaceInviteRole(data.Role) {
+		data.AddFieldError("role", "Papel inválido")
+	}
+
+	if !data.Valid() {
+		return wh.render.RenderPage(w, r, "workspace-view.html", data, http.StatusUnprocessableEntity)
+	}
+
+	token := tools.GenerateToken()
+
+	err = wh.repo.CreateInvitation(r.Context(), data.Workspace.Id, int(wh.getUserIdFromSession(r)), data.Email, data.Role, tools.HashToken(token))
+
+	if err == repositories.ErrAlreadyWorkspaceMember {
+		data.AddFieldError("email", "Este email já é membro do espaço")
+		return wh.render.RenderPage(w, r, "workspace-view.html", data, http.StatusUnprocessableEntity)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	body, err := wh.render.RenderMailBody(r, "workspace-invitation.html", map[string]string{
+		"token":     token,
+		"workspace": data.Workspace.Name,
+		"invitedBy": wh.session.GetString(r.Context(), "userEmail"),
+		"validFor":  formatRetryAfter(int(wh.invitationTTL.Seconds())),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := wh.mail.Send(mailers.MailMessage{
+		To:      []string{data.Email},
+		Subject: "Convite para o espaço " + data.Workspace.Name,
+		IsHTML:  true,
+		Body:    body,
+	}); err != nil {
+		return err
+	}
+
+	return wh.done(w, r, data.Workspace.Id, "Enviamos um convite para "+data.Email+".")
+}
+
+func (wh *workspaceHandler) WorkspaceRevokeInvitation(w http.ResponseWriter, r *http.Request) error {
+	workspace, err := wh.managedWorkspace(r)
+
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(r.PathValue("invitationId"))
+
+	if err != nil {
+		return apperrors.ErrorNotFound("invitation not found")
+	}
+
+	if err := wh.repo.RevokeInvitation(r.Context(), workspace.Id, id); err != nil {
+		if err == repositories.ErrInvalidToken {
+			return apperrors.ErrorNotFound("invitation not found")
+		}
+		return err
+	}
+
+	return wh.done(w, r, workspace.Id, "O convite foi cancelado.")
+}
+
+// WorkspaceMemberRole changes the role of a member. The owner keeps theirs.
+func (wh *workspaceHandler) WorkspaceMemberRole(w http.ResponseWriter, r *http.Request) error {
+	workspace, err := wh.managedWorkspace(r)
+
+	if err != nil {
+		return err
+	}
+
+	userId, err := strconv.Atoi(r.PathValue("userId"))
+
+	if err != nil {
+		return apperrors.ErrorNotFound("member not found")
+	}
+
+	role := r.PostFormValue("role")
+
+	if !dtos.IsValidWorkspaceInviteRole(role) {
+		return apperrors.NewWithStatus(errors.New("papel inválido"), http.StatusUnprocessableEntity)
+	}
+
+	if err := wh.handleRepoError(wh.repo.SetMemberRole(r.Context(), workspace.Id, userId, role)); err != nil {
+		return err
+	}
+
+	return wh.done(w, r, workspace.Id, "O papel do membro foi alterado.")
+}
+
+// WorkspaceMemberRemove takes a member out of the workspace. Owners and admins
+// remove anyone but the owner, the other members can only leave.
+func (wh *workspaceHandler) WorkspaceMemberRemove(w http.ResponseWriter, r *http.Request) error {
+	workspace, err := wh.currentWorkspace(r)
+
+	if err != nil {
+		return err
+	}
+
+	userId, err := strconv.Atoi(r.PathValue("userId"))
+
+	if err != nil {
+		return apperrors.ErrorNotFound("member not found")
+	}
+
+	self := userId == int(wh.getUserIdFromSession(r))
+
+	if !self && !workspace.CanManage {
+		return apperrors.ErrorForbidden("only owners and admins can remove members")
+	}
+
+	if self && workspace.IsOwner {
+		return apperrors.NewWithStatus(errors.New("o dono não pode sair do espaço, exclua-o"), http.StatusUnprocessableEntity)
+	}
+
+	if err := wh.handleRepoError(wh.repo.RemoveMember(r.Context(), workspace.Id, userId)); err != nil {
+		return err
+	}
+
+	if self {
+		wh.leave(r, workspace.Id)
+		wh.session.Put(r.Context(), "flash", "Você saiu do espaço "+workspace.Name+".")
+
+		http.Redirect(w, r, "/workspaces", http.StatusSeeOther)
+		return nil
+	}
+
+	return wh.done(w, r, workspace.Id, "O membro foi removido.")
+}
+
+// WorkspaceDelete removes the workspace and all of its notes. Only the owner
+// can delete it.
+func (wh *workspaceHandler) WorkspaceDelete(w http.ResponseWriter, r *http.Request) error {
+	workspace, err := wh.currentWorkspace(r)
+
+	if err != nil {
+		return err
+	}
+
+	if !workspace.IsOwner {
+		return apperrors.ErrorForbidden("only the owner can delete the workspace")
+	}
+
+	if err := wh.repo.Delete(r.Context(), workspace.Id); err != nil {
+		return err
+	}
+
+	wh.leave(r, workspace.Id)
+	wh.session.Put(r.Context(), "flash", "O espaço "+workspace.Name+" foi excluído.")
+
+	http.Redirect(w, r, "/workspaces", http.StatusSeeOther)
+	return nil
+}
+
+// InvitationView shows an invitation. It is open to everyone, so the invited
+// user can see which email to sign in or sign up with.
+func (wh *workspaceHandler) InvitationView(w http.ResponseWriter, r *http.Request) error {
+	token := r.PathValue("token")
+
+	invitation, err := wh.repo.FindInvitation(r.Context(), tools.HashToken(token))
+
+	if err == repositories.ErrInvalidToken {
+		return wh.render.RenderPage(w, r, "generic-error.html", "O convite é inválido, expirou ou já foi usado.", http.StatusNotFound)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	data := dtos.NewWorkspaceJoinResponse(token, invitation)
+	data.CanAccept = strings.EqualFold(wh.session.GetString(r.Context(), "userEmail"), invitation.Email.String)
+
+	return wh.render.RenderPage(w, r, "workspace-invitation.html", data, http.StatusOK)
+}
+
+// InvitationAccept adds the signed in user to the workspace of the invitation
+// and switches to it.
+func (wh *workspaceHandler) InvitationAccept(w http.ResponseWriter, r *http.Request) error {
+	token := r.PathValue("token")
+	email := wh.session.GetString(r.Context(), "userEmail")
+
+	workspaceId, err := wh.repo.AcceptInvitation(r.Context(), tools.HashToken(token), int(wh.getUserIdFromSession(r)), email)
+
+	switch err {
+	case nil:
+	case repositories.ErrInvalidToken:
+		return wh.render.RenderPage(w, r, "generic-error.html", "O convite é inválido, expirou ou já foi usado.", http.StatusNotFound)
+	case repositories.ErrInvitationEmailMismatch:
+		return wh.render.RenderPage(w, r, "generic-error.html", "Este convite foi enviado para outro email. Entre com o email convidado para aceitá-lo.", http.StatusForbidden)
+	default:
+		return err
+	}
+
+	wh.session.Put(r.Context(), "workspaceId", int64(workspaceId))
+	wh.session.Put(r.Context(), "flash", "Você agora faz parte do espaço.")
+
+	http.Redirect(w, r, fmt.Sprintf("/workspaces/%d", workspaceId), http.StatusSeeOther)
+	return nil
+}
+
+// currentWorkspace returns the workspace of the path, as long as the user is
+// one of its members.
+func (wh *workspaceHandler) currentWorkspace(r *http.Request) (*dtos.WorkspaceResponse, error) {
+	workspaceId, err := strconv.Atoi(r.PathValue("id"))
+
+	if err != nil {
+		return nil, apperrors.ErrorNotFound("workspace not found")
+	}
+
+	workspace, err := wh.repo.Get(r.Context(), int(wh.getUserIdFromSession(r)), workspaceId)
+
+	if err != nil {
+		return nil, wh.handleRepoError(err)
+	}
+
+	response := dtos.NewWorkspaceResponse(workspace)
+
+	return &response, nil
+}
+
+// managedWorkspace is currentWorkspace for the actions of owners and admins.
+func (wh *workspaceHandler) managedWorkspace(r *http.Request) (*dtos.WorkspaceResponse, error) {
+	workspace, err := wh.currentWorkspace(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !workspace.CanManage {
+		return nil, apperrors.ErrorForbidden("only owners and admins can manage the workspace")
+	}
+
+	return workspace, nil
+}
+
+func (wh *workspaceHandler) newWorkspaceViewResponse(r *http.Request) (*dtos.WorkspaceViewResponse, error) {
+	workspaceId, err := strconv.Atoi(r.PathValue("id"))
+
+	if err != nil {
+		return nil, apperrors.ErrorNotFound("workspace not found")
+	}
+
+	userId := int(wh.getUserIdFromSession(r))
+
+	workspace, err := wh.repo.Get(r.Context(), userId, workspaceId)
+
+	if err != nil {
+		return nil, wh.handleRepoError(err)
+	}
+
+	members, err := wh.repo.ListMembers(r.Context(), workspaceId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := wh.repo.ListInvitations(r.Context(), workspaceId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dtos.NewWorkspaceViewResponse(workspace, members, invitations, userId), nil
+}
+
+// leave switches back to the personal notes when the user was working on
+// the workspace they no longer are part of.
+func (wh *workspaceHandler) leave(r *http.Request, workspaceId int) {
+	if wh.session.GetInt64(r.Context(), "workspaceId") == int64(workspaceId) {
+		wh.session.Remove(r.Context(), "workspaceId")
+	}
+}
+
+func (wh *workspaceHandler) handleRepoError(err error) error {
+	switch err {
+	case repositories.ErrWorkspaceNotFound:
+		return apperrors.ErrorNotFound("workspace not found")
+	case repositories.ErrWorkspaceMemberNotFound:
+		return apperrors.NewWithStatus(errors.New("membro não encontrado ou é o dono do espaço"), http.StatusUnprocessableEntity)
+	}
+
+	return err
+}
+
+func (wh *workspaceHandler) done(w http.ResponseWriter, r *http.Request, workspaceId int, msg string) error {
+	wh.session.Put(r.Context(), "flash", msg)
+
+	http.Redirect(w, r, fmt.Sprintf("/workspaces/%d", workspaceId), http.StatusSeeOther)
+	return nil
+}
@@ -0,0 +1,87 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// The interface is written in Portuguese. Each file of locales translates
+// those messages to one more language, keyed by the Portuguese text. Only the
+// header, the footer and the note pages are translated so far; the other
+// pages, the flash messages and the errors are Portuguese only.
+//
+//go:embed locales/*.json
+var files embed.FS
+
+type Locale struct {
+	Code string
+	Name string
+}
+
+// Locales are the languages of the interface, the first one being the one it
+// is written in.
+var Locales = []Locale{
+	{Code: "pt-BR", Name: "Português (Brasil)"},
+	{Code: "en", Name: "English"},
+}
+
+var catalogs = map[string]map[string]string{}
+
+var matcher = language.NewMatcher([]language.Tag{
+	language.BrazilianPortuguese,
+	language.English,
+})
+
+func init() {
+	for _, locale := range Locales[1:] {
+		data, err := files.ReadFile("locales/" + strings.ToLower(locale.Code) + ".json")
+
+		if err != nil {
+			panic(err)
+		}
+
+		catalog := map[string]string{}
+
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(err)
+		}
+
+		catalogs[locale.Code] = catalog
+	}
+}
+
+// T translates msg to locale. Messages without a translation are returned as
+// they are, in Portuguese.
+func T(locale, msg string) string {
+	if translated, ok := catalogs[locale][msg]; ok {
+		return translated
+	}
+
+	return msg
+}
+
+func IsSupported(code string) bool {
+	for _, locale := range Locales {
+		if locale.Code == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Match returns the supported locale that best fits an Accept-Language
+// header, the first one when none does.
+func Match(acceptLanguage string) string {
+	tags, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, index, confidence := matcher.Match(tags...)
+
+	if confidence == language.No {
+		return Locales[0].Code
+	}
+
+	return Locales[index].Code
+}
@@ -0,0 +1,35 @@
+package inbox
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	invisibleBlocks = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	lineBreakTags   = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|tr|h[1-6]|blockquote|pre)>`)
+	listItemTags    = regexp.MustCompile(`(?i)<li[^>]*>`)
+	anyTag          = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLines      = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText makes a readable plain text version of an HTML mail body. It is
+// only used when the message has no text/plain alternative.
+func htmlToText(body string) string {
+	text := invisibleBlocks.ReplaceAllString(body, "")
+	text = lineBreakTags.ReplaceAllString(text, "\n")
+	text = listItemTags.ReplaceAllString(text, "- ")
+	text = anyTag.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+
+	lines := strings.Split(text, "\n")
+
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+
+	text = strings.Join(lines, "\n")
+
+	return strings.TrimSpace(blankLines.ReplaceAllString(text, "\n\n"))
+}
@@ -0,0 +1,169 @@
+package inbox
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+var ErrNoTextContent = errors.New("message has no text content")
+
+const maxMultipartDepth = 5
+
+type parsedMessage struct {
+	Title   string
+	Content string
+}
+
+func parseMessage(r io.Reader) (*parsedMessage, error) {
+	msg, err := mail.ReadMessage(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := mime.WordDecoder{CharsetReader: charsetReader}
+	subject, err := decoder.DecodeHeader(msg.Header.Get("Subject"))
+
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	plain, html, err := readBody(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	content := plain
+
+	if strings.TrimSpace(content) == "" {
+		content = htmlToText(html)
+	}
+
+	content = strings.TrimSpace(content)
+
+	if content == "" {
+		return nil, ErrNoTextContent
+	}
+
+	return &parsedMessage{Title: strings.TrimSpace(subject), Content: content}, nil
+}
+
+// readBody walks the MIME tree and returns the first text/plain and the first
+// text/html parts it finds. Attachments are ignored.
+func readBody(contentType, encoding string, body io.Reader, depth int) (plain, html string, err error) {
+	if contentType == "" {
+		contentType = "text/plain; charset=us-ascii"
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxMultipartDepth {
+			return "", "", nil
+		}
+
+		reader := multipart.NewReader(body, params["boundary"])
+
+		for {
+			part, err := reader.NextRawPart()
+
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return plain, html, err
+			}
+
+			if strings.HasPrefix(part.Header.Get("Content-Disposition"), "attachment") {
+				continue
+			}
+
+			p, h, err := readBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part, depth+1)
+
+			if err != nil {
+				return plain, html, err
+			}
+
+			if plain == "" {
+				plain = p
+			}
+
+			if html == "" {
+				html = h
+			}
+		}
+
+		return plain, html, nil
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return "", "", nil
+	}
+
+	text, err := decodeText(body, encoding, params["charset"])
+
+	if err != nil {
+		return "", "", err
+	}
+
+	if mediaType == "text/html" {
+		return "", text, nil
+	}
+
+	return text, "", nil
+}
+
+func decodeText(body io.Reader, encoding, charset string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	reader, err := charsetReader(charset, body)
+
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := io.ReadAll(reader)
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ReplaceAll(string(raw), "\r\n", "\n"), nil
+}
+
+// charsetReader converts the body of a part to UTF-8 using the charset
+// declared by the mail client.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	charset = strings.ToLower(strings.TrimSpace(charset))
+
+	if charset == "" || charset == "us-ascii" {
+		return input, nil
+	}
+
+	enc, err := htmlindex.Get(charset)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return enc.NewDecoder().Reader(input), nil
+}
@@ -0,0 +1,159 @@
+package inbox
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// crlf turns a message written with \n into the CRLF lines of the wire.
+func crlf(s string) string {
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		wantTitle   string
+		wantContent string
+	}{
+		{
+			name:        "plain text without content type",
+			raw:         "Subject: Lista\n\nleite\novos\n",
+			wantTitle:   "Lista",
+			wantContent: "leite\novos",
+		},
+		{
+			name:        "encoded subject",
+			raw:         "Subject: =?UTF-8?B?UmV1bmnDo28=?=\nContent-Type: text/plain; charset=utf-8\n\ncorpo\n",
+			wantTitle:   "Reunião",
+			wantContent: "corpo",
+		},
+		{
+			name:        "latin-1 subject",
+			raw:         "Subject: =?ISO-8859-1?Q?Caf=E9?=\n\ncorpo\n",
+			wantTitle:   "Café",
+			wantContent: "corpo",
+		},
+		{
+			name:        "latin-1 body",
+			raw:         "Subject: a\nContent-Type: text/plain; charset=ISO-8859-1\n\nol\xe1, a\xe7\xe3o\n",
+			wantTitle:   "a",
+			wantContent: "olá, ação",
+		},
+		{
+			name:        "windows-1252 body",
+			raw:         "Subject: a\nContent-Type: text/plain; charset=windows-1252\n\n\x93aspas\x94\n",
+			wantTitle:   "a",
+			wantContent: "“aspas”",
+		},
+		{
+			name:        "quoted-printable",
+			raw:         "Subject: a\nContent-Type: text/plain; charset=utf-8\nContent-Transfer-Encoding: quoted-printable\n\nlinha =\nquebrada =C3=A9\n",
+			wantTitle:   "a",
+			wantContent: "linha quebrada é",
+		},
+		{
+			name:        "base64",
+			raw:         "Subject: a\nContent-Type: text/plain; charset=utf-8\nContent-Transfer-Encoding: base64\n\nb2zDoSBtdW5kbw==\n",
+			wantTitle:   "a",
+			wantContent: "olá mundo",
+		},
+		{
+			name: "html only",
+			raw: "Subject: a\nContent-Type: text/html; charset=utf-8\n\n" +
+				"<html><head><title>x</title><style>p{}</style></head><body><p>Oi &amp; tchau</p><ul><li>um</li><li>dois</li></ul><script>alert(1)</script></body></html>\n",
+			wantTitle:   "a",
+			wantContent: "Oi & tchau\n- um\n- dois",
+		},
+		{
+			name: "multipart alternative prefers plain text",
+			raw: "Subject: a\nContent-Type: multipart/alternative; boundary=b1\n\n" +
+				"--b1\nContent-Type: text/html\n\n<p>versão html</p>\n" +
+				"--b1\nContent-Type: text/plain; charset=utf-8\n\nversão texto\n" +
+				"--b1--\n",
+			wantTitle:   "a",
+			wantContent: "versão texto",
+		},
+		{
+			name: "nested multipart with attachment",
+			raw: "Subject: a\nContent-Type: multipart/mixed; boundary=outer\n\n" +
+				"--outer\nContent-Type: text/plain\nContent-Disposition: attachment; filename=a.txt\n\nanexo\n" +
+				"--outer\nContent-Type: multipart/alternative; boundary=inner\n\n" +
+				"--inner\nContent-Type: text/html\n\n<b>html</b>\n" +
+				"--inner--\n" +
+				"--outer--\n",
+			wantTitle:   "a",
+			wantContent: "html",
+		},
+		{
+			name:        "no subject",
+			raw:         "From: a@example.com\n\ncorpo\n",
+			wantTitle:   "",
+			wantContent: "corpo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := parseMessage(strings.NewReader(crlf(tt.raw)))
+
+			if err != nil {
+				t.Fatalf("parseMessage: %v", err)
+			}
+
+			if msg.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", msg.Title, tt.wantTitle)
+			}
+
+			if msg.Content != tt.wantContent {
+				t.Errorf("Content = %q, want %q", msg.Content, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestParseMessageErrors(t *testing.T) {
+	// one level more than maxMultipartDepth, with the text at the bottom
+	deep := "Subject: a\nContent-Type: multipart/mixed; boundary=b0\n\n"
+
+	for i := 1; i <= maxMultipartDepth; i++ {
+		deep += fmt.Sprintf("--b%d\nContent-Type: multipart/mixed; boundary=b%d\n\n", i-1, i)
+	}
+
+	deep += fmt.Sprintf("--b%d\nContent-Type: text/plain\n\nfundo demais\n", maxMultipartDepth)
+
+	for i := maxMultipartDepth; i >= 0; i-- {
+		deep += fmt.Sprintf("--b%d--\n", i)
+	}
+
+	tests := []struct {
+		name       string
+		raw        string
+		wantNoText bool
+	}{
+		{"empty body", "Subject: a\n\n   \n", true},
+		{"only an attachment", "Subject: a\nContent-Type: application/pdf\n\n%PDF\n", true},
+		{"html without text", "Subject: a\nContent-Type: text/html\n\n<img src=x>\n", true},
+		{"too deeply nested", deep, true},
+		{"unknown charset", "Subject: a\nContent-Type: text/plain; charset=x-unknown\n\ncorpo\n", false},
+		{"bad content type", "Subject: a\nContent-Type: text/plain; charset\n\ncorpo\n", false},
+		{"no header separator", "não é um email", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseMessage(strings.NewReader(crlf(tt.raw)))
+
+			if err == nil {
+				t.Fatal("parseMessage accepted the message")
+			}
+
+			if got := errors.Is(err, ErrNoTextContent); got != tt.wantNoText {
+				t.Fatalf("err = %v, want ErrNoTextContent: %v", err, tt.wantNoText)
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package inbox
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/repositories"
+	"go_pro/tools"
+	"io"
+	"log/slog"
+	"net"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	noteColor       = "color1"
+	untitledNote    = "(sem assunto)"
+	maxRecipients   = 20
+	maxCommandLine  = 1024
+	commandTimeout  = 5 * time.Minute
+	deliveryTimeout = 30 * time.Second
+)
+
+var errLineTooLong = errors.New("line too long")
+
+type Config struct {
+	Addr    string
+	Domain  string
+	MaxSize int64
+}
+
+// Server is a minimal SMTP receiver that only accepts mail addressed to
+// <inbox token>@<domain> and turns every message into a note.
+type Server struct {
+	cfg   Config
+	users repositories.UserRepository
+	notes repositories.NoteRepository
+}
+
+func NewServer(cfg Config, users repositories.UserRepository, notes repositories.NoteRepository) *Server {
+	return &Server{cfg: cfg, users: users, notes: notes}
+}
+
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.cfg.Addr)
+
+	if err != nil {
+		return err
+	}
+
+	slog.Info(fmt.Sprintf("Caixa de entrada SMTP rodando em %s\n", s.cfg.Addr))
+
+	return s.Serve(listener)
+}
+
+func (s *Server) Serve(listener net.Listener) error {
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+
+		if err != nil {
+			var netErr net.Error
+
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+
+			return err
+		}
+
+		go s.handle(conn)
+	}
+}
+
+type session struct {
+	reader     *bufio.Reader
+	writer     *bufio.Writer
+	from       string
+	recipients []int
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	sess := &session{
+		reader: bufio.NewReaderSize(conn, maxCommandLine),
+		writer: bufio.NewWriter(conn),
+	}
+
+	sess.reply(220, "%s Quicknotes inbox ready", s.cfg.Domain)
+
+	for {
+		conn.SetDeadline(time.Now().Add(commandTimeout))
+
+		line, err := sess.readLine()
+
+		if err == errLineTooLong {
+			sess.reply(500, "5.5.2 line too long")
+			continue
+		}
+
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			sess.reset()
+			sess.reply(250, "%s", s.cfg.Domain)
+		case "EHLO":
+			sess.reset()
+			sess.reply(250, "%s\n8BITMIME\nSIZE %d", s.cfg.Domain, s.cfg.MaxSize)
+		case "MAIL":
+			s.mail(sess, arg)
+		case "RCPT":
+			s.rcpt(sess, arg)
+		case "DATA":
+			s.data(sess)
+		case "RSET":
+			sess.reset()
+			sess.reply(250, "2.0.0 ok")
+		case "NOOP":
+			sess.reply(250, "2.0.0 ok")
+		case "VRFY":
+			sess.reply(252, "2.1.5 cannot verify user")
+		case "QUIT":
+			sess.reply(221, "2.0.0 bye")
+			return
+		default:
+			sess.reply(502, "5.5.1 command not implemented")
+		}
+	}
+}
+
+func (s *Server) mail(sess *session, arg string) {
+	from, params, ok := parsePath(arg, "FROM:")
+
+	if !ok {
+		sess.reply(501, "5.5.4 syntax: MAIL FROM:<address>")
+		return
+	}
+
+	if size, ok := params["SIZE"]; ok {
+		declared, err := strconv.ParseInt(size, 10, 64)
+
+		if err == nil && declared > s.cfg.MaxSize {
+			sess.reply(552, "5.3.4 message size exceeds the limit of %d bytes", s.cfg.MaxSize)
+			return
+		}
+	}
+
+	sess.reset()
+	sess.from = from
+	sess.reply(250, "2.1.0 ok")
+}
+
+func (s *Server) rcpt(sess *session, arg string) {
+	if sess.from == "" {
+		sess.reply(503, "5.5.1 need MAIL before RCPT")
+		return
+	}
+
+	to, _, ok := parsePath(arg, "TO:")
+
+	if !ok {
+		sess.reply(501, "5.5.4 syntax: RCPT TO:<address>")
+		return
+	}
+
+	if len(sess.recipients) >= maxRecipients {
+		sess.reply(452, "4.5.3 too many recipients")
+		return
+	}
+
+	token, domain, found := strings.Cut(to, "@")
+
+	if !found || !strings.EqualFold(domain, s.cfg.Domain) {
+		sess.reply(550, "5.1.1 mailbox unavailable")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+
+	userId, err := s.users.FindUserIdByInboxToken(ctx, tools.HashToken(strings.ToLower(token)))
+
+	if err == repositories.ErrInboxTokenNotFound {
+		sess.reply(550, "5.1.1 mailbox unavailable")
+		return
+	}
+
+	if err != nil {
+		slog.Error(err.Error())
+		sess.reply(451, "4.3.0 temporary failure, try again later")
+		return
+	}
+
+	if !slices.Contains(sess.recipients, userId) {
+		sess.recipients = append(sess.recipients, userId)
+	}
+
+	sess.reply(250, "2.1.5 ok")
+}
+
+func (s *Server) data(sess *session) {
+	if len(sess.recipients) == 0 {
+		sess.reply(503, "5.5.1 need RCPT before DATA")
+		return
+	}
+
+	sess.reply(354, "end data with <CR><LF>.<CR><LF>")
+
+	dot := textproto.NewReader(sess.reader).DotReader()
+	raw, err := io.ReadAll(io.LimitReader(dot, s.cfg.MaxSize+1))
+
+	if err != nil {
+		return
+	}
+
+	if int64(len(raw)) > s.cfg.MaxSize {
+		io.Copy(io.Discard, dot)
+		sess.reset()
+		sess.reply(552, "5.3.4 message size exceeds the limit of %d bytes", s.cfg.MaxSize)
+		return
+	}
+
+	defer sess.reset()
+
+	msg, err := parseMessage(bytes.NewReader(raw))
+
+	if err != nil {
+		slog.Warn("mensagem recebida inválida", "error", err.Error())
+		sess.reply(554, "5.6.0 %s", err.Error())
+		return
+	}
+
+	title := msg.Title
+
+	if title == "" {
+		title = untitledNote
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+
+	// every recipient gets the note or none does: the sender retries the
+	// whole message after a failure
+	if err := s.notes.CreateForUsers(ctx, sess.recipients, title, msg.Content, noteColor); err != nil {
+		var statusError apperrors.StatusError
+
+		if errors.As(err, &statusError) {
+			slog.Warn("mensagem recusada pela cota do usuário", "error", err.Error())
+			sess.reply(552, "5.2.2 %s", err.Error())
+			return
+		}
+
+		slog.Error(err.Error())
+		sess.reply(451, "4.3.0 temporary failure, try again later")
+		return
+	}
+
+	sess.reply(250, "2.0.0 note created")
+}
+
+func (sess *session) reset() {
+	sess.from = ""
+	sess.recipients = nil
+}
+
+func (sess *session) reply(code int, format string, args ...any) {
+	lines := strings.Split(fmt.Sprintf(format, args...), "\n")
+
+	for i, line := range lines {
+		sep := " "
+
+		if i < len(lines)-1 {
+			sep = "-"
+		}
+
+		fmt.Fprintf(sess.writer, "%d%s%s\r\n", code, sep, line)
+	}
+
+	sess.writer.Flush()
+}
+
+// readLine reads a command line, refusing lines longer than the buffer so a
+// client cannot make the server hold an unbounded line in memory.
+func (sess *session) readLine() (string, error) {
+	line, err := sess.reader.ReadSlice('\n')
+
+	if err == bufio.ErrBufferFull {
+		for err == bufio.ErrBufferFull {
+			_, err = sess.reader.ReadSlice('\n')
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		return "", errLineTooLong
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// parsePath reads "FROM:<addr> PARAM=value" style arguments. The null reverse
+// path "<>" is accepted for bounces.
+func parsePath(arg, prefix string) (string, map[string]string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+
+	rest := strings.TrimSpace(arg[len(prefix):])
+
+	if !strings.HasPrefix(rest, "<") {
+		return "", nil, false
+	}
+
+	end := strings.Index(rest, ">")
+
+	if end < 0 {
+		return "", nil, false
+	}
+
+	path := rest[1:end]
+	params := map[string]string{}
+
+	for _, param := range strings.Fields(rest[end+1:]) {
+		key, value, _ := strings.Cut(param, "=")
+		params[strings.ToUpper(key)] = value
+	}
+
+	if path == "" {
+		path = "<>"
+	}
+
+	return path, params, true
+}
@@ -0,0 +1,139 @@
+package inbox
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"go_pro/internal/repositories"
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		name       string
+		arg        string
+		prefix     string
+		wantPath   string
+		wantParams map[string]string
+		wantOk     bool
+	}{
+		{"address", "FROM:<a@example.com>", "FROM:", "a@example.com", map[string]string{}, true},
+		{"lowercase prefix", "to:<a@example.com>", "TO:", "a@example.com", map[string]string{}, true},
+		{"space after the colon", "FROM: <a@example.com>", "FROM:", "a@example.com", map[string]string{}, true},
+		{"null reverse path", "FROM:<>", "FROM:", "<>", map[string]string{}, true},
+		{"parameters", "FROM:<a@example.com> size=1024 BODY=8BITMIME", "FROM:", "a@example.com",
+			map[string]string{"SIZE": "1024", "BODY": "8BITMIME"}, true},
+		{"parameter without value", "FROM:<a@example.com> SMTPUTF8", "FROM:", "a@example.com",
+			map[string]string{"SMTPUTF8": ""}, true},
+		{"wrong prefix", "TO:<a@example.com>", "FROM:", "", nil, false},
+		{"short argument", "FR", "FROM:", "", nil, false},
+		{"missing brackets", "FROM:a@example.com", "FROM:", "", nil, false},
+		{"unclosed bracket", "FROM:<a@example.com", "FROM:", "", nil, false},
+		{"empty", "", "TO:", "", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, params, ok := parsePath(tt.arg, tt.prefix)
+
+			if ok != tt.wantOk || path != tt.wantPath || !maps.Equal(params, tt.wantParams) {
+				t.Fatalf("parsePath(%q) = %q, %v, %v, want %q, %v, %v", tt.arg, path, params, ok, tt.wantPath, tt.wantParams, tt.wantOk)
+			}
+		})
+	}
+}
+
+// fakeNotes records the notes the server creates. Any other method of the
+// repository panics.
+type fakeNotes struct {
+	repositories.NoteRepository
+	created []string
+}
+
+func (fn *fakeNotes) CreateForUsers(ctx context.Context, userIds []int, title, content, color string) error {
+	fn.created = append(fn.created, title+": "+content)
+	return nil
+}
+
+// newTestSession returns a session that reads the client input from in and
+// has already accepted the sender and one recipient.
+func newTestSession(in string) (*session, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+
+	return &session{
+		reader:     bufio.NewReaderSize(strings.NewReader(crlf(in)), maxCommandLine),
+		writer:     bufio.NewWriter(out),
+		from:       "a@example.com",
+		recipients: []int{1},
+	}, out
+}
+
+func TestDataSizeLimit(t *testing.T) {
+	// the limit applies to the message after the dot reader turned its CRLF
+	// line ends into LF
+	message := "Subject: nota\n\ncorpo\n"
+
+	tests := []struct {
+		name      string
+		maxSize   int64
+		wantReply string
+		wantNotes int
+	}{
+		{"under the limit", 100, "250 ", 1},
+		{"exactly the limit", int64(len(message)), "250 ", 1},
+		{"over the limit", int64(len(message)) - 1, "552 ", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notes := &fakeNotes{}
+			s := &Server{cfg: Config{Domain: "example.com", MaxSize: tt.maxSize}, notes: notes}
+			sess, out := newTestSession(message + ".\nQUIT\n")
+
+			s.data(sess)
+
+			replies := strings.Split(strings.TrimSpace(out.String()), "\r\n")
+
+			if last := replies[len(replies)-1]; !strings.HasPrefix(last, tt.wantReply) {
+				t.Fatalf("reply = %q, want %q", last, tt.wantReply)
+			}
+
+			if len(notes.created) != tt.wantNotes {
+				t.Fatalf("created %d notes, want %d", len(notes.created), tt.wantNotes)
+			}
+
+			// the rest of the message was read, the next command comes after it
+			if line, err := sess.readLine(); err != nil || line != "QUIT" {
+				t.Fatalf("next line = %q, %v, want QUIT", line, err)
+			}
+		})
+	}
+}
+
+func TestMailDeclaredSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		arg       string
+		wantReply string
+	}{
+		{"no size", "FROM:<a@example.com>", "250 "},
+		{"under the limit", "FROM:<a@example.com> SIZE=100", "250 "},
+		{"over the limit", "FROM:<a@example.com> SIZE=101", "552 "},
+		{"invalid size", "FROM:<a@example.com> SIZE=abc", "250 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{cfg: Config{Domain: "example.com", MaxSize: 100}}
+			sess, out := newTestSession("")
+
+			s.mail(sess, tt.arg)
+
+			if !strings.HasPrefix(out.String(), tt.wantReply) {
+				t.Fatalf("reply = %q, want %q", out.String(), tt.wantReply)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+const (
+	AccessTokenScopeRead  = "read"
+	AccessTokenScopeWrite = "write"
+)
+
+type AccessToken struct {
+	Id         pgtype.Numeric
+	Name       pgtype.Text
+	Scope      pgtype.Text
+	ExpiresAt  pgtype.Timestamp
+	LastUsedAt pgtype.Timestamp
+	CreatedAt  pgtype.Timestamp
+}
@@ -0,0 +1,33 @@
+package models
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+const (
+	AdminActionDisable       = "disable"
+	AdminActionEnable        = "enable"
+	AdminActionConfirm       = "confirm"
+	AdminActionPasswordReset = "password_reset"
+	AdminActionDelete        = "delete"
+)
+
+// AdminUser is a user as listed in the admin panel.
+type AdminUser struct {
+	Id          pgtype.Numeric
+	Email       pgtype.Text
+	Role        pgtype.Text
+	Active      pgtype.Bool
+	DisabledAt  pgtype.Timestamp
+	LastLoginAt pgtype.Timestamp
+	CreatedAt   pgtype.Timestamp
+	Notes       pgtype.Int8
+}
+
+// AdminAuditEntry records an action taken by an admin on a user. The emails
+// are copied so the entry survives the deletion of either account.
+type AdminAuditEntry struct {
+	Id          pgtype.Numeric
+	AdminEmail  pgtype.Text
+	TargetEmail pgtype.Text
+	Action      pgtype.Text
+	CreatedAt   pgtype.Timestamp
+}
@@ -0,0 +1,14 @@
+package models
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+type Comment struct {
+	Id          pgtype.Numeric
+	NoteId      pgtype.Numeric
+	UserId      pgtype.Numeric
+	AuthorEmail pgtype.Text
+	AuthorName  pgtype.Text
+	Body        pgtype.Text
+	CreatedAt   pgtype.Timestamp
+	UpdatedAt   pgtype.Timestamp
+}
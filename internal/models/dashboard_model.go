@@ -0,0 +1,33 @@
+package models
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+type NoteColorStat struct {
+	Color pgtype.Text
+	Total pgtype.Int8
+}
+
+type NoteWeekStat struct {
+	Week  pgtype.Date
+	Total pgtype.Int8
+}
+
+type NoteLengthStat struct {
+	Id     pgtype.Numeric
+	Title  pgtype.Text
+	Length pgtype.Int8
+}
+
+type NoteStaleStat struct {
+	Id          pgtype.Numeric
+	Title       pgtype.Text
+	LastTouched pgtype.Timestamp
+}
+
+type NoteDashboard struct {
+	Total   pgtype.Int8
+	ByColor []NoteColorStat
+	PerWeek []NoteWeekStat
+	Longest []NoteLengthStat
+	Stale   []NoteStaleStat
+}
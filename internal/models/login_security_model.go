@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// LockoutPolicy locks an account for Duration after Threshold failed
+// password checks in a row. Each new lockout doubles the duration, up to
+// MaxDuration, until the user signs in or unlocks the account.
+type LockoutPolicy struct {
+	Threshold   int
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+type Lockout struct {
+	FailedAttempts int
+	LockedUntil    pgtype.Timestamp
+	// Remaining is how long the account stays locked, zero when it is not.
+	Remaining time.Duration
+}
+
+type FailedLogin struct {
+	Id        pgtype.Numeric
+	IP        pgtype.Text
+	UserAgent pgtype.Text
+	CreatedAt pgtype.Timestamp
+}
+
+type Device struct {
+	Id         pgtype.Numeric
+	IP         pgtype.Text
+	UserAgent  pgtype.Text
+	Current    pgtype.Bool
+	CreatedAt  pgtype.Timestamp
+	LastSeenAt pgtype.Timestamp
+}
@@ -4,11 +4,53 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const (
+	NoteSortCreated = "created"
+	NoteSortRecent  = "recent"
+	NoteSortTitle   = "title"
+	NoteSortCustom  = "custom"
+)
+
 type Note struct {
 	Id        pgtype.Numeric
 	Title     pgtype.Text
 	Content   pgtype.Text
 	Color     pgtype.Text
-	CreatedAt pgtype.Date
-	UpdatedAt pgtype.Date
+	DueAt     pgtype.Timestamp
+	Locked    pgtype.Bool
+	CreatedAt pgtype.Timestamp
+	UpdatedAt pgtype.Timestamp
+}
+
+// NoteScope is the set of notes a query sees: the personal notes of UserId
+// or, when WorkspaceId is set, the notes of that workspace, as long as UserId
+// is one of its members.
+type NoteScope struct {
+	UserId      int
+	WorkspaceId int
+}
+
+// NoteAccess is how the current user relates to a note: Role is their role in
+// the workspace of the note, empty for a personal note, and Author tells if
+// they wrote it.
+type NoteAccess struct {
+	Role   string
+	Author bool
+}
+
+// CanManage tells if the user may delete the note, lock it or remove its lock.
+// Every member reads and edits the notes of a workspace, but only the author
+// and the owners and admins of the workspace manage them.
+func (a NoteAccess) CanManage() bool {
+	switch a.Role {
+	case "", WorkspaceRoleOwner, WorkspaceRoleAdmin:
+		return true
+	default:
+		return a.Author
+	}
+}
+
+type NoteLock struct {
+	Password pgtype.Text
+	Salt     []byte
 }
@@ -0,0 +1,28 @@
+package models
+
+import "testing"
+
+func TestNoteAccessCanManage(t *testing.T) {
+	tests := []struct {
+		name   string
+		access NoteAccess
+		want   bool
+	}{
+		{"personal note", NoteAccess{Role: "", Author: true}, true},
+		{"owner, author", NoteAccess{Role: WorkspaceRoleOwner, Author: true}, true},
+		{"owner, other author", NoteAccess{Role: WorkspaceRoleOwner}, true},
+		{"admin, author", NoteAccess{Role: WorkspaceRoleAdmin, Author: true}, true},
+		{"admin, other author", NoteAccess{Role: WorkspaceRoleAdmin}, true},
+		{"member, author", NoteAccess{Role: WorkspaceRoleMember, Author: true}, true},
+		{"member, other author", NoteAccess{Role: WorkspaceRoleMember}, false},
+		{"unknown role", NoteAccess{Role: "guest"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.access.CanManage(); got != tt.want {
+				t.Fatalf("CanManage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
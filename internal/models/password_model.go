@@ -0,0 +1,18 @@
+package models
+
+// PasswordPolicy holds the rules every new password must follow. BreachedList
+// is an optional file of known passwords, one per line, checked on top of the
+// embedded list of common ones.
+type PasswordPolicy struct {
+	MinLength    int
+	MinEntropy   float64
+	BreachedList string
+}
+
+// PasswordHashParams are the argon2id costs new password hashes are written
+// with. Memory is in KiB.
+type PasswordHashParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
@@ -0,0 +1,23 @@
+package models
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+const (
+	DefaultLocale   = "pt-BR"
+	DefaultTimezone = "America/Sao_Paulo"
+)
+
+// AvatarSizes are the sides, in pixels, an uploaded avatar is resized to.
+var AvatarSizes = []int{32, 128}
+
+// Profile is how a user presents themselves. Users that never saved it get
+// the default locale and timezone.
+type Profile struct {
+	UserId      pgtype.Numeric
+	Email       pgtype.Text
+	DisplayName pgtype.Text
+	Locale      pgtype.Text
+	Timezone    pgtype.Text
+	HasAvatar   pgtype.Bool
+	UpdatedAt   pgtype.Timestamp
+}
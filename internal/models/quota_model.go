@@ -0,0 +1,18 @@
+package models
+
+type Quota struct {
+	MaxNotes    int64
+	MaxNoteSize int64
+	MaxStorage  int64
+}
+
+type QuotaUsage struct {
+	Quota
+	Notes   int64
+	Storage int64
+}
+
+// NoteSize is the number of bytes a note counts against the storage quota.
+func NoteSize(title, content string) int64 {
+	return int64(len(title) + len(content))
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RateLimit allows Requests per Period, refilled continuously, with bursts
+// of up to Requests.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// FormRateLimit holds the limits of a form by client address and by the
+// account it is for, usually the email it was sent with.
+type FormRateLimit struct {
+	ByIP    RateLimit
+	ByEmail RateLimit
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// UserSession is a signed in session of a user, with the device it was
+// started from.
+type UserSession struct {
+	Id         pgtype.Numeric
+	IP         pgtype.Text
+	UserAgent  pgtype.Text
+	Current    pgtype.Bool
+	CreatedAt  pgtype.Timestamp
+	LastSeenAt pgtype.Timestamp
+}
+
+// SessionPolicy holds how long sessions last. Remembered sessions keep their
+// cookie when the browser is closed and get their own lifetimes.
+type SessionPolicy struct {
+	Lifetime            time.Duration
+	IdleTimeout         time.Duration
+	RememberLifetime    time.Duration
+	RememberIdleTimeout time.Duration
+}
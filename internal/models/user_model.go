@@ -2,11 +2,22 @@ package models
 
 import "github.com/jackc/pgx/v5/pgtype"
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	Id        pgtype.Numeric
 	Email     pgtype.Text
 	Password  pgtype.Text
 	Active    pgtype.Bool
-	CreatedAt pgtype.Date
-	UpdatedAt pgtype.Date
+	CreatedAt pgtype.Timestamp
+	UpdatedAt pgtype.Timestamp
+
+	DeletionRequestedAt pgtype.Timestamp
+	TOTPSecret          pgtype.Text
+	TOTPEnabled         pgtype.Bool
+	Role                pgtype.Text
+	DisabledAt          pgtype.Timestamp
 }
@@ -1,12 +1,42 @@
 package models
 
-import "github.com/jackc/pgx/v5/pgtype"
-
-type UserConfirmmationToken struct {
-	Id        pgtype.Numeric
-	UserId    pgtype.Numeric
-	Token     pgtype.Text
-	Confirmed pgtype.Bool
-	CreatedAt pgtype.Date
-	UpdatedAt pgtype.Date
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	TokenPurposeConfirmation        = "confirmation"
+	TokenPurposePasswordReset       = "password_reset"
+	TokenPurposeMagicLink           = "magic_link"
+	TokenPurposeWorkspaceInvitation = "workspace_invitation"
+)
+
+// UserToken is a single use token sent by email. Only its SHA-256 hash is
+// stored.
+type UserToken struct {
+	Id         pgtype.Numeric
+	UserId     pgtype.Numeric
+	Purpose    pgtype.Text
+	DeviceHash pgtype.Text
+	ExpiresAt  pgtype.Timestamp
+	UsedAt     pgtype.Timestamp
+	CreatedAt  pgtype.Timestamp
+}
+
+// MagicLinkPolicy holds how long a sign in link is valid and if it only works
+// in the browser it was asked from.
+type MagicLinkPolicy struct {
+	TTL         time.Duration
+	SameBrowser bool
+}
+
+// UserTokenTTL is how long the tokens of each purpose are valid.
+type UserTokenTTL struct {
+	Confirmation        time.Duration
+	PasswordReset       time.Duration
+	MagicLink           time.Duration
+	WorkspaceInvitation time.Duration
+	EmailChange         time.Duration
 }
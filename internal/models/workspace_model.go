@@ -0,0 +1,43 @@
+package models
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleMember = "member"
+)
+
+// Workspace is a team space as seen by one of its members, with the role
+// that member has.
+type Workspace struct {
+	Id        pgtype.Numeric
+	Name      pgtype.Text
+	Role      pgtype.Text
+	CreatedAt pgtype.Timestamp
+}
+
+// CanManage tells if the role may invite and remove members.
+func (w *Workspace) CanManage() bool {
+	return w.Role.String == WorkspaceRoleOwner || w.Role.String == WorkspaceRoleAdmin
+}
+
+type WorkspaceMember struct {
+	UserId    pgtype.Numeric
+	Email     pgtype.Text
+	Name      pgtype.Text
+	Role      pgtype.Text
+	CreatedAt pgtype.Timestamp
+}
+
+// WorkspaceInvitation is a pending invitation. Email is the address it was
+// sent to, the one the invited user must be signed in with to accept it.
+type WorkspaceInvitation struct {
+	Id            pgtype.Numeric
+	WorkspaceId   pgtype.Numeric
+	WorkspaceName pgtype.Text
+	Email         pgtype.Text
+	Role          pgtype.Text
+	InvitedBy     pgtype.Text
+	ExpiresAt     pgtype.Timestamp
+}
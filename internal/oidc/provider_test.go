@@ -0,0 +1,255 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID = "quicknotes"
+	testKeyID    = "key-1"
+	testCode     = "auth-code"
+)
+
+// fakeIssuer serves the discovery document, the keys and the token endpoint
+// of an OpenID Connect provider. The token endpoint answers with idToken
+// when the code verifier matches the challenge of the authorization request.
+type fakeIssuer struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	idToken   string
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi := &fakeIssuer{key: key}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 fi.server.URL,
+			"authorization_endpoint": fi.server.URL + "/authorize",
+			"token_endpoint":         fi.server.URL + "/token",
+			"jwks_uri":               fi.server.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": testKeyID,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != testCode || Challenge(r.PostFormValue("code_verifier")) != fi.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{"id_token": fi.idToken})
+	})
+
+	fi.server = httptest.NewServer(mux)
+	t.Cleanup(fi.server.Close)
+
+	return fi
+}
+
+func (fi *fakeIssuer) provider() *Provider {
+	return NewProvider(Config{
+		Issuer:      fi.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost/user/oidc/callback",
+		HTTPClient:  fi.server.Client(),
+	})
+}
+
+// claims returns the claims of a valid ID token for nonce.
+func (fi *fakeIssuer) claims(nonce string) map[string]any {
+	now := time.Now()
+
+	return map[string]any{
+		"iss":            fi.server.URL,
+		"sub":            "user-1",
+		"aud":            testClientID,
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          nonce,
+		"email":          "user@example.com",
+		"email_verified": true,
+	}
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, alg string, claims map[string]any) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": testKeyID, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// authorize follows AuthCodeURL the way the browser would, handing the
+// challenge to the fake issuer.
+func authorize(t *testing.T, fi *fakeIssuer, p *Provider, nonce, verifier string) {
+	t.Helper()
+
+	address, err := p.AuthCodeURL(context.Background(), "state", nonce, verifier)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(address)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := u.Query().Get("code_challenge_method"); got != "S256" {
+		t.Fatalf("code_challenge_method = %q, want S256", got)
+	}
+
+	fi.challenge = u.Query().Get("code_challenge")
+}
+
+func TestExchange(t *testing.T) {
+	fi := newFakeIssuer(t)
+	p := fi.provider()
+	verifier := GenerateVerifier()
+
+	authorize(t, fi, p, "nonce-1", verifier)
+	fi.idToken = sign(t, fi.key, "RS256", fi.claims("nonce-1"))
+
+	claims, err := p.Exchange(context.Background(), testCode, verifier, "nonce-1")
+
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	if claims.Subject != "user-1" || claims.Email != "user@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func TestReauthCodeURLForcesSignIn(t *testing.T) {
+	fi := newFakeIssuer(t)
+	p := fi.provider()
+
+	address, err := p.ReauthCodeURL(context.Background(), "state", "nonce-1", GenerateVerifier())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(address)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := u.Query().Get("prompt"); got != "login" {
+		t.Fatalf("prompt = %q, want login", got)
+	}
+
+	if got := u.Query().Get("max_age"); got != "0" {
+		t.Fatalf("max_age = %q, want 0", got)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	fi := newFakeIssuer(t)
+	p := fi.provider()
+
+	authorize(t, fi, p, "nonce-1", GenerateVerifier())
+	fi.idToken = sign(t, fi.key, "RS256", fi.claims("nonce-1"))
+
+	if _, err := p.Exchange(context.Background(), testCode, GenerateVerifier(), "nonce-1"); err == nil {
+		t.Fatal("Exchange accepted a code verifier that does not match the challenge")
+	}
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	fi := newFakeIssuer(t)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{"signature", func() string {
+			return sign(t, otherKey, "RS256", fi.claims("nonce-1"))
+		}},
+		{"algorithm", func() string {
+			return sign(t, fi.key, "none", fi.claims("nonce-1"))
+		}},
+		{"issuer", func() string {
+			claims := fi.claims("nonce-1")
+			claims["iss"] = "https://attacker.example.com"
+			return sign(t, fi.key, "RS256", claims)
+		}},
+		{"audience", func() string {
+			claims := fi.claims("nonce-1")
+			claims["aud"] = "another-client"
+			return sign(t, fi.key, "RS256", claims)
+		}},
+		{"expiry", func() string {
+			claims := fi.claims("nonce-1")
+			claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			return sign(t, fi.key, "RS256", claims)
+		}},
+		{"nonce", func() string {
+			return sign(t, fi.key, "RS256", fi.claims("nonce-2"))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fi.provider().Verify(context.Background(), tt.token(), "nonce-1")
+
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("Verify error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
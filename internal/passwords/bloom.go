@@ -0,0 +1,62 @@
+package passwords
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// bloomFilter answers whether a string was added to it. It never misses an
+// added string, but may claim one it never saw with probability about the
+// rate it was built with.
+type bloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint64
+}
+
+func newBloomFilter(entries int, rate float64) *bloomFilter {
+	n := float64(max(entries, 1))
+	size := uint64(math.Ceil(-n * math.Log(rate) / (math.Ln2 * math.Ln2)))
+	hashes := uint64(max(1, math.Round(float64(size)/n*math.Ln2)))
+
+	return &bloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+func (bf *bloomFilter) Add(value string) {
+	h1, h2 := bloomHash(value)
+
+	for i := uint64(0); i < bf.hashes; i++ {
+		bit := (h1 + i*h2) % bf.size
+		bf.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (bf *bloomFilter) Contains(value string) bool {
+	h1, h2 := bloomHash(value)
+
+	for i := uint64(0); i < bf.hashes; i++ {
+		bit := (h1 + i*h2) % bf.size
+
+		if bf.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bloomHash derives the two hashes the positions of a value are built from.
+func bloomHash(value string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	h1 := h.Sum64()
+
+	h.Write([]byte{0})
+	h2 := h.Sum64() | 1
+
+	return h1, h2
+}
@@ -0,0 +1,141 @@
+package passwords
+
+import (
+	"encoding/base64"
+	"fmt"
+	"go_pro/internal/models"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams keep the tests fast, they are far below what production uses.
+var testParams = models.PasswordHashParams{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestHasherVerify(t *testing.T) {
+	hasher := NewHasher(testParams)
+
+	argon, err := hasher.Hash("senha-correta")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	older, err := NewHasher(models.PasswordHashParams{Memory: 32, Iterations: 2, Parallelism: 1}).Hash("senha-correta")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("senha-correta"), bcrypt.MinCost)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a hash written with a 16 byte key, and the key of argon cut to 16 bytes,
+	// which is not the same: the length is an input of argon2
+	parts := strings.Split(argon, "$")
+	salt, _ := base64.RawStdEncoding.DecodeString(parts[4])
+	key, _ := base64.RawStdEncoding.DecodeString(parts[5])
+	shortKey := argon2.IDKey([]byte("senha-correta"), salt, testParams.Iterations, testParams.Memory, testParams.Parallelism, 16)
+	short := strings.Join(append(parts[:5:5], base64.RawStdEncoding.EncodeToString(shortKey)), "$")
+	truncated := strings.Join(append(parts[:5:5], base64.RawStdEncoding.EncodeToString(key[:16])), "$")
+
+	tests := []struct {
+		name       string
+		password   string
+		hash       string
+		wantOk     bool
+		wantRehash bool
+	}{
+		{"argon2id", "senha-correta", argon, true, false},
+		{"argon2id, wrong password", "senha-errada", argon, false, false},
+		{"argon2id with other parameters", "senha-correta", older, true, true},
+		{"argon2id with other parameters, wrong password", "senha-errada", older, false, false},
+		{"argon2id with a shorter key", "senha-correta", short, true, true},
+		{"argon2id with a truncated key", "senha-correta", truncated, false, false},
+		{"bcrypt is rehashed", "senha-correta", string(legacy), true, true},
+		{"bcrypt, wrong password", "senha-errada", string(legacy), false, false},
+		{"empty password", "", argon, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash := hasher.Verify(tt.password, tt.hash)
+
+			if ok != tt.wantOk || rehash != tt.wantRehash {
+				t.Fatalf("Verify() = %v, %v, want %v, %v", ok, rehash, tt.wantOk, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestHasherRehashVerifies(t *testing.T) {
+	hasher := NewHasher(testParams)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("senha-correta"), bcrypt.MinCost)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, rehash := hasher.Verify("senha-correta", string(legacy)); !ok || !rehash {
+		t.Fatalf("Verify(bcrypt) = %v, %v, want true, true", ok, rehash)
+	}
+
+	hash, err := hasher.Hash("senha-correta")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(hash, fmt.Sprintf("$argon2id$v=19$m=%d,t=%d,p=%d$", testParams.Memory, testParams.Iterations, testParams.Parallelism)) {
+		t.Fatalf("Hash() = %q, not argon2id with the configured parameters", hash)
+	}
+
+	if ok, rehash := hasher.Verify("senha-correta", hash); !ok || rehash {
+		t.Fatalf("Verify(new hash) = %v, %v, want true, false", ok, rehash)
+	}
+
+	if other, _ := hasher.Hash("senha-correta"); other == hash {
+		t.Fatal("Hash() wrote the same hash twice, the salt is not random")
+	}
+}
+
+func TestHasherVerifyMalformed(t *testing.T) {
+	hasher := NewHasher(testParams)
+	salt := base64.RawStdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	key := base64.RawStdEncoding.EncodeToString(make([]byte, keyLength))
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"empty", ""},
+		{"no password", "!external"},
+		{"plain text", "senha-correta"},
+		{"missing fields", "$argon2id$v=19$m=64,t=1,p=1$" + salt},
+		{"extra fields", "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$" + key + "$x"},
+		{"other algorithm", "$argon2i$v=19$m=64,t=1,p=1$" + salt + "$" + key},
+		{"other version", "$argon2id$v=16$m=64,t=1,p=1$" + salt + "$" + key},
+		{"no version", "$argon2id$x$m=64,t=1,p=1$" + salt + "$" + key},
+		{"bad parameters", "$argon2id$v=19$m=a,t=1,p=1$" + salt + "$" + key},
+		{"zero iterations", "$argon2id$v=19$m=64,t=0,p=1$" + salt + "$" + key},
+		{"zero parallelism", "$argon2id$v=19$m=64,t=1,p=0$" + salt + "$" + key},
+		{"bad salt", "$argon2id$v=19$m=64,t=1,p=1$!!$" + key},
+		{"bad key", "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$!!"},
+		{"empty key", "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$"},
+		{"broken bcrypt", "$2a$10$short"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok, rehash := hasher.Verify("senha-correta", tt.hash); ok || rehash {
+				t.Fatalf("Verify(%q) = %v, %v, want false, false", tt.hash, ok, rehash)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package passwords
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"go_pro/internal/models"
+	"go_pro/internal/validations"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// common.txt lists passwords that show up the most in public breaches.
+//
+//go:embed common.txt
+var commonPasswords string
+
+// falsePositiveRate is how often a password that is on no list is still
+// refused as known.
+const falsePositiveRate = 0.001
+
+// Checker applies the password policy to every password a user sets.
+type Checker struct {
+	policy models.PasswordPolicy
+	known  *bloomFilter
+}
+
+// NewChecker loads the embedded list of common passwords, and the breached
+// list of the policy when there is one, into a bloom filter.
+func NewChecker(policy models.PasswordPolicy) (*Checker, error) {
+	known := []string{}
+
+	if err := readList(strings.NewReader(commonPasswords), &known); err != nil {
+		return nil, err
+	}
+
+	if policy.BreachedList != "" {
+		file, err := os.Open(policy.BreachedList)
+
+		if err != nil {
+			return nil, fmt.Errorf("error opening the breached password list: %w", err)
+		}
+
+		defer file.Close()
+
+		if err := readList(file, &known); err != nil {
+			return nil, fmt.Errorf("error reading the breached password list: %w", err)
+		}
+	}
+
+	filter := newBloomFilter(len(known), falsePositiveRate)
+
+	for _, password := range known {
+		filter.Add(password)
+	}
+
+	return &Checker{policy: policy, known: filter}, nil
+}
+
+func readList(r io.Reader, list *[]string) error {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		if password := normalize(scanner.Text()); password != "" {
+			*list = append(*list, password)
+		}
+	}
+
+	return scanner.Err()
+}
+
+func normalize(password string) string {
+	return strings.ToLower(strings.TrimSpace(password))
+}
+
+// Validate adds to form, under field, the first rule of the policy password
+// breaks. The email of the account is used to refuse passwords built on it.
+func (c *Checker) Validate(form *validations.FormValidator, field, password, email string) {
+	if utf8.RuneCountInString(password) < c.policy.MinLength {
+		form.AddFieldError(field, fmt.Sprintf("Senha precisa ter no mínimo %d caracteres", c.policy.MinLength))
+		return
+	}
+
+	if containsEmail(password, email) {
+		form.AddFieldError(field, "Senha não pode conter o seu email")
+		return
+	}
+
+	if c.known.Contains(normalize(password)) {
+		form.AddFieldError(field, "Essa senha é muito comum ou já apareceu em vazamentos de dados. Escolha outra")
+		return
+	}
+
+	if Measure(password).Entropy < c.policy.MinEntropy {
+		form.AddFieldError(field, "Senha muito fraca. Use uma senha mais longa, misturando letras, números e símbolos")
+	}
+}
+
+// containsEmail tells if password contains the email or the part of it
+// before the @, when that is long enough to matter.
+func containsEmail(password, email string) bool {
+	password = strings.ToLower(password)
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	if email == "" {
+		return false
+	}
+
+	if strings.Contains(password, email) {
+		return true
+	}
+
+	local, _, _ := strings.Cut(email, "@")
+
+	return utf8.RuneCountInString(local) >= 3 && strings.Contains(password, local)
+}
@@ -0,0 +1,89 @@
+package passwords
+
+import (
+	"go_pro/internal/models"
+	"go_pro/internal/validations"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestChecker(t *testing.T, policy models.PasswordPolicy) *Checker {
+	t.Helper()
+
+	checker, err := NewChecker(policy)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return checker
+}
+
+func TestCheckerValidate(t *testing.T) {
+	checker := newTestChecker(t, models.PasswordPolicy{MinLength: 8, MinEntropy: 36})
+
+	tests := []struct {
+		name     string
+		password string
+		email    string
+		want     string
+	}{
+		{"strong", "Tr3m-azul-Ventania", "joao@example.com", ""},
+		{"too short", "aB3$xY7", "joao@example.com", "no mínimo 8 caracteres"},
+		{"length counts characters, not bytes", "çãõéêáíó", "joao@example.com", ""},
+		{"multi-byte too short", "çãõéêáí", "joao@example.com", "no mínimo 8 caracteres"},
+		{"whole email", "x-Joao@Example.com-9", "joao@example.com", "não pode conter o seu email"},
+		{"part before the @", "Tr3m-joao-Ventania", "joao@example.com", "não pode conter o seu email"},
+		{"short part before the @ is allowed", "Tr3m-ab-Ventania", "ab@example.com", ""},
+		{"no email", "Tr3m-azul-Ventania", "", ""},
+		{"common", "password", "joao@example.com", "muito comum"},
+		{"common in other case and spacing", "  PassWord  ", "joao@example.com", "muito comum"},
+		{"common in portuguese", "senha123", "joao@example.com", "muito comum"},
+		{"repeated characters", "zzzzzzzzzzzz", "joao@example.com", "muito fraca"},
+		{"long sequence", "abcdefghijkl", "joao@example.com", "muito fraca"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var form validations.FormValidator
+
+			checker.Validate(&form, "password", tt.password, tt.email)
+
+			got := form.FieldErrors["password"]
+
+			if tt.want == "" && got != "" {
+				t.Fatalf("Validate(%q) refused the password: %q", tt.password, got)
+			}
+
+			if !strings.Contains(got, tt.want) {
+				t.Fatalf("Validate(%q) = %q, want an error containing %q", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckerBreachedList(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "breached.txt")
+
+	if err := os.WriteFile(list, []byte("Tr3m-azul-Ventania\n\n  OUTRA-senha-vazada  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	checker := newTestChecker(t, models.PasswordPolicy{MinLength: 8, MinEntropy: 36, BreachedList: list})
+
+	for _, password := range []string{"Tr3m-azul-Ventania", "outra-senha-vazada", "password"} {
+		var form validations.FormValidator
+
+		checker.Validate(&form, "password", password, "")
+
+		if !strings.Contains(form.FieldErrors["password"], "vazamentos") {
+			t.Errorf("Validate(%q) = %q, want it refused as breached", password, form.FieldErrors["password"])
+		}
+	}
+
+	if _, err := NewChecker(models.PasswordPolicy{BreachedList: filepath.Join(t.TempDir(), "missing.txt")}); err == nil {
+		t.Error("NewChecker accepted a breached list that does not exist")
+	}
+}
@@ -0,0 +1,85 @@
+package passwords
+
+import (
+	"math"
+	"unicode"
+)
+
+// Score thresholds, in bits of entropy. assets/javascript/password-strength.js
+// draws the meter of the forms with the same ones.
+var scoreThresholds = []float64{28, 36, 60, 80}
+
+var scoreLabels = []string{"Muito fraca", "Fraca", "Razoável", "Forte", "Muito forte"}
+
+// Strength is an estimate of how hard a password is to guess.
+type Strength struct {
+	Entropy float64
+	Score   int
+}
+
+func (s Strength) Label() string {
+	return scoreLabels[s.Score]
+}
+
+// Measure estimates the entropy of password as its length times the bits of
+// each character, given the kinds of characters it uses. Characters that
+// repeat the previous one or continue a run like "abc" or "321" count for a
+// quarter, as they add little to guess.
+func Measure(password string) Strength {
+	var lower, upper, digit, symbol, other bool
+	var length float64
+	var prev, prevStep rune
+	first := true
+
+	for _, c := range password {
+		switch {
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= '0' && c <= '9':
+			digit = true
+		case c < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+
+		step := c - prev
+
+		if !first && (step == 0 || ((step == 1 || step == -1) && step == prevStep)) {
+			length += 0.25
+		} else {
+			length++
+		}
+
+		first = false
+		prev, prevStep = c, step
+	}
+
+	pool := 0
+
+	for _, kind := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if kind.used {
+			pool += kind.size
+		}
+	}
+
+	if pool == 0 {
+		return Strength{}
+	}
+
+	entropy := length * math.Log2(float64(pool))
+	score := 0
+
+	for _, threshold := range scoreThresholds {
+		if entropy >= threshold {
+			score++
+		}
+	}
+
+	return Strength{Entropy: entropy, Score: score}
+}
@@ -0,0 +1,51 @@
+package passwords
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMeasure(t *testing.T) {
+	lower := math.Log2(26)
+	lowerDigit := math.Log2(36)
+	all := math.Log2(95)
+
+	tests := []struct {
+		name     string
+		password string
+		entropy  float64
+		score    int
+	}{
+		{"empty", "", 0, 0},
+		{"one kind", "senha", 5 * lower, 0},
+		{"repeated characters count for a quarter", "aaaa", 1.75 * lower, 0},
+		{"ascending run", "abcd", 2.5 * lower, 0},
+		{"descending run", "4321", 2.5 * math.Log2(10), 0},
+		{"broken run", "acbd", 4 * lower, 0},
+		{"longer with one kind", "abacate", 7 * lower, 1},
+		{"letters and digits", "banana42", 8 * lowerDigit, 2},
+		{"every kind", "Ab1!Cd2@", 8 * all, 2},
+		{"longer with every kind", "Ab1!Cd2@Ef", 10 * all, 3},
+		// the double r of correto counts for a quarter
+		{"long passphrase", "cavalo-correto-bateria", 21.25 * math.Log2(26+33), 4},
+		{"non ascii characters", "ção", 3 * math.Log2(26+100), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Measure(tt.password)
+
+			if math.Abs(got.Entropy-tt.entropy) > 1e-9 {
+				t.Errorf("Entropy = %v, want %v", got.Entropy, tt.entropy)
+			}
+
+			if got.Score != tt.score {
+				t.Errorf("Score = %d, want %d", got.Score, tt.score)
+			}
+
+			if got.Label() != scoreLabels[tt.score] {
+				t.Errorf("Label() = %q, want %q", got.Label(), scoreLabels[tt.score])
+			}
+		})
+	}
+}
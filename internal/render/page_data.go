@@ -0,0 +1,41 @@
+package render
+
+import (
+	"context"
+	"go_pro/internal/dtos"
+	"net/http"
+	"sync"
+)
+
+// PageData is what the layout shows of the signed in user around every page.
+// Any of it may be nil, and the layout then leaves that part out.
+type PageData struct {
+	Profile  *dtos.ProfileResponse
+	Quota    *dtos.QuotaUsageResponse
+	Switcher *dtos.WorkspaceSwitcherResponse
+}
+
+type pageDataKey struct{}
+
+// WithPageData attaches the loader of the page data to the request. It runs
+// once, when the first page of the request is rendered, and not at all for
+// requests that render none.
+func WithPageData(r *http.Request, load func() *PageData) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), pageDataKey{}, sync.OnceValue(load)))
+}
+
+// pageData returns the page data of the request. Error pages of the server
+// and of the rate limiter go without it, so they do not hit the database.
+func pageData(r *http.Request, status int) *PageData {
+	load, ok := r.Context().Value(pageDataKey{}).(func() *PageData)
+
+	if !ok || status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+		return &PageData{}
+	}
+
+	if page := load(); page != nil {
+		return page
+	}
+
+	return &PageData{}
+}
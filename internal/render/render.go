@@ -4,11 +4,16 @@ import (
 	"bytes"
 	"fmt"
 	"go_pro/internal/apperrors"
+	"go_pro/internal/dtos"
+	"go_pro/internal/i18n"
+	"go_pro/internal/models"
 	"go_pro/views"
 	"html/template"
 	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/gorilla/csrf"
@@ -16,10 +21,13 @@ import (
 
 type RenderTemplate struct {
 	session *scs.SessionManager
+	baseURL string
 }
 
-func NewRender(session *scs.SessionManager) *RenderTemplate {
-	return &RenderTemplate{session: session}
+// NewRender builds the renderer of pages and emails. The links of the emails
+// point to baseURL.
+func NewRender(session *scs.SessionManager, baseURL string) *RenderTemplate {
+	return &RenderTemplate{session: session, baseURL: baseURL}
 }
 
 func getTemplatePageFiles(t *template.Template, page string, useFS bool) (*template.Template, error) {
@@ -47,6 +55,17 @@ func getTemplateMailFiles(mailTmpl string, useFS bool) (*template.Template, erro
 }
 
 func (rt *RenderTemplate) RenderPage(w http.ResponseWriter, r *http.Request, page string, data interface{}, status int) error {
+	layout := pageData(r, status)
+	locale := i18n.Match(r.Header.Get("Accept-Language"))
+
+	if layout.Profile != nil {
+		locale = layout.Profile.Locale
+	}
+
+	location := sync.OnceValue(func() *time.Location {
+		return rt.location(layout.Profile)
+	})
+
 	t := template.New("").Funcs(template.FuncMap{
 		"csrfField": func() template.HTML {
 			return csrf.TemplateField(r)
@@ -57,9 +76,30 @@ func (rt *RenderTemplate) RenderPage(w http.ResponseWriter, r *http.Request, pag
 		"isAuthenticated": func() bool {
 			return rt.session.Exists(r.Context(), "userId")
 		},
+		"isAdmin": func() bool {
+			return rt.session.GetString(r.Context(), "userRole") == models.RoleAdmin
+		},
 		"userEmail": func() string {
 			return rt.session.GetString(r.Context(), "userEmail")
 		},
+		"quotaUsage": func() *dtos.QuotaUsageResponse {
+			return layout.Quota
+		},
+		"workspaceSwitcher": func() *dtos.WorkspaceSwitcherResponse {
+			return layout.Switcher
+		},
+		"profile": func() *dtos.ProfileResponse {
+			return layout.Profile
+		},
+		"locale": func() string {
+			return locale
+		},
+		"t": func(msg string) string {
+			return i18n.T(locale, msg)
+		},
+		"localTime": func(t time.Time) string {
+			return t.In(location()).Format("02/01/2006 15:04")
+		},
 	})
 
 	useFS := !strings.Contains(r.Host, "localhost")
@@ -82,9 +122,29 @@ func (rt *RenderTemplate) RenderPage(w http.ResponseWriter, r *http.Request, pag
 	return nil
 }
 
+// location is the timezone timestamps are shown in. Timestamps are stored in
+// UTC, without a zone: the database sessions run in UTC and the repositories
+// write time.Now().UTC().
+func (rt *RenderTemplate) location(profile *dtos.ProfileResponse) *time.Location {
+	timezone := models.DefaultTimezone
+
+	if profile != nil {
+		timezone = profile.Timezone
+	}
+
+	location, err := time.LoadLocation(timezone)
+
+	if err != nil {
+		slog.Error(err.Error())
+		return time.UTC
+	}
+
+	return location
+}
+
 func (rt *RenderTemplate) RenderMailBody(r *http.Request, mailTemplate string, data map[string]string) ([]byte, error) {
 	useFS := !strings.Contains(r.Host, "localhost")
-	data["hostAddr"] = "http://" + r.Host
+	data["hostAddr"] = rt.baseURL
 	t, err := getTemplateMailFiles(mailTemplate, useFS)
 
 	if err != nil {
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/database/querys"
+	"go_pro/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrAccessTokenNotFound = apperrors.NewRepositoryError(errors.New("access token not found"))
+
+type AccessTokenRepository interface {
+	List(ctx context.Context, userId int) ([]models.AccessToken, error)
+	Create(ctx context.Context, userId int, name, tokenHash, scope string, expiresInDays int) error
+	Delete(ctx context.Context, userId int, id int) error
+	Use(ctx context.Context, tokenHash string) (int, string, error)
+}
+
+type accessTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAccessTokenRepository(db *pgxpool.Pool) AccessTokenRepository {
+	return &accessTokenRepository{
+		db: db,
+	}
+}
+
+func (ar *accessTokenRepository) List(ctx context.Context, userId int) ([]models.AccessToken, error) {
+	var list []models.AccessToken
+
+	rows, err := ar.db.Query(ctx, querys.ListAccessTokensQuery, userId)
+
+	if err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.AccessToken
+
+		if err = rows.Scan(&row.Id, &row.Name, &row.Scope, &row.ExpiresAt, &row.LastUsedAt, &row.CreatedAt); err != nil {
+			return nil, apperrors.NewRepositoryError(err)
+		}
+
+		list = append(list, row)
+	}
+
+	return list, nil
+}
+
+// Create saves a token that expires in expiresInDays, or never when it is zero.
+func (ar *accessTokenRepository) Create(ctx context.Context, userId int, name, tokenHash, scope string, expiresInDays int) error {
+	if _, err := ar.db.Exec(ctx, querys.CreateAccessTokenQuery, userId, name, tokenHash, scope, expiresInDays); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+func (ar *accessTokenRepository) Delete(ctx context.Context, userId int, id int) error {
+	tag, err := ar.db.Exec(ctx, querys.DeleteAccessTokenQuery, id, userId)
+
+	if err != nil {
+		return fail(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrAccessTokenNotFound
+	}
+
+	return nil
+}
+
+// Use returns the user and the scope of a valid token, recording that it was
+// used.
+func (ar *accessTokenRepository) Use(ctx context.Context, tokenHash string) (int, string, error) {
+	var userId int
+	var scope string
+
+	row := ar.db.QueryRow(ctx, querys.UseAccessTokenQuery, tokenHash)
+
+	if err := row.Scan(&userId, &scope); err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, "", ErrAccessTokenNotFound
+		}
+		return 0, "", apperrors.NewRepositoryError(err)
+	}
+
+	return userId, scope, nil
+}
+
+// deleteAccessTokens revokes every personal access token of the user, so a
+// token that leaked does not outlive a new password.
+func deleteAccessTokens(ctx context.Context, tx pgx.Tx, userId any) error {
+	_, err := tx.Exec(ctx, querys.DeleteUserAccessTokensQuery, userId)
+
+	return err
+}
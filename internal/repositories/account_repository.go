@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/database/querys"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrAccountExportNotFound = apperrors.NewRepositoryError(errors.New("account export not found"))
+
+type AccountRepository interface {
+	RequestDeletion(ctx context.Context, userId int, exportTokenHash string, grace time.Duration) error
+	CancelDeletion(ctx context.Context, userId int) error
+	GetExport(ctx context.Context, tokenHash string) ([]byte, error)
+	PurgeDeleted(ctx context.Context, grace time.Duration) (int64, error)
+}
+
+type accountRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAccountRepository(db *pgxpool.Pool) AccountRepository {
+	return &accountRepository{
+		db: db,
+	}
+}
+
+// RequestDeletion saves an export of every data of the user, available for
+// the grace period, marks the account for deletion and ends all its sessions
+// and access tokens.
+// An owner of a workspace has to delete it first.
+func (ar *accountRepository) RequestDeletion(ctx context.Context, userId int, exportTokenHash string, grace time.Duration) error {
+	tx, err := ar.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := checkNoOwnedWorkspace(ctx, tx, userId); err != nil {
+		if err == ErrOwnsWorkspace {
+			return err
+		}
+		return fail(err)
+	}
+
+	var data []byte
+
+	if err := tx.QueryRow(ctx, querys.BuildAccountExportQuery, userId).Scan(&data); err != nil {
+		return fail(err)
+	}
+
+	if _, err := tx.Exec(ctx, querys.CreateAccountExportQuery, userId, exportTokenHash, data, grace.Seconds()); err != nil {
+		return fail(err)
+	}
+
+	if _, err := tx.Exec(ctx, querys.RequestAccountDeletionQuery, userId); err != nil {
+		return fail(err)
+	}
+
+	if err := deleteOtherSessions(ctx, tx, userId, ""); err != nil {
+		return fail(err)
+	}
+
+	if err := deleteAccessTokens(ctx, tx, userId); err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+func (ar *accountRepository) CancelDeletion(ctx context.Context, userId int) error {
+	if _, err := ar.db.Exec(ctx, querys.CancelAccountDeletionQuery, userId); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+func (ar *accountRepository) GetExport(ctx context.Context, tokenHash string) ([]byte, error) {
+	var data []byte
+
+	if err := ar.db.QueryRow(ctx, querys.GetAccountExportQuery, tokenHash).Scan(&data); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrAccountExportNotFound
+		}
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	return data, nil
+}
+
+// PurgeDeleted removes the accounts whose grace period is over, together with
+// the expired exports. The data of the accounts goes with them through the
+// on delete cascade constraints. Owners of a workspace are kept.
+func (ar *accountRepository) PurgeDeleted(ctx context.Context, grace time.Duration) (int64, error) {
+	tag, err := ar.db.Exec(ctx, querys.PurgeDeletedAccountsQuery, grace.Seconds())
+
+	if err != nil {
+		return 0, fail(err)
+	}
+
+	if _, err := ar.db.Exec(ctx, querys.DeleteExpiredAccountExportsQuery); err != nil {
+		return 0, fail(err)
+	}
+
+	return tag.RowsAffected(), nil
+}
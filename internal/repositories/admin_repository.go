@@ -0,0 +1,184 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/database/querys"
+	"go_pro/internal/models"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrAdminUserNotFound = apperrors.NewRepositoryError(errors.New("user not found"))
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// AdminRepository backs the admin panel. Every change it makes to a user is
+// recorded in the audit log, in the same transaction.
+type AdminRepository interface {
+	ListUsers(ctx context.Context, search string, limit, offset int) ([]models.AdminUser, error)
+	SetDisabled(ctx context.Context, adminId, userId int, disabled bool) error
+	Confirm(ctx context.Context, adminId, userId int) error
+	Delete(ctx context.Context, adminId, userId int) error
+	Audit(ctx context.Context, adminId, userId int, action string) error
+	ListAudit(ctx context.Context, limit, offset int) ([]models.AdminAuditEntry, error)
+}
+
+type adminRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAdminRepository(db *pgxpool.Pool) AdminRepository {
+	return &adminRepository{
+		db: db,
+	}
+}
+
+// ListUsers returns the users whose email contains search, newest first.
+func (ar *adminRepository) ListUsers(ctx context.Context, search string, limit, offset int) ([]models.AdminUser, error) {
+	var list []models.AdminUser
+
+	rows, err := ar.db.Query(ctx, querys.ListAdminUsersQuery, likeEscaper.Replace(search), limit, offset)
+
+	if err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.AdminUser
+
+		if err = rows.Scan(&row.Id, &row.Email, &row.Role, &row.Active, &row.DisabledAt, &row.LastLoginAt, &row.CreatedAt, &row.Notes); err != nil {
+			return nil, apperrors.NewRepositoryError(err)
+		}
+
+		list = append(list, row)
+	}
+
+	return list, nil
+}
+
+// SetDisabled deactivates or reactivates the user. Deactivating also ends all
+// of their sessions.
+func (ar *adminRepository) SetDisabled(ctx context.Context, adminId, userId int, disabled bool) error {
+	action := models.AdminActionEnable
+
+	if disabled {
+		action = models.AdminActionDisable
+	}
+
+	return ar.withAudit(ctx, adminId, userId, action, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, querys.UpdateUserDisabledQuery, userId, disabled); err != nil {
+			return err
+		}
+
+		if disabled {
+			return deleteOtherSessions(ctx, tx, userId, "")
+		}
+
+		return nil
+	})
+}
+
+// Confirm activates a user that did not confirm their registration, voiding
+// the confirmation links sent to them.
+func (ar *adminRepository) Confirm(ctx context.Context, adminId, userId int) error {
+	return ar.withAudit(ctx, adminId, userId, models.AdminActionConfirm, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, querys.AdminConfirmUserQuery, userId); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, querys.DeleteUnusedUserTokensQuery, userId, models.TokenPurposeConfirmation)
+		return err
+	})
+}
+
+// Delete removes the user and everything they own right away, without the
+// grace period of a deletion requested by the user. An owner of a workspace
+// can not be deleted.
+func (ar *adminRepository) Delete(ctx context.Context, adminId, userId int) error {
+	return ar.withAudit(ctx, adminId, userId, models.AdminActionDelete, func(tx pgx.Tx) error {
+		if err := checkNoOwnedWorkspace(ctx, tx, userId); err != nil {
+			return err
+		}
+
+		if err := deleteOtherSessions(ctx, tx, userId, ""); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, querys.AdminDeleteUserQuery, userId)
+		return err
+	})
+}
+
+// Audit records an action taken outside of this repository, like sending a
+// password reset email.
+func (ar *adminRepository) Audit(ctx context.Context, adminId, userId int, action string) error {
+	return ar.withAudit(ctx, adminId, userId, action, func(tx pgx.Tx) error {
+		return nil
+	})
+}
+
+func (ar *adminRepository) ListAudit(ctx context.Context, limit, offset int) ([]models.AdminAuditEntry, error) {
+	var list []models.AdminAuditEntry
+
+	rows, err := ar.db.Query(ctx, querys.ListAdminAuditQuery, limit, offset)
+
+	if err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.AdminAuditEntry
+
+		if err = rows.Scan(&row.Id, &row.AdminEmail, &row.TargetEmail, &row.Action, &row.CreatedAt); err != nil {
+			return nil, apperrors.NewRepositoryError(err)
+		}
+
+		list = append(list, row)
+	}
+
+	return list, nil
+}
+
+// withAudit runs change in a transaction after recording the action. The
+// audit entry is written first, while the target still exists, and doubles
+// as the check that it does.
+func (ar *adminRepository) withAudit(ctx context.Context, adminId, userId int, action string, change func(tx pgx.Tx) error) error {
+	tx, err := ar.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, querys.CreateAdminAuditQuery, adminId, userId, action)
+
+	if err != nil {
+		return fail(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrAdminUserNotFound
+	}
+
+	if err := change(tx); err != nil {
+		if err == ErrOwnsWorkspace {
+			return err
+		}
+		return fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/database/querys"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrCalendarTokenNotFound = apperrors.NewRepositoryError(errors.New("calendar token not found"))
+
+// CalendarRepository stores the token of the calendar feed of each user. Only
+// its hash is kept, the address of the feed is shown once, when generated.
+type CalendarRepository interface {
+	HasToken(ctx context.Context, userId int) (bool, error)
+	RegenerateToken(ctx context.Context, userId int, tokenHash string) error
+	RevokeToken(ctx context.Context, userId int) error
+	FindUserIdByToken(ctx context.Context, tokenHash string) (int, error)
+}
+
+type calendarRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCalendarRepository(db *pgxpool.Pool) CalendarRepository {
+	return &calendarRepository{
+		db: db,
+	}
+}
+
+func (cr *calendarRepository) HasToken(ctx context.Context, userId int) (bool, error) {
+	var exists bool
+
+	if err := cr.db.QueryRow(ctx, querys.HasCalendarTokenQuery, userId).Scan(&exists); err != nil {
+		return false, apperrors.NewRepositoryError(err)
+	}
+
+	return exists, nil
+}
+
+func (cr *calendarRepository) RegenerateToken(ctx context.Context, userId int, tokenHash string) error {
+	if _, err := cr.db.Exec(ctx, querys.UpsertCalendarTokenQuery, userId, tokenHash); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+func (cr *calendarRepository) RevokeToken(ctx context.Context, userId int) error {
+	if _, err := cr.db.Exec(ctx, querys.DeleteCalendarTokenQuery, userId); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+func (cr *calendarRepository) FindUserIdByToken(ctx context.Context, tokenHash string) (int, error) {
+	var userId pgtype.Int8
+
+	row := cr.db.QueryRow(ctx, querys.GetUserIdByCalendarTokenQuery, tokenHash)
+
+	if err := row.Scan(&userId); err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrCalendarTokenNotFound
+		}
+		return 0, apperrors.NewRepositoryError(err)
+	}
+
+	return int(userId.Int64), nil
+}
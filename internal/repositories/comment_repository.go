@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/database/querys"
+	"go_pro/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrCommentNotFound = apperrors.NewRepositoryError(errors.New("comment not found"))
+var ErrNoteOwnerNotFound = apperrors.NewRepositoryError(errors.New("note owner not found"))
+
+type CommentRepository interface {
+	ListByNote(ctx context.Context, noteId int) ([]models.Comment, error)
+	GetById(ctx context.Context, noteId, id int) (*models.Comment, error)
+	Create(ctx context.Context, noteId, userId int, body string) (*models.Comment, error)
+	Update(ctx context.Context, noteId, userId, id int, body string) error
+	Delete(ctx context.Context, id int) error
+	GetNoteOwner(ctx context.Context, noteId int) (*models.User, error)
+}
+
+type commentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCommentRepository(db *pgxpool.Pool) CommentRepository {
+	return &commentRepository{
+		db: db,
+	}
+}
+
+func (cr *commentRepository) ListByNote(ctx context.Context, noteId int) ([]models.Comment, error) {
+	var list []models.Comment
+
+	rows, err := cr.db.Query(ctx, querys.ListCommentsByNoteQuery, noteId)
+
+	if err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.Comment
+
+		if err = rows.Scan(&row.Id, &row.NoteId, &row.UserId, &row.AuthorEmail, &row.AuthorName,
+			&row.Body, &row.CreatedAt, &row.UpdatedAt); err != nil {
+			return nil, apperrors.NewRepositoryError(err)
+		}
+
+		list = append(list, row)
+	}
+
+	return list, nil
+}
+
+func (cr *commentRepository) GetById(ctx context.Context, noteId, id int) (*models.Comment, error) {
+	var comment models.Comment
+
+	row := cr.db.QueryRow(ctx, querys.GetCommentByIdQuery, id, noteId)
+
+	if err := row.Scan(&comment.Id, &comment.NoteId, &comment.UserId, &comment.AuthorEmail, &comment.AuthorName,
+		&comment.Body, &comment.CreatedAt, &comment.UpdatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrCommentNotFound
+		}
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	return &comment, nil
+}
+
+func (cr *commentRepository) Create(ctx context.Context, noteId, userId int, body string) (*models.Comment, error) {
+	var comment models.Comment
+
+	comment.Body = pgtype.Text{String: body, Valid: true}
+
+	row := cr.db.QueryRow(ctx, querys.CreateCommentQuery, noteId, userId, comment.Body)
+
+	if err := row.Scan(&comment.Id, &comment.CreatedAt); err != nil {
+		return nil, fail(err)
+	}
+
+	return &comment, nil
+}
+
+func (cr *commentRepository) Update(ctx context.Context, noteId, userId, id int, body string) error {
+	tag, err := cr.db.Exec(ctx, querys.UpdateCommentQuery, body, id, userId, noteId)
+
+	if err != nil {
+		return fail(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrCommentNotFound
+	}
+
+	return nil
+}
+
+func (cr *commentRepository) Delete(ctx context.Context, id int) error {
+	if _, err := cr.db.Exec(ctx, querys.DeleteCommentQuery, id); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// GetNoteOwner returns the owner of a personal note, or the author of a
+// workspace note while they are still a member of the workspace. A workspace
+// note whose author left or was deleted has no owner.
+func (cr *commentRepository) GetNoteOwner(ctx context.Context, noteId int) (*models.User, error) {
+	var user models.User
+
+	row := cr.db.QueryRow(ctx, querys.GetNoteOwnerQuery, noteId)
+
+	if err := row.Scan(&user.Id, &user.Email); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNoteOwnerNotFound
+		}
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	return &user, nil
+}
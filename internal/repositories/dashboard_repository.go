@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"context"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/database/querys"
+	"go_pro/internal/models"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	dashboardWeeks     = 12
+	dashboardListLimit = 5
+	dashboardStaleDays = 90
+)
+
+type DashboardRepository interface {
+	Stats(ctx context.Context, scope models.NoteScope) (*models.NoteDashboard, error)
+}
+
+type dashboardRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDashboardRepository(db *pgxpool.Pool) DashboardRepository {
+	return &dashboardRepository{
+		db: db,
+	}
+}
+
+func (dr *dashboardRepository) Stats(ctx context.Context, scope models.NoteScope) (*models.NoteDashboard, error) {
+	var dashboard models.NoteDashboard
+
+	row := dr.db.QueryRow(ctx, querys.CountNotesQuery, scope.UserId, scope.WorkspaceId)
+
+	if err := row.Scan(&dashboard.Total); err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	rows, err := dr.db.Query(ctx, querys.CountNotesByColorQuery, scope.UserId, scope.WorkspaceId)
+
+	if err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	for rows.Next() {
+		var stat models.NoteColorStat
+
+		if err = rows.Scan(&stat.Color, &stat.Total); err != nil {
+			rows.Close()
+			return nil, apperrors.NewRepositoryError(err)
+		}
+
+		dashboard.ByColor = append(dashboard.ByColor, stat)
+	}
+
+	rows.Close()
+
+	rows, err = dr.db.Query(ctx, querys.CountNotesPerWeekQuery, scope.UserId, scope.WorkspaceId, dashboardWeeks)
+
+	if err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	for rows.Next() {
+		var stat models.NoteWeekStat
+
+		if err = rows.Scan(&stat.Week, &stat.Total); err != nil {
+			rows.Close()
+			return nil, apperrors.NewRepositoryError(err)
+		}
+
+		dashboard.PerWeek = append(dashboard.PerWeek, stat)
+	}
+
+	rows.Close()
+
+	rows, err = dr.db.Query(ctx, querys.LongestNotesQuery, scope.UserId, scope.WorkspaceId, dashboardListLimit)
+
+	if err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	for rows.Next() {
+		var stat models.NoteLengthStat
+
+		if err = rows.Scan(&stat.Id, &stat.Title, &stat.Length); err != nil {
+			rows.Close()
+			return nil, apperrors.NewRepositoryError(err)
+		}
+
+		dashboard.Longest = append(dashboard.Longest, stat)
+	}
+
+	rows.Close()
+
+	rows, err = dr.db.Query(ctx, querys.StaleNotesQuery, scope.UserId, scope.WorkspaceId, dashboardStaleDays, dashboardListLimit)
+
+	if err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var stat models.NoteStaleStat
+
+		if err = rows.Scan(&stat.Id, &stat.Title, &stat.LastTouched); err != nil {
+			return nil, apperrors.NewRepositoryError(err)
+		}
+
+		dashboard.Stale = append(dashboard.Stale, stat)
+	}
+
+	return &dashboard, nil
+}
+
+type dashboardCacheEntry struct {
+	dashboard *models.NoteDashboard
+	expiresAt time.Time
+}
+
+// cachedDashboardRepository keeps the aggregates of each scope in memory for a
+// short time, so reloading the dashboard does not run every query again.
+type cachedDashboardRepository struct {
+	next    DashboardRepository
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[models.NoteScope]dashboardCacheEntry
+}
+
+func NewCachedDashboardRepository(next DashboardRepository, ttl time.Duration) DashboardRepository {
+	return &cachedDashboardRepository{
+		next:    next,
+		ttl:     ttl,
+		entries: make(map[models.NoteScope]dashboardCacheEntry),
+	}
+}
+
+func (cr *cachedDashboardRepository) Stats(ctx context.Context, scope models.NoteScope) (*models.NoteDashboard, error) {
+	now := time.Now()
+
+	cr.mu.Lock()
+	entry, ok := cr.entries[scope]
+	cr.mu.Unlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		return entry.dashboard, nil
+	}
+
+	dashboard, err := cr.next.Stats(ctx, scope)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	for id, e := range cr.entries {
+		if now.After(e.expiresAt) {
+			delete(cr.entries, id)
+		}
+	}
+
+	cr.entries[scope] = dashboardCacheEntry{dashboard: dashboard, expiresAt: now.Add(cr.ttl)}
+
+	return dashboard, nil
+}
@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/database/querys"
+	"go_pro/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrIdentityEmailNotVerified = apperrors.NewRepositoryError(errors.New("identity email not verified"))
+
+// unusablePassword is stored for users created or confirmed through an
+// identity provider. It is not a valid password hash, so no password ever
+// matches it.
+const unusablePassword = "!external"
+
+// HasPassword tells whether the user has a password of their own, which users
+// created through an identity provider do not.
+func HasPassword(user *models.User) bool {
+	return user.Password.String != unusablePassword
+}
+
+type IdentityRepository interface {
+	SignIn(ctx context.Context, issuer, subject, email string, emailVerified bool) (*models.User, error)
+	IsLinked(ctx context.Context, userId int64, issuer, subject string) (bool, error)
+}
+
+type identityRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewIdentityRepository(db *pgxpool.Pool) IdentityRepository {
+	return &identityRepository{
+		db: db,
+	}
+}
+
+// SignIn returns the user of an external identity. An identity seen for the
+// first time is linked to the user with the same email, which the provider
+// must have verified, or to a new user when there is none.
+func (ir *identityRepository) SignIn(ctx context.Context, issuer, subject, email string, emailVerified bool) (*models.User, error) {
+	var user models.User
+
+	tx, err := ir.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	row := tx.QueryRow(ctx, querys.FindUserByIdentityQuery, issuer, subject)
+
+	err = row.Scan(&user.Id, &user.Email, &user.Password, &user.Active, &user.DeletionRequestedAt,
+		&user.TOTPSecret, &user.TOTPEnabled, &user.Role, &user.DisabledAt)
+
+	if err == nil {
+		if _, err := tx.Exec(ctx, querys.UpdateIdentityEmailQuery, email, issuer, subject); err != nil {
+			return nil, fail(err)
+		}
+
+		if err = tx.Commit(ctx); err != nil {
+			return nil, fail(err)
+		}
+
+		return &user, nil
+	}
+
+	if err != pgx.ErrNoRows {
+		return nil, fail(err)
+	}
+
+	if !emailVerified || email == "" {
+		return nil, ErrIdentityEmailNotVerified
+	}
+
+	row = tx.QueryRow(ctx, querys.FindUserByEmailForUpdateQuery, email)
+
+	err = row.Scan(&user.Id, &user.Email, &user.Password, &user.Active, &user.DeletionRequestedAt,
+		&user.TOTPSecret, &user.TOTPEnabled, &user.Role, &user.DisabledAt)
+
+	switch {
+	case err == pgx.ErrNoRows:
+		user.Email = pgtype.Text{String: email, Valid: true}
+		user.Active = pgtype.Bool{Bool: true, Valid: true}
+
+		if err := tx.QueryRow(ctx, querys.CreateActiveUserQuery, email, unusablePassword).Scan(&user.Id); err != nil {
+			return nil, fail(err)
+		}
+	case err != nil:
+		return nil, fail(err)
+	case !user.Active.Bool:
+		// The provider verified the email, which confirms a pending signup too.
+		// Whoever signed up may not own the email, so their password and the
+		// links sent to it stop working.
+		if _, err := tx.Exec(ctx, querys.ActivateUserQuery, user.Id, unusablePassword); err != nil {
+			return nil, fail(err)
+		}
+
+		if _, err := tx.Exec(ctx, querys.DeleteAllUnusedUserTokensQuery, user.Id); err != nil {
+			return nil, fail(err)
+		}
+
+		user.Password = pgtype.Text{String: unusablePassword, Valid: true}
+		user.Active = pgtype.Bool{Bool: true, Valid: true}
+	}
+
+	if _, err := tx.Exec(ctx, querys.CreateIdentityQuery, user.Id, issuer, subject, email); err != nil {
+		return nil, fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fail(err)
+	}
+
+	return &user, nil
+}
+
+// IsLinked tells whether the external identity belongs to the user.
+func (ir *identityRepository) IsLinked(ctx context.Context, userId int64, issuer, subject string) (bool, error) {
+	var linked bool
+
+	if err := ir.db.QueryRow(ctx, querys.IsIdentityLinkedQuery, userId, issuer, subject).Scan(&linked); err != nil {
+		return false, fail(err)
+	}
+
+	return linked, nil
+}
@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/database/querys"
+	"go_pro/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrUnlockTokenNotFound = apperrors.NewRepositoryError(errors.New("unlock token not found"))
+
+type LoginSecurityRepository interface {
+	GetLockout(ctx context.Context, userId int) (*models.Lockout, error)
+	RecordFailure(ctx context.Context, userId int, ip, userAgent string, policy models.LockoutPolicy, unlockTokenHash string) (time.Duration, error)
+	ResetLockout(ctx context.Context, userId int) error
+	Unlock(ctx context.Context, unlockTokenHash string) error
+	ListFailedLogins(ctx context.Context, userId int, limit int) ([]models.FailedLogin, error)
+	DeleteOldFailedLogins(ctx context.Context, age time.Duration) error
+	RegisterDevice(ctx context.Context, userId int, deviceHash, ip, userAgent string) (bool, error)
+	ListDevices(ctx context.Context, userId int, currentDeviceHash string) ([]models.Device, error)
+}
+
+type loginSecurityRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginSecurityRepository(db *pgxpool.Pool) LoginSecurityRepository {
+	return &loginSecurityRepository{
+		db: db,
+	}
+}
+
+// GetLockout returns the failed attempts of the user and until when they
+// are locked out. Users who never failed a password check have no lockout.
+func (lr *loginSecurityRepository) GetLockout(ctx context.Context, userId int) (*models.Lockout, error) {
+	var lockout models.Lockout
+	var remaining float64
+
+	row := lr.db.QueryRow(ctx, querys.GetLockoutQuery, userId)
+
+	if err := row.Scan(&lockout.FailedAttempts, &lockout.LockedUntil, &remaining); err != nil {
+		if err == pgx.ErrNoRows {
+			return &lockout, nil
+		}
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	lockout.Remaining = time.Duration(remaining * float64(time.Second))
+
+	return &lockout, nil
+}
+
+// RecordFailure counts a failed password check. When it reaches the
+// threshold of the policy the account is locked, the unlock token replaces
+// any previous one and the lock duration is returned; it is zero otherwise.
+// The unlock token is valid for the longest lock of the policy.
+func (lr *loginSecurityRepository) RecordFailure(ctx context.Context, userId int, ip, userAgent string, policy models.LockoutPolicy, unlockTokenHash string) (time.Duration, error) {
+	tx, err := lr.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return 0, fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, querys.CreateFailedLoginQuery, userId, ip, userAgent); err != nil {
+		return 0, fail(err)
+	}
+
+	var attempts int
+
+	// the upsert locks the row, so concurrent failures are counted in turn
+	if err := tx.QueryRow(ctx, querys.IncrementFailedAttemptsQuery, userId).Scan(&attempts); err != nil {
+		return 0, fail(err)
+	}
+
+	var lockedFor float64
+
+	if attempts >= policy.Threshold {
+		row := tx.QueryRow(ctx, querys.LockUserQuery, userId, policy.MaxDuration.Seconds(), policy.Duration.Seconds(), unlockTokenHash)
+
+		if err := row.Scan(&lockedFor); err != nil {
+			return 0, fail(err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fail(err)
+	}
+
+	return time.Duration(lockedFor * float64(time.Second)), nil
+}
+
+// ResetLockout forgets the failed attempts and previous lockouts of a user
+// who signed in.
+func (lr *loginSecurityRepository) ResetLockout(ctx context.Context, userId int) error {
+	if _, err := lr.db.Exec(ctx, querys.ResetLockoutQuery, userId); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+func (lr *loginSecurityRepository) Unlock(ctx context.Context, unlockTokenHash string) error {
+	tag, err := lr.db.Exec(ctx, querys.UnlockUserByTokenQuery, unlockTokenHash)
+
+	if err != nil {
+		return fail(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrUnlockTokenNotFound
+	}
+
+	return nil
+}
+
+func (lr *loginSecurityRepository) ListFailedLogins(ctx context.Context, userId int, limit int) ([]models.FailedLogin, error) {
+	var list []models.FailedLogin
+
+	rows, err := lr.db.Query(ctx, querys.ListFailedLoginsQuery, userId, limit)
+
+	if err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.FailedLogin
+
+		if err = rows.Scan(&row.Id, &row.IP, &row.UserAgent, &row.CreatedAt); err != nil {
+			return nil, apperrors.NewRepositoryError(err)
+		}
+
+		list = append(list, row)
+	}
+
+	return list, nil
+}
+
+func (lr *loginSecurityRepository) DeleteOldFailedLogins(ctx context.Context, age time.Duration) error {
+	if _, err := lr.db.Exec(ctx, querys.DeleteOldFailedLoginsQuery, age.Seconds()); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// RegisterDevice records that the user signed in from a device and tells if
+// it is a new one. The first device of a user is not new, there is nothing
+// to compare it to.
+func (lr *loginSecurityRepository) RegisterDevice(ctx context.Context, userId int, deviceHash, ip, userAgent string) (bool, error) {
+	tag, err := lr.db.Exec(ctx, querys.TouchDeviceQuery, userId, deviceHash, ip, userAgent)
+
+	if err != nil {
+		return false, fail(err)
+	}
+
+	if tag.RowsAffected() > 0 {
+		return false, nil
+	}
+
+	var hasDevices bool
+
+	if err := lr.db.QueryRow(ctx, querys.HasDevicesQuery, userId).Scan(&hasDevices); err != nil {
+		return false, fail(err)
+	}
+
+	if _, err := lr.db.Exec(ctx, querys.CreateDeviceQuery, userId, deviceHash, ip, userAgent); err != nil {
+		return false, fail(err)
+	}
+
+	return hasDevices, nil
+}
+
+func (lr *loginSecurityRepository) ListDevices(ctx context.Context, userId int, currentDeviceHash string) ([]models.Device, error) {
+	var list []models.Device
+
+	rows, err := lr.db.Query(ctx, querys.ListDevicesQuery, userId, currentDeviceHash)
+
+	if err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.Device
+
+		if err = rows.Scan(&row.Id, &row.IP, &row.UserAgent, &row.Current, &row.CreatedAt, &row.LastSeenAt); err != nil {
+			return nil, apperrors.NewRepositoryError(err)
+		}
+
+		list = append(list, row)
+	}
+
+	return list, nil
+}
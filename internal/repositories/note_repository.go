@@ -2,32 +2,63 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"go_pro/internal/apperrors"
 	"go_pro/internal/database/querys"
 	"go_pro/internal/models"
+	"go_pro/tools"
 	"math/big"
+	"slices"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var ErrNoteNotFound = apperrors.NewRepositoryError(errors.New("note not found"))
+
+// Every query of NoteRepository is limited to a scope, the personal notes of
+// a user or the notes of the workspace they are working in.
 type NoteRepository interface {
-	List(ctx context.Context, userId int) ([]models.Note, error)
-	GetById(ctx context.Context, userId int, id int) (*models.Note, error)
-	Create(ctx context.Context, userId int, title, content, color string) (*models.Note, error)
-	Update(ctx context.Context, userId int, id int, title, content, color string) (*models.Note, error)
-	Delete(ctx context.Context, userId int, id int) error
+	List(ctx context.Context, scope models.NoteScope, sort string) ([]models.Note, error)
+	ListWithDueDate(ctx context.Context, scope models.NoteScope) ([]models.Note, error)
+	GetById(ctx context.Context, scope models.NoteScope, id int) (*models.Note, error)
+	GetAccess(ctx context.Context, scope models.NoteScope, id int) (*models.NoteAccess, error)
+	Create(ctx context.Context, scope models.NoteScope, title, content, color string, dueAt *time.Time) (*models.Note, error)
+	CreateForUsers(ctx context.Context, userIds []int, title, content, color string) error
+	Update(ctx context.Context, scope models.NoteScope, id int, title, content, color string, dueAt *time.Time) (*models.Note, error)
+	Delete(ctx context.Context, scope models.NoteScope, id int) error
+	GetLock(ctx context.Context, scope models.NoteScope, id int) (*models.NoteLock, error)
+	Lock(ctx context.Context, scope models.NoteScope, id int, password string, salt []byte, content string) error
+	RemoveLock(ctx context.Context, scope models.NoteScope, id int, content string) error
+	Move(ctx context.Context, scope models.NoteScope, id int, afterId int, beforeId int) error
 }
 
 type noteRepository struct {
-	db *pgxpool.Pool
+	db    *pgxpool.Pool
+	quota models.Quota
+}
+
+func (nr *noteRepository) List(ctx context.Context, scope models.NoteScope, sort string) ([]models.Note, error) {
+	orderBy, ok := querys.ListNoteOrderBy[sort]
+
+	if !ok {
+		orderBy = querys.ListNoteOrderBy[models.NoteSortCreated]
+	}
+
+	return nr.list(ctx, fmt.Sprintf(querys.ListNoteQuery, orderBy), scope.UserId, scope.WorkspaceId)
+}
+
+func (nr *noteRepository) ListWithDueDate(ctx context.Context, scope models.NoteScope) ([]models.Note, error) {
+	return nr.list(ctx, querys.ListNoteWithDueDateQuery, scope.UserId, scope.WorkspaceId)
 }
 
-func (nr *noteRepository) List(ctx context.Context, userId int) ([]models.Note, error) {
+func (nr *noteRepository) list(ctx context.Context, query string, args ...any) ([]models.Note, error) {
 	var list []models.Note
 
-	rows, err := nr.db.Query(ctx, querys.ListNoteQuery, userId)
+	rows, err := nr.db.Query(ctx, query, args...)
 
 	if err != nil {
 		return nil, apperrors.NewRepositoryError(err)
@@ -40,7 +71,7 @@ func (nr *noteRepository) List(ctx context.Context, userId int) ([]models.Note,
 
 		if err = rows.Scan(
 			&row.Id, &row.Title,
-			&row.Content, &row.Color,
+			&row.Content, &row.Color, &row.DueAt, &row.Locked,
 			&row.CreatedAt, &row.UpdatedAt); err != nil {
 			return nil, apperrors.NewRepositoryError(err)
 		}
@@ -51,13 +82,13 @@ func (nr *noteRepository) List(ctx context.Context, userId int) ([]models.Note,
 	return list, nil
 }
 
-func (nr *noteRepository) GetById(ctx context.Context, userId int, id int) (*models.Note, error) {
+func (nr *noteRepository) GetById(ctx context.Context, scope models.NoteScope, id int) (*models.Note, error) {
 	var note models.Note
 
-	row := nr.db.QueryRow(ctx, querys.GetByIdNoteQuery, id, userId)
+	row := nr.db.QueryRow(ctx, querys.GetByIdNoteQuery, scope.UserId, scope.WorkspaceId, id)
 
 	if err := row.Scan(&note.Id, &note.Title,
-		&note.Content, &note.Color,
+		&note.Content, &note.Color, &note.DueAt, &note.Locked,
 		&note.CreatedAt, &note.UpdatedAt); err != nil {
 		return &note, apperrors.NewRepositoryError(err)
 	}
@@ -65,23 +96,121 @@ func (nr *noteRepository) GetById(ctx context.Context, userId int, id int) (*mod
 	return &note, nil
 }
 
-func (nr *noteRepository) Create(ctx context.Context, userId int, title, content, color string) (*models.Note, error) {
+// GetAccess returns how the user of the scope relates to the note, to tell
+// what they may do with it.
+func (nr *noteRepository) GetAccess(ctx context.Context, scope models.NoteScope, id int) (*models.NoteAccess, error) {
+	var access models.NoteAccess
+
+	row := nr.db.QueryRow(ctx, querys.GetNoteAccessQuery, scope.UserId, scope.WorkspaceId, id)
+
+	if err := row.Scan(&access.Role, &access.Author); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNoteNotFound
+		}
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	return &access, nil
+}
+
+// Create adds a note to the scope. Notes of a workspace count in the quota of
+// its owner.
+func (nr *noteRepository) Create(ctx context.Context, scope models.NoteScope, title, content, color string, dueAt *time.Time) (*models.Note, error) {
 	var note models.Note
 
 	note.Title = pgtype.Text{String: title, Valid: true}
 	note.Content = pgtype.Text{String: content, Valid: true}
 	note.Color = pgtype.Text{String: color, Valid: true}
+	note.DueAt = newTimestamp(dueAt)
 
-	row := nr.db.QueryRow(ctx, querys.CreateNoteQuery, note.Title, note.Content, note.Color, userId)
+	tx, err := nr.db.BeginTx(ctx, pgx.TxOptions{})
 
-	if err := row.Scan(&note.Id, &note.CreatedAt); err != nil {
+	if err != nil {
+		return &models.Note{}, apperrors.NewRepositoryError(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := nr.create(ctx, tx, scope, &note); err != nil {
+		return &models.Note{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
 		return &models.Note{}, apperrors.NewRepositoryError(err)
 	}
 
 	return &note, nil
 }
 
-func (nr *noteRepository) Update(ctx context.Context, userId int, id int, title, content, color string) (*models.Note, error) {
+// CreateForUsers adds the same personal note to every user, or to none of
+// them when one is over quota. The users are locked in ascending order, so
+// two calls never wait on each other.
+func (nr *noteRepository) CreateForUsers(ctx context.Context, userIds []int, title, content, color string) error {
+	userIds = slices.Clone(userIds)
+	slices.Sort(userIds)
+	userIds = slices.Compact(userIds)
+
+	tx, err := nr.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return apperrors.NewRepositoryError(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	for _, userId := range userIds {
+		note := models.Note{
+			Title:   pgtype.Text{String: title, Valid: true},
+			Content: pgtype.Text{String: content, Valid: true},
+			Color:   pgtype.Text{String: color, Valid: true},
+		}
+
+		if err := nr.create(ctx, tx, models.NoteScope{UserId: userId}, &note); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return apperrors.NewRepositoryError(err)
+	}
+
+	return nil
+}
+
+// create inserts note at the end of the scope, within its quota.
+func (nr *noteRepository) create(ctx context.Context, tx pgx.Tx, scope models.NoteScope, note *models.Note) error {
+	quotaUser, err := lockNotes(ctx, tx, scope)
+
+	if err != nil {
+		return err
+	}
+
+	usage, err := loadQuotaUsage(ctx, tx, quotaUser, nr.quota)
+
+	if err != nil {
+		return err
+	}
+
+	if err := CheckQuota(usage, true, 0, models.NoteSize(note.Title.String, note.Content.String)); err != nil {
+		return err
+	}
+
+	var last string
+
+	if err := tx.QueryRow(ctx, querys.LastNotePositionQuery, scope.UserId, scope.WorkspaceId).Scan(&last); err != nil {
+		return apperrors.NewRepositoryError(err)
+	}
+
+	row := tx.QueryRow(ctx, querys.CreateNoteQuery, scope.UserId, scope.WorkspaceId, note.Title, note.Content, note.Color, note.DueAt, tools.RankBetween(last, ""))
+
+	if err := row.Scan(&note.Id, &note.CreatedAt); err != nil {
+		return apperrors.NewRepositoryError(err)
+	}
+
+	return nil
+}
+
+func (nr *noteRepository) Update(ctx context.Context, scope models.NoteScope, id int, title, content, color string, dueAt *time.Time) (*models.Note, error) {
 	var note models.Note
 
 	var titleValue, contentValue, colorValue, updatedAtValue interface{}
@@ -104,9 +233,9 @@ func (nr *noteRepository) Update(ctx context.Context, userId int, id int, title,
 		colorValue = nil
 	}
 
-	updatedAtValue = time.Now()
+	updatedAtValue = time.Now().UTC()
 
-	_, err := nr.db.Exec(ctx, querys.UpdateNoteQuery, titleValue, contentValue, colorValue, updatedAtValue, id, userId)
+	_, err := nr.db.Exec(ctx, querys.UpdateNoteQuery, scope.UserId, scope.WorkspaceId, titleValue, contentValue, colorValue, newTimestamp(dueAt), updatedAtValue, id)
 
 	if err != nil {
 		return &models.Note{}, apperrors.NewRepositoryError(err)
@@ -115,14 +244,15 @@ func (nr *noteRepository) Update(ctx context.Context, userId int, id int, title,
 	note.Title = pgtype.Text{String: title, Valid: true}
 	note.Content = pgtype.Text{String: content, Valid: true}
 	note.Color = pgtype.Text{String: color, Valid: true}
-	note.UpdatedAt = pgtype.Date{Time: time.Now(), Valid: true}
+	note.DueAt = newTimestamp(dueAt)
+	note.UpdatedAt = pgtype.Timestamp{Time: time.Now().UTC(), Valid: true}
 	note.Id = pgtype.Numeric{Int: big.NewInt(int64(id))}
 
 	return &note, nil
 }
 
-func (nr *noteRepository) Delete(ctx context.Context, userId int, id int) error {
-	_, err := nr.db.Exec(ctx, querys.DeleteNoteQuery, id, userId)
+func (nr *noteRepository) Delete(ctx context.Context, scope models.NoteScope, id int) error {
+	_, err := nr.db.Exec(ctx, querys.DeleteNoteQuery, scope.UserId, scope.WorkspaceId, id)
 
 	if err != nil {
 		return apperrors.NewRepositoryError(err)
@@ -131,8 +261,176 @@ func (nr *noteRepository) Delete(ctx context.Context, userId int, id int) error
 	return nil
 }
 
-func NewNoteRepository(db *pgxpool.Pool) NoteRepository {
+func (nr *noteRepository) GetLock(ctx context.Context, scope models.NoteScope, id int) (*models.NoteLock, error) {
+	var lock models.NoteLock
+
+	row := nr.db.QueryRow(ctx, querys.GetNoteLockQuery, scope.UserId, scope.WorkspaceId, id)
+
+	if err := row.Scan(&lock.Password, &lock.Salt); err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	return &lock, nil
+}
+
+func (nr *noteRepository) Lock(ctx context.Context, scope models.NoteScope, id int, password string, salt []byte, content string) error {
+	tag, err := nr.db.Exec(ctx, querys.LockNoteQuery, scope.UserId, scope.WorkspaceId, password, salt, content, id)
+
+	if err != nil {
+		return apperrors.NewRepositoryError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoteNotFound
+	}
+
+	return nil
+}
+
+func (nr *noteRepository) RemoveLock(ctx context.Context, scope models.NoteScope, id int, content string) error {
+	tag, err := nr.db.Exec(ctx, querys.RemoveNoteLockQuery, scope.UserId, scope.WorkspaceId, content, id)
+
+	if err != nil {
+		return apperrors.NewRepositoryError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoteNotFound
+	}
+
+	return nil
+}
+
+// Move places a note between two neighbours of the manual order. Moves in the
+// same scope are serialized, and when the neighbours sent by the client are no
+// longer adjacent the note is placed right after afterId (or before beforeId).
+// Only the moved note is updated.
+func (nr *noteRepository) Move(ctx context.Context, scope models.NoteScope, id int, afterId int, beforeId int) error {
+	tx, err := nr.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return apperrors.NewRepositoryError(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if _, err := lockNotes(ctx, tx, scope); err != nil {
+		return err
+	}
+
+	var current string
+
+	if err := tx.QueryRow(ctx, querys.GetNotePositionQuery, scope.UserId, scope.WorkspaceId, id).Scan(&current); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNoteNotFound
+		}
+		return apperrors.NewRepositoryError(err)
+	}
+
+	after, err := nr.position(ctx, tx, scope, afterId)
+
+	if err != nil {
+		return err
+	}
+
+	before, err := nr.position(ctx, tx, scope, beforeId)
+
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case after != "" && before != "" && after < before:
+	case after != "":
+		if before, err = nr.neighbour(ctx, tx, querys.NextNotePositionQuery, scope, after, id); err != nil {
+			return err
+		}
+	case before != "":
+		if after, err = nr.neighbour(ctx, tx, querys.PreviousNotePositionQuery, scope, before, id); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, querys.UpdateNotePositionQuery, scope.UserId, scope.WorkspaceId, tools.RankBetween(after, before), id); err != nil {
+		return apperrors.NewRepositoryError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return apperrors.NewRepositoryError(err)
+	}
+
+	return nil
+}
+
+func (nr *noteRepository) position(ctx context.Context, tx pgx.Tx, scope models.NoteScope, id int) (string, error) {
+	var position string
+
+	if id == 0 {
+		return "", nil
+	}
+
+	if err := tx.QueryRow(ctx, querys.GetNotePositionQuery, scope.UserId, scope.WorkspaceId, id).Scan(&position); err != nil {
+		if err == pgx.ErrNoRows {
+			return "", ErrNoteNotFound
+		}
+		return "", apperrors.NewRepositoryError(err)
+	}
+
+	return position, nil
+}
+
+func (nr *noteRepository) neighbour(ctx context.Context, tx pgx.Tx, query string, scope models.NoteScope, position string, id int) (string, error) {
+	var neighbour string
+
+	if err := tx.QueryRow(ctx, query, scope.UserId, scope.WorkspaceId, position, id).Scan(&neighbour); err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", apperrors.NewRepositoryError(err)
+	}
+
+	return neighbour, nil
+}
+
+// lockNotes serializes the changes to the order of the notes of a scope, and
+// to the quota they count in, and returns the user of that quota. Only one
+// user is locked per transaction, before the workspace, so two scopes never
+// wait on each other.
+func lockNotes(ctx context.Context, tx pgx.Tx, scope models.NoteScope) (int, error) {
+	quotaUser, err := quotaUserId(ctx, tx, scope)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, querys.LockUserNotesQuery, quotaUser); err != nil {
+		return 0, apperrors.NewRepositoryError(err)
+	}
+
+	if scope.WorkspaceId == 0 {
+		return quotaUser, nil
+	}
+
+	if _, err := tx.Exec(ctx, querys.LockWorkspaceNotesQuery, scope.WorkspaceId); err != nil {
+		return 0, apperrors.NewRepositoryError(err)
+	}
+
+	return quotaUser, nil
+}
+
+func newTimestamp(t *time.Time) pgtype.Timestamp {
+	if t == nil {
+		return pgtype.Timestamp{}
+	}
+
+	return pgtype.Timestamp{Time: *t, Valid: true}
+}
+
+func NewNoteRepository(db *pgxpool.Pool, quota models.Quota) NoteRepository {
 	return &noteRepository{
-		db: db,
+		db:    db,
+		quota: quota,
 	}
 }
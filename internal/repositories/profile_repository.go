@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/database/querys"
+	"go_pro/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrAvatarNotFound = apperrors.NewRepositoryError(errors.New("avatar not found"))
+
+type ProfileRepository interface {
+	Get(ctx context.Context, userId int) (*models.Profile, error)
+	Update(ctx context.Context, userId int, displayName, locale, timezone string) error
+	SaveAvatar(ctx context.Context, userId int, images map[int][]byte) error
+	GetAvatar(ctx context.Context, userId int, size int) ([]byte, error)
+	DeleteAvatar(ctx context.Context, userId int) error
+}
+
+type profileRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewProfileRepository(db *pgxpool.Pool) ProfileRepository {
+	return &profileRepository{
+		db: db,
+	}
+}
+
+func (pr *profileRepository) Get(ctx context.Context, userId int) (*models.Profile, error) {
+	var profile models.Profile
+
+	row := pr.db.QueryRow(ctx, querys.GetProfileQuery, userId, models.DefaultLocale, models.DefaultTimezone)
+
+	if err := row.Scan(&profile.UserId, &profile.Email, &profile.DisplayName, &profile.Locale,
+		&profile.Timezone, &profile.HasAvatar, &profile.UpdatedAt); err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	return &profile, nil
+}
+
+// Update saves the profile of the user. An empty display name clears it.
+func (pr *profileRepository) Update(ctx context.Context, userId int, displayName, locale, timezone string) error {
+	if _, err := pr.db.Exec(ctx, querys.UpsertProfileQuery, userId, displayName, locale, timezone); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// SaveAvatar replaces the avatar of the user by images, keyed by their size.
+// The profile is touched so the address of the avatar changes and browsers
+// do not show the old one from their cache.
+func (pr *profileRepository) SaveAvatar(ctx context.Context, userId int, images map[int][]byte) error {
+	tx, err := pr.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, querys.DeleteAvatarQuery, userId); err != nil {
+		return fail(err)
+	}
+
+	for size, image := range images {
+		if _, err := tx.Exec(ctx, querys.CreateAvatarQuery, userId, size, image); err != nil {
+			return fail(err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, querys.TouchProfileQuery, userId); err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+func (pr *profileRepository) GetAvatar(ctx context.Context, userId int, size int) ([]byte, error) {
+	var image []byte
+
+	if err := pr.db.QueryRow(ctx, querys.GetAvatarQuery, userId, size).Scan(&image); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrAvatarNotFound
+		}
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	return image, nil
+}
+
+func (pr *profileRepository) DeleteAvatar(ctx context.Context, userId int) error {
+	if _, err := pr.db.Exec(ctx, querys.DeleteAvatarQuery, userId); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
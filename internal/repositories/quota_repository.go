@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/database/querys"
+	"go_pro/internal/models"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrNoteCountQuotaExceeded = apperrors.NewWithStatus(errors.New("você atingiu o limite de anotações da sua conta"), http.StatusUnprocessableEntity)
+var ErrStorageQuotaExceeded = apperrors.NewWithStatus(errors.New("você atingiu o limite de armazenamento da sua conta"), http.StatusUnprocessableEntity)
+
+func NewNoteSizeQuotaError(max int64) error {
+	return apperrors.NewWithStatus(fmt.Errorf("a anotação passou do tamanho máximo de %d KB", max/1024), http.StatusRequestEntityTooLarge)
+}
+
+type QuotaRepository interface {
+	Usage(ctx context.Context, scope models.NoteScope) (*models.QuotaUsage, error)
+}
+
+type quotaRepository struct {
+	db       *pgxpool.Pool
+	defaults models.Quota
+}
+
+func NewQuotaRepository(db *pgxpool.Pool, defaults models.Quota) QuotaRepository {
+	return &quotaRepository{
+		db:       db,
+		defaults: defaults,
+	}
+}
+
+// Usage returns the usage of the quota that applies to the notes of scope.
+func (qr *quotaRepository) Usage(ctx context.Context, scope models.NoteScope) (*models.QuotaUsage, error) {
+	userId, err := quotaUserId(ctx, qr.db, scope)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return loadQuotaUsage(ctx, qr.db, userId, qr.defaults)
+}
+
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// quotaUserId returns the user whose quota the notes of scope count in: the
+// user for personal notes, the owner for the notes of a workspace. A
+// workspace the user is not a member of has no notes to see.
+func quotaUserId(ctx context.Context, db queryRower, scope models.NoteScope) (int, error) {
+	if scope.WorkspaceId == 0 {
+		return scope.UserId, nil
+	}
+
+	var ownerId pgtype.Numeric
+
+	if err := db.QueryRow(ctx, querys.WorkspaceQuotaUserQuery, scope.UserId, scope.WorkspaceId).Scan(&ownerId); err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrNoteNotFound
+		}
+		return 0, apperrors.NewRepositoryError(err)
+	}
+
+	return int(ownerId.Int.Int64()), nil
+}
+
+// loadQuotaUsage reads the usage of a user together with the limits that
+// apply to them: the per-user overrides when set, the defaults otherwise.
+func loadQuotaUsage(ctx context.Context, db queryRower, userId int, defaults models.Quota) (*models.QuotaUsage, error) {
+	var usage models.QuotaUsage
+	var maxNotes, maxNoteSize, maxStorage pgtype.Int8
+
+	row := db.QueryRow(ctx, querys.QuotaUsageQuery, userId)
+
+	if err := row.Scan(&usage.Notes, &usage.Storage, &maxNotes, &maxNoteSize, &maxStorage); err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	usage.Quota = defaults
+
+	if maxNotes.Valid {
+		usage.MaxNotes = maxNotes.Int64
+	}
+
+	if maxNoteSize.Valid {
+		usage.MaxNoteSize = maxNoteSize.Int64
+	}
+
+	if maxStorage.Valid {
+		usage.MaxStorage = maxStorage.Int64
+	}
+
+	return &usage, nil
+}
+
+// IsQuotaError tells if err is one of the errors of CheckQuota, which carry
+// their status: 413 for a note too large, 422 for a full account.
+func IsQuotaError(err error) bool {
+	var statusError apperrors.StatusError
+
+	if err == ErrNoteCountQuotaExceeded || err == ErrStorageQuotaExceeded {
+		return true
+	}
+
+	return errors.As(err, &statusError) && statusError.HTTPStatus() == http.StatusRequestEntityTooLarge
+}
+
+// CheckQuota returns the quota error for saving a note of newSize bytes that
+// replaces one of oldSize bytes (zero for a new note).
+func CheckQuota(usage *models.QuotaUsage, isNew bool, oldSize, newSize int64) error {
+	if usage.MaxNoteSize > 0 && newSize > usage.MaxNoteSize {
+		return NewNoteSizeQuotaError(usage.MaxNoteSize)
+	}
+
+	if isNew && usage.MaxNotes > 0 && usage.Notes >= usage.MaxNotes {
+		return ErrNoteCountQuotaExceeded
+	}
+
+	if usage.MaxStorage > 0 && usage.Storage-oldSize+newSize > usage.MaxStorage {
+		return ErrStorageQuotaExceeded
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"go_pro/internal/database/querys"
+	"go_pro/internal/models"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RateLimitRepository interface {
+	Take(ctx context.Context, key string, limit models.RateLimit) (bool, time.Duration, error)
+	DeleteIdle(ctx context.Context, idle time.Duration) error
+}
+
+type rateLimitRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRateLimitRepository(db *pgxpool.Pool) RateLimitRepository {
+	return &rateLimitRepository{
+		db: db,
+	}
+}
+
+// Take removes a token from the bucket of key. When the bucket is empty it
+// returns false and how long until a token is available. The bucket row is
+// locked for the whole check, so every instance sees the same count.
+func (rr *rateLimitRepository) Take(ctx context.Context, key string, limit models.RateLimit) (bool, time.Duration, error) {
+	tx, err := rr.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return false, 0, fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, querys.CreateRateLimitBucketQuery, key, float64(limit.Requests)); err != nil {
+		return false, 0, fail(err)
+	}
+
+	var tokens, elapsed float64
+
+	if err := tx.QueryRow(ctx, querys.GetRateLimitBucketQuery, key).Scan(&tokens, &elapsed); err != nil {
+		return false, 0, fail(err)
+	}
+
+	tokens, allowed, retryAfter := takeToken(tokens, elapsed, limit)
+
+	if _, err := tx.Exec(ctx, querys.UpdateRateLimitBucketQuery, tokens, key); err != nil {
+		return false, 0, fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return false, 0, fail(err)
+	}
+
+	return allowed, retryAfter, nil
+}
+
+// takeToken refills a bucket that held tokens elapsed seconds ago, at the
+// rate of the limit and up to its capacity, then takes one token from it. It
+// returns the tokens left, and when none could be taken, how long until one
+// is back.
+func takeToken(tokens, elapsed float64, limit models.RateLimit) (float64, bool, time.Duration) {
+	capacity := float64(limit.Requests)
+	refill := capacity / limit.Period.Seconds()
+
+	tokens = math.Min(capacity, tokens+math.Max(elapsed, 0)*refill)
+
+	if tokens >= 1 {
+		return tokens - 1, true, 0
+	}
+
+	return tokens, false, time.Duration((1 - tokens) / refill * float64(time.Second))
+}
+
+// DeleteIdle removes the buckets untouched for longer than idle. Once full
+// again they behave the same as a missing bucket.
+func (rr *rateLimitRepository) DeleteIdle(ctx context.Context, idle time.Duration) error {
+	if _, err := rr.db.Exec(ctx, querys.DeleteIdleRateLimitBucketsQuery, idle.Seconds()); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
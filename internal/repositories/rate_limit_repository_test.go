@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"go_pro/internal/models"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTakeToken(t *testing.T) {
+	// 5 requests every 10 minutes, one token back every 2 minutes
+	limit := models.RateLimit{Requests: 5, Period: 10 * time.Minute}
+
+	tests := []struct {
+		name        string
+		tokens      float64
+		elapsed     float64
+		wantTokens  float64
+		wantAllowed bool
+		wantRetry   time.Duration
+	}{
+		{"full bucket", 5, 0, 4, true, 0},
+		{"last token", 1, 0, 0, true, 0},
+		{"empty bucket", 0, 0, 0, false, 2 * time.Minute},
+		{"almost a token", 0.5, 0, 0.5, false, time.Minute},
+		{"refilled to one token", 0, 120, 0, true, 0},
+		{"partly refilled", 0, 60, 0.5, false, time.Minute},
+		{"refill stops at the capacity", 2, 3600, 4, true, 0},
+		{"clock going back does not drain", 0, -600, 0, false, 2 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, allowed, retry := takeToken(tt.tokens, tt.elapsed, limit)
+
+			if math.Abs(tokens-tt.wantTokens) > 1e-9 || allowed != tt.wantAllowed {
+				t.Fatalf("takeToken() = %v, %v, want %v, %v", tokens, allowed, tt.wantTokens, tt.wantAllowed)
+			}
+
+			if (retry - tt.wantRetry).Abs() > time.Millisecond {
+				t.Fatalf("retry after %v, want %v", retry, tt.wantRetry)
+			}
+		})
+	}
+}
+
+// A burst drains the bucket after exactly Requests calls, and the bucket then
+// lets one request through every Period/Requests.
+func TestTakeTokenBurstThenSteady(t *testing.T) {
+	limit := models.RateLimit{Requests: 3, Period: time.Minute}
+	tokens := float64(limit.Requests)
+
+	for i := range limit.Requests {
+		var allowed bool
+
+		if tokens, allowed, _ = takeToken(tokens, 0, limit); !allowed {
+			t.Fatalf("request %d of the burst refused", i+1)
+		}
+	}
+
+	tokens, allowed, retry := takeToken(tokens, 0, limit)
+
+	if allowed {
+		t.Fatal("request after the burst allowed")
+	}
+
+	for i := range 5 {
+		if tokens, allowed, _ = takeToken(tokens, retry.Seconds(), limit); !allowed {
+			t.Fatalf("steady request %d refused after waiting %v", i+1, retry)
+		}
+
+		if _, allowed, _ = takeToken(tokens, 0, limit); allowed {
+			t.Fatalf("steady request %d let a second one through at once", i+1)
+		}
+	}
+}
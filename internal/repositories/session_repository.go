@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/database/querys"
+	"go_pro/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrSessionNotFound = apperrors.NewRepositoryError(errors.New("session not found"))
+
+// SessionRepository keeps track of which sessions belong to which user. The
+// sessions themselves are stored by scs, which knows nothing about users.
+type SessionRepository interface {
+	Register(ctx context.Context, userId int, token, ip, userAgent string) error
+	Touch(ctx context.Context, token, ip string) error
+	List(ctx context.Context, userId int, currentToken string) ([]models.UserSession, error)
+	Remove(ctx context.Context, token string) error
+	Revoke(ctx context.Context, userId int, id int, currentToken string) error
+	RevokeOthers(ctx context.Context, userId int, keepToken string) error
+	DeleteExpired(ctx context.Context, age time.Duration) error
+}
+
+type sessionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionRepository(db *pgxpool.Pool) SessionRepository {
+	return &sessionRepository{
+		db: db,
+	}
+}
+
+func (sr *sessionRepository) Register(ctx context.Context, userId int, token, ip, userAgent string) error {
+	if _, err := sr.db.Exec(ctx, querys.CreateUserSessionQuery, token, userId, ip, userAgent); err != nil {
+		return fail(err)
+	}
+
+	if _, err := sr.db.Exec(ctx, querys.UpdateLastLoginQuery, userId); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// Touch records that the session was just used, and from where.
+func (sr *sessionRepository) Touch(ctx context.Context, token, ip string) error {
+	if _, err := sr.db.Exec(ctx, querys.TouchUserSessionQuery, token, ip); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// List returns the sessions of the user that did not expire, flagging the
+// one of currentToken.
+func (sr *sessionRepository) List(ctx context.Context, userId int, currentToken string) ([]models.UserSession, error) {
+	var list []models.UserSession
+
+	rows, err := sr.db.Query(ctx, querys.ListUserSessionsQuery, userId, currentToken)
+
+	if err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.UserSession
+
+		if err = rows.Scan(&row.Id, &row.IP, &row.UserAgent, &row.Current, &row.CreatedAt, &row.LastSeenAt); err != nil {
+			return nil, apperrors.NewRepositoryError(err)
+		}
+
+		list = append(list, row)
+	}
+
+	return list, nil
+}
+
+func (sr *sessionRepository) Remove(ctx context.Context, token string) error {
+	if _, err := sr.db.Exec(ctx, querys.DeleteUserSessionQuery, token); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// Revoke signs the user out of one of their sessions. The session of
+// currentToken is not found here: it ends by signing out, otherwise it would
+// be saved again without its row.
+func (sr *sessionRepository) Revoke(ctx context.Context, userId int, id int, currentToken string) error {
+	tx, err := sr.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, querys.DeleteSessionByIdQuery, id, userId, currentToken); err != nil {
+		return fail(err)
+	}
+
+	tag, err := tx.Exec(ctx, querys.DeleteUserSessionByIdQuery, id, userId, currentToken)
+
+	if err != nil {
+		return fail(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// RevokeOthers signs the user out of every session but the one of keepToken.
+func (sr *sessionRepository) RevokeOthers(ctx context.Context, userId int, keepToken string) error {
+	tx, err := sr.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := deleteOtherSessions(ctx, tx, userId, keepToken); err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// deleteOtherSessions signs the user out everywhere but in the session of
+// keepToken. An empty keepToken signs the user out of every session.
+func deleteOtherSessions(ctx context.Context, tx pgx.Tx, userId any, keepToken string) error {
+	if _, err := tx.Exec(ctx, querys.DeleteOtherSessionsQuery, userId, keepToken); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, querys.DeleteOtherUserSessionsQuery, userId, keepToken); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteExpired removes the rows of sessions scs no longer stores. A row is
+// written before scs saves its session at the end of the sign in request, so
+// only rows older than age are considered.
+func (sr *sessionRepository) DeleteExpired(ctx context.Context, age time.Duration) error {
+	if _, err := sr.db.Exec(ctx, querys.DeleteExpiredUserSessionsQuery, age.Seconds()); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"context"
+	"go_pro/internal/database/querys"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TwoFactorRepository interface {
+	Enable(ctx context.Context, userId int, secret string, step int64, codeHashes []string) error
+	Disable(ctx context.Context, userId int) error
+	UseStep(ctx context.Context, userId int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error)
+	RegenerateRecoveryCodes(ctx context.Context, userId int, codeHashes []string) error
+	CountRecoveryCodes(ctx context.Context, userId int) (int, error)
+}
+
+type twoFactorRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTwoFactorRepository(db *pgxpool.Pool) TwoFactorRepository {
+	return &twoFactorRepository{
+		db: db,
+	}
+}
+
+// Enable turns two-factor authentication on with the confirmed secret. The
+// step used to confirm it counts as used, and the recovery codes replace any
+// previous ones.
+func (tr *twoFactorRepository) Enable(ctx context.Context, userId int, secret string, step int64, codeHashes []string) error {
+	tx, err := tr.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, querys.EnableTwoFactorQuery, secret, step, userId); err != nil {
+		return fail(err)
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userId, codeHashes); err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+func (tr *twoFactorRepository) Disable(ctx context.Context, userId int) error {
+	tx, err := tr.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, querys.DisableTwoFactorQuery, userId); err != nil {
+		return fail(err)
+	}
+
+	if _, err := tx.Exec(ctx, querys.DeleteRecoveryCodesQuery, userId); err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// UseStep records the step of an accepted code. It returns false when that
+// step, or a later one, was already used, so a code works only once.
+func (tr *twoFactorRepository) UseStep(ctx context.Context, userId int, step int64) (bool, error) {
+	tag, err := tr.db.Exec(ctx, querys.UseTOTPStepQuery, step, userId)
+
+	if err != nil {
+		return false, fail(err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (tr *twoFactorRepository) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error) {
+	tag, err := tr.db.Exec(ctx, querys.UseRecoveryCodeQuery, userId, codeHash)
+
+	if err != nil {
+		return false, fail(err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (tr *twoFactorRepository) RegenerateRecoveryCodes(ctx context.Context, userId int, codeHashes []string) error {
+	tx, err := tr.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userId, codeHashes); err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+func (tr *twoFactorRepository) CountRecoveryCodes(ctx context.Context, userId int) (int, error) {
+	var count int
+
+	if err := tr.db.QueryRow(ctx, querys.CountRecoveryCodesQuery, userId).Scan(&count); err != nil {
+		return 0, fail(err)
+	}
+
+	return count, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userId int, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, querys.DeleteRecoveryCodesQuery, userId); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(ctx, querys.CreateRecoveryCodeQuery, userId, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
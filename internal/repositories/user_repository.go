@@ -8,6 +8,7 @@ import (
 	"go_pro/internal/models"
 	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -15,33 +16,55 @@ import (
 )
 
 var ErrDuplicateEmail = apperrors.NewRepositoryError(errors.New("duplicate email"))
-var ErrInvalidTokenOrUserAlreadyConfirmed = apperrors.NewRepositoryError(errors.New("invalid token or user already confirmed"))
+var ErrInvalidToken = apperrors.NewRepositoryError(errors.New("invalid, expired or used token"))
 var ErrEmailNotFound = apperrors.NewRepositoryError(errors.New("email not found"))
+var ErrInboxTokenNotFound = apperrors.NewRepositoryError(errors.New("inbox token not found"))
+var ErrEmailChangeNotFound = apperrors.NewRepositoryError(errors.New("invalid or expired email change"))
 var fail = func(err error) error {
 	slog.Error(err.Error())
 	return apperrors.NewRepositoryError(err)
 }
 
 type UserRepository interface {
-	Create(ctx context.Context, email, password, hashKey string) (*models.User, string, error)
-	CreateResetPasswordToken(ctx context.Context, email, hashToken string) (string, error)
-	ConfirmUserByToken(ctx context.Context, token string) error
+	Create(ctx context.Context, email, password, tokenHash string) (*models.User, error)
+	CreateConfirmationToken(ctx context.Context, email, tokenHash string) error
+	CreateResetPasswordToken(ctx context.Context, email, tokenHash string) error
+	CreateMagicLinkToken(ctx context.Context, email, tokenHash, deviceHash string) error
+	UseMagicLinkToken(ctx context.Context, tokenHash string) (*models.User, error)
+	ConfirmUserByToken(ctx context.Context, tokenHash string) error
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
-	GetUserConfirmationByToken(ctx context.Context, token string) (*models.UserConfirmmationToken, error)
-	UpdatePasswordByToken(ctx context.Context, pass, token string) (string, error)
+	FindValidToken(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error)
+	DeleteUnconfirmed(ctx context.Context, age time.Duration) (int64, error)
+	UpdatePasswordByToken(ctx context.Context, pass, tokenHash string) (string, error)
+	UpdatePassword(ctx context.Context, userId int, pass, currentSession string) error
+	RehashPassword(ctx context.Context, userId int, oldHash, newHash string) error
+	HasInboxToken(ctx context.Context, userId int) (bool, error)
+	UpdateInboxToken(ctx context.Context, userId int, tokenHash string) error
+	FindUserIdByInboxToken(ctx context.Context, tokenHash string) (int, error)
+	FindById(ctx context.Context, userId int) (*models.User, error)
+	CreateEmailChange(ctx context.Context, userId int, newEmail, tokenHash string) error
+	GetPendingEmailChange(ctx context.Context, userId int) (string, error)
+	ConfirmEmailChange(ctx context.Context, tokenHash string) (*models.User, error)
 }
 
 type userRepository struct {
-	db *pgxpool.Pool
+	db       *pgxpool.Pool
+	tokenTTL models.UserTokenTTL
 }
 
-func NewUserRepository(db *pgxpool.Pool) UserRepository {
+func NewUserRepository(db *pgxpool.Pool, tokenTTL models.UserTokenTTL) UserRepository {
 	return &userRepository{
-		db: db,
+		db:       db,
+		tokenTTL: tokenTTL,
 	}
 }
 
-func (ur *userRepository) Create(ctx context.Context, email, password, hashKey string) (*models.User, string, error) {
+// Create saves a user waiting for confirmation, together with the hash of
+// the token sent to confirm them. Signing up again with an email that was
+// never confirmed keeps the stored password: the new one travels with the
+// token and only replaces it when that link is used. Only the latest link
+// works.
+func (ur *userRepository) Create(ctx context.Context, email, password, tokenHash string) (*models.User, error) {
 	var user models.User
 
 	user.Email = pgtype.Text{String: email, Valid: true}
@@ -50,7 +73,7 @@ func (ur *userRepository) Create(ctx context.Context, email, password, hashKey s
 	tx, err := ur.db.BeginTx(ctx, pgx.TxOptions{})
 
 	if err != nil {
-		return &user, "", fail(err)
+		return &user, fail(err)
 	}
 
 	defer tx.Rollback(ctx)
@@ -58,36 +81,115 @@ func (ur *userRepository) Create(ctx context.Context, email, password, hashKey s
 	row := tx.QueryRow(ctx, querys.CreateUserQuery, user.Email, user.Password)
 
 	if err := row.Scan(&user.Id, &user.CreatedAt); err != nil {
-		if strings.Contains(err.Error(), "violates unique constraint") {
-			return &user, "", fail(ErrDuplicateEmail)
+		if err == pgx.ErrNoRows {
+			return &user, ErrDuplicateEmail
 		}
-		return &user, "", fail(err)
+		return &user, fail(err)
+	}
+
+	if _, err := tx.Exec(ctx, querys.DeleteUnusedUserTokensQuery, user.Id, models.TokenPurposeConfirmation); err != nil {
+		return &user, fail(err)
+	}
+
+	if _, err := tx.Exec(ctx, querys.CreateConfirmationTokenQuery, user.Id, tokenHash, ur.tokenTTL.Confirmation.Seconds(),
+		user.Password); err != nil {
+		return &user, fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return &user, fail(err)
 	}
 
-	userToken, err := ur.createConfirmationToken(ctx, tx, &user, hashKey)
+	return &user, nil
+}
+
+func (ur *userRepository) ConfirmUserByToken(ctx context.Context, tokenHash string) error {
+	tx, err := ur.db.BeginTx(ctx, pgx.TxOptions{})
 
 	if err != nil {
-		return &user, "", fail(err)
+		return fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	userId, err := useToken(ctx, tx, models.TokenPurposeConfirmation, tokenHash)
+
+	if err != nil {
+		return err
+	}
+
+	var password pgtype.Text
+
+	if err := tx.QueryRow(ctx, querys.GetUserTokenPasswordQuery, tokenHash).Scan(&password); err != nil {
+		return fail(err)
+	}
+
+	if _, err = tx.Exec(ctx, querys.UpdateUserConfirmedQuery, userId, password); err != nil {
+		return fail(err)
 	}
 
 	if err = tx.Commit(ctx); err != nil {
-		return &user, "", fail(err)
+		return fail(err)
+	}
+
+	return nil
+}
+
+// createToken saves the hash of a token for purpose, valid for the
+// configured time of that purpose. deviceHash ties the token to a browser,
+// an empty one to none.
+func (ur *userRepository) createToken(ctx context.Context, tx pgx.Tx, userId any, purpose, tokenHash, deviceHash string) error {
+	ttl := ur.tokenTTL.Confirmation
+
+	switch purpose {
+	case models.TokenPurposePasswordReset:
+		ttl = ur.tokenTTL.PasswordReset
+	case models.TokenPurposeMagicLink:
+		ttl = ur.tokenTTL.MagicLink
 	}
 
-	return &user, userToken.Token.String, nil
+	_, err := tx.Exec(ctx, querys.CreateUserTokenQuery, userId, purpose, tokenHash, ttl.Seconds(), deviceHash)
+
+	return err
 }
 
-func (ur *userRepository) ConfirmUserByToken(ctx context.Context, token string) error {
-	var userId, tokenId pgtype.Numeric
-	row := ur.db.QueryRow(ctx, querys.GetUserJoinUserTokenQuery, token)
+// useToken marks a valid token as used and returns its user. The update
+// locks the row, so a token is only ever used once.
+func useToken(ctx context.Context, tx pgx.Tx, purpose, tokenHash string) (pgtype.Numeric, error) {
+	var userId pgtype.Numeric
+
+	row := tx.QueryRow(ctx, querys.UseUserTokenQuery, tokenHash, purpose)
 
-	if err := row.Scan(&userId, &tokenId); err != nil {
+	if err := row.Scan(&userId); err != nil {
 		if err == pgx.ErrNoRows {
-			return ErrInvalidTokenOrUserAlreadyConfirmed
+			return userId, ErrInvalidToken
 		}
-		return apperrors.NewRepositoryError(err)
+		return userId, fail(err)
+	}
+
+	return userId, nil
+}
+
+func (ur *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+
+	row := ur.db.QueryRow(ctx, querys.FindByEmailQuery, email)
+
+	if err := row.Scan(&user.Id, &user.Email, &user.Password, &user.Active, &user.DeletionRequestedAt,
+		&user.TOTPSecret, &user.TOTPEnabled, &user.Role, &user.DisabledAt); err != nil {
+		return nil, apperrors.NewRepositoryError(err)
 	}
 
+	return &user, nil
+}
+
+// CreateConfirmationToken saves the hash of a new confirmation token for a
+// user who did not confirm the sign up yet. The previous links stop working,
+// and the account counts as recent again for DeleteUnconfirmed. The new link
+// carries the password of the latest sign up, like the link it replaces.
+func (ur *userRepository) CreateConfirmationToken(ctx context.Context, email, tokenHash string) error {
+	var userId pgtype.Numeric
+
 	tx, err := ur.db.BeginTx(ctx, pgx.TxOptions{})
 
 	if err != nil {
@@ -96,18 +198,93 @@ func (ur *userRepository) ConfirmUserByToken(ctx context.Context, token string)
 
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, querys.UpdateUserConfirmedQuery, userId)
+	if err := tx.QueryRow(ctx, querys.TouchUnconfirmedUserQuery, email).Scan(&userId); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrEmailNotFound
+		}
+		return fail(err)
+	}
+
+	var password pgtype.Text
+
+	if err := tx.QueryRow(ctx, querys.GetPendingConfirmationPasswordQuery, userId).Scan(&password); err != nil && err != pgx.ErrNoRows {
+		return fail(err)
+	}
+
+	if _, err := tx.Exec(ctx, querys.DeleteUnusedUserTokensQuery, userId, models.TokenPurposeConfirmation); err != nil {
+		return fail(err)
+	}
+
+	if _, err := tx.Exec(ctx, querys.CreateConfirmationTokenQuery, userId, tokenHash, ur.tokenTTL.Confirmation.Seconds(),
+		password); err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// CreateResetPasswordToken saves the hash of a password reset token for an
+// active user. The previous reset links of the user stop working.
+func (ur *userRepository) CreateResetPasswordToken(ctx context.Context, email, tokenHash string) error {
+	user, err := ur.FindByEmail(ctx, email)
+
+	if err != nil || !user.Active.Bool {
+		return ErrEmailNotFound
+	}
+
+	tx, err := ur.db.BeginTx(ctx, pgx.TxOptions{})
 
 	if err != nil {
 		return fail(err)
 	}
 
-	_, err = tx.Exec(ctx, querys.UpdateTokenConfirmedQuery, tokenId)
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, querys.DeleteUnusedUserTokensQuery, user.Id, models.TokenPurposePasswordReset); err != nil {
+		return fail(err)
+	}
+
+	if err := ur.createToken(ctx, tx, user.Id, models.TokenPurposePasswordReset, tokenHash, ""); err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// CreateMagicLinkToken saves the hash of a sign in link for an active user,
+// along with the hash of the device cookie of the browser that asked for it.
+// The previous links of the user stop working.
+func (ur *userRepository) CreateMagicLinkToken(ctx context.Context, email, tokenHash, deviceHash string) error {
+	user, err := ur.FindByEmail(ctx, email)
+
+	if err != nil || !user.Active.Bool || user.DisabledAt.Valid {
+		return ErrEmailNotFound
+	}
+
+	tx, err := ur.db.BeginTx(ctx, pgx.TxOptions{})
 
 	if err != nil {
 		return fail(err)
 	}
 
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, querys.DeleteUnusedUserTokensQuery, user.Id, models.TokenPurposeMagicLink); err != nil {
+		return fail(err)
+	}
+
+	if err := ur.createToken(ctx, tx, user.Id, models.TokenPurposeMagicLink, tokenHash, deviceHash); err != nil {
+		return fail(err)
+	}
+
 	if err = tx.Commit(ctx); err != nil {
 		return fail(err)
 	}
@@ -115,41 +292,64 @@ func (ur *userRepository) ConfirmUserByToken(ctx context.Context, token string)
 	return nil
 }
 
-func (ur *userRepository) createConfirmationToken(ctx context.Context, tx pgx.Tx, user *models.User, hashKey string) (*models.UserConfirmmationToken, error) {
-	var userToken models.UserConfirmmationToken
+// UseMagicLinkToken uses a sign in link and returns the user it signs in.
+func (ur *userRepository) UseMagicLinkToken(ctx context.Context, tokenHash string) (*models.User, error) {
+	tx, err := ur.db.BeginTx(ctx, pgx.TxOptions{})
 
-	userToken.Token = pgtype.Text{String: hashKey, Valid: true}
-	userToken.UserId = user.Id
+	if err != nil {
+		return nil, fail(err)
+	}
 
-	row := tx.QueryRow(ctx, querys.CreateTokenQuery, userToken.UserId, userToken.Token)
+	defer tx.Rollback(ctx)
 
-	if err := row.Scan(&userToken.Id, &userToken.CreatedAt); err != nil {
-		tx.Rollback(ctx)
+	userId, err := useToken(ctx, tx, models.TokenPurposeMagicLink, tokenHash)
+
+	if err != nil {
 		return nil, err
 	}
 
-	return &userToken, nil
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fail(err)
+	}
+
+	return ur.FindById(ctx, int(userId.Int.Int64()))
 }
 
-func (ur *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
-	var user models.User
+// FindValidToken returns the token of purpose when it is neither expired nor
+// used.
+func (ur *userRepository) FindValidToken(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	var token models.UserToken
 
-	row := ur.db.QueryRow(ctx, querys.FindByEmailQuery, email)
+	row := ur.db.QueryRow(ctx, querys.GetUserTokenQuery, tokenHash, purpose)
 
-	if err := row.Scan(&user.Id, &user.Email, &user.Password, &user.Active); err != nil {
+	if err := row.Scan(&token.Id, &token.UserId, &token.Purpose, &token.DeviceHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrInvalidToken
+		}
 		return nil, apperrors.NewRepositoryError(err)
 	}
 
-	return &user, nil
+	return &token, nil
 }
 
-func (ur *userRepository) CreateResetPasswordToken(ctx context.Context, email, hashToken string) (string, error) {
-	user, err := ur.FindByEmail(ctx, email)
+// DeleteUnconfirmed removes the accounts that were not confirmed within age
+// of their last sign up, returning how many.
+func (ur *userRepository) DeleteUnconfirmed(ctx context.Context, age time.Duration) (int64, error) {
+	tag, err := ur.db.Exec(ctx, querys.DeleteUnconfirmedUsersQuery, age.Seconds())
 
-	if err != nil || !user.Active.Bool {
-		return "", ErrEmailNotFound
+	if err != nil {
+		return 0, fail(err)
 	}
 
+	return tag.RowsAffected(), nil
+}
+
+// UpdatePasswordByToken uses a reset token to change the password of its
+// user, signing them out everywhere and revoking their access tokens, and
+// returns their email.
+func (ur *userRepository) UpdatePasswordByToken(ctx context.Context, pass, tokenHash string) (string, error) {
+	var email pgtype.Text
+
 	tx, err := ur.db.BeginTx(ctx, pgx.TxOptions{})
 
 	if err != nil {
@@ -158,63 +358,223 @@ func (ur *userRepository) CreateResetPasswordToken(ctx context.Context, email, h
 
 	defer tx.Rollback(ctx)
 
-	ctoken, err := ur.createConfirmationToken(ctx, tx, user, hashToken)
+	userId, err := useToken(ctx, tx, models.TokenPurposePasswordReset, tokenHash)
 
 	if err != nil {
-		return "", ErrEmailNotFound
+		return "", err
+	}
+
+	if _, err := tx.Exec(ctx, querys.UpdatePasswordQuery, pass, userId); err != nil {
+		return "", fail(err)
+	}
+
+	if err := tx.QueryRow(ctx, querys.GetUserEmailQuery, userId).Scan(&email); err != nil {
+		return "", fail(err)
+	}
+
+	if _, err := tx.Exec(ctx, querys.DeleteUnusedUserTokensQuery, userId, models.TokenPurposePasswordReset); err != nil {
+		return "", fail(err)
+	}
+
+	if err := deleteOtherSessions(ctx, tx, userId, ""); err != nil {
+		return "", fail(err)
+	}
+
+	if err := deleteAccessTokens(ctx, tx, userId); err != nil {
+		return "", fail(err)
 	}
 
 	if err = tx.Commit(ctx); err != nil {
 		return "", fail(err)
 	}
 
-	return ctoken.Token.String, nil
+	return email.String, nil
+}
+
+// UpdatePassword changes the password of the user, signs them out of every
+// session but currentSession and revokes their access tokens.
+func (ur *userRepository) UpdatePassword(ctx context.Context, userId int, pass, currentSession string) error {
+	tx, err := ur.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, querys.UpdatePasswordQuery, pass, userId); err != nil {
+		return fail(err)
+	}
+
+	if err := deleteOtherSessions(ctx, tx, userId, currentSession); err != nil {
+		return fail(err)
+	}
+
+	if err := deleteAccessTokens(ctx, tx, userId); err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fail(err)
+	}
+
+	return nil
 }
 
-func (ur *userRepository) GetUserConfirmationByToken(ctx context.Context, token string) (*models.UserConfirmmationToken, error) {
-	var userToken models.UserConfirmmationToken
+// RehashPassword stores a new hash of the same password. Unlike a change of
+// password it keeps the sessions, and it does nothing when the password was
+// changed since oldHash was read.
+func (ur *userRepository) RehashPassword(ctx context.Context, userId int, oldHash, newHash string) error {
+	if _, err := ur.db.Exec(ctx, querys.RehashPasswordQuery, userId, oldHash, newHash); err != nil {
+		return fail(err)
+	}
 
-	row := ur.db.QueryRow(ctx, querys.GetUserConfirmationByTokenQuery, token)
+	return nil
+}
+
+func (ur *userRepository) HasInboxToken(ctx context.Context, userId int) (bool, error) {
+	var exists bool
+
+	if err := ur.db.QueryRow(ctx, querys.HasInboxTokenQuery, userId).Scan(&exists); err != nil {
+		return false, apperrors.NewRepositoryError(err)
+	}
+
+	return exists, nil
+}
+
+// UpdateInboxToken replaces the hash of the inbox token of the user. An empty
+// tokenHash disables the inbox.
+func (ur *userRepository) UpdateInboxToken(ctx context.Context, userId int, tokenHash string) error {
+	var value pgtype.Text
 
-	if err := row.Scan(&userToken.Id, &userToken.UserId, &userToken.Token, &userToken.Confirmed, &userToken.CreatedAt, &userToken.UpdatedAt); err != nil {
+	if tokenHash != "" {
+		value = pgtype.Text{String: tokenHash, Valid: true}
+	}
+
+	if _, err := ur.db.Exec(ctx, querys.UpdateInboxTokenQuery, value, userId); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+func (ur *userRepository) FindUserIdByInboxToken(ctx context.Context, tokenHash string) (int, error) {
+	var userId pgtype.Int8
+
+	row := ur.db.QueryRow(ctx, querys.GetUserIdByInboxTokenQuery, tokenHash)
+
+	if err := row.Scan(&userId); err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrInboxTokenNotFound
+		}
+		return 0, apperrors.NewRepositoryError(err)
+	}
+
+	return int(userId.Int64), nil
+}
+
+func (ur *userRepository) FindById(ctx context.Context, userId int) (*models.User, error) {
+	var user models.User
+
+	row := ur.db.QueryRow(ctx, querys.FindUserByIdQuery, userId)
+
+	if err := row.Scan(&user.Id, &user.Email, &user.Password, &user.Active, &user.DeletionRequestedAt,
+		&user.TOTPSecret, &user.TOTPEnabled, &user.Role, &user.DisabledAt); err != nil {
 		return nil, apperrors.NewRepositoryError(err)
 	}
 
-	return &userToken, nil
+	return &user, nil
 }
 
-func (ur *userRepository) UpdatePasswordByToken(ctx context.Context, pass, token string) (string, error) {
-	var userId, tokenId pgtype.Numeric
+// CreateEmailChange records a change of email waiting for confirmation. Any
+// previous pending change of the user is discarded, so only the latest link
+// works.
+func (ur *userRepository) CreateEmailChange(ctx context.Context, userId int, newEmail, tokenHash string) error {
+	var exists bool
+
+	if err := ur.db.QueryRow(ctx, querys.ExistsUserByEmailQuery, newEmail).Scan(&exists); err != nil {
+		return fail(err)
+	}
+
+	if exists {
+		return ErrDuplicateEmail
+	}
+
+	tx, err := ur.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, querys.DeletePendingEmailChangesQuery, userId); err != nil {
+		return fail(err)
+	}
+
+	if _, err := tx.Exec(ctx, querys.CreateEmailChangeQuery, userId, newEmail, tokenHash); err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+func (ur *userRepository) GetPendingEmailChange(ctx context.Context, userId int) (string, error) {
 	var email pgtype.Text
-	row := ur.db.QueryRow(ctx, querys.SelectPasswordByTokenQuery, token)
 
-	if err := row.Scan(&userId, &email, &tokenId); err != nil {
+	row := ur.db.QueryRow(ctx, querys.GetPendingEmailChangeQuery, userId, ur.tokenTTL.EmailChange.Seconds())
+
+	if err := row.Scan(&email); err != nil {
 		if err == pgx.ErrNoRows {
-			return "", ErrInvalidTokenOrUserAlreadyConfirmed
+			return "", nil
 		}
-
 		return "", apperrors.NewRepositoryError(err)
 	}
 
+	return email.String, nil
+}
+
+// ConfirmEmailChange applies the change of email of the token hash and
+// returns the user with the new email.
+func (ur *userRepository) ConfirmEmailChange(ctx context.Context, tokenHash string) (*models.User, error) {
+	var user models.User
+	var changeId pgtype.Numeric
+
 	tx, err := ur.db.BeginTx(ctx, pgx.TxOptions{})
 
 	if err != nil {
-		return "", fail(err)
+		return nil, fail(err)
 	}
 
 	defer tx.Rollback(ctx)
 
-	if _, err := tx.Exec(ctx, querys.UpdateTokenConfirmedQuery, tokenId); err != nil {
-		return "", fail(err)
+	row := tx.QueryRow(ctx, querys.GetEmailChangeByTokenQuery, tokenHash, ur.tokenTTL.EmailChange.Seconds())
+
+	if err := row.Scan(&changeId, &user.Id, &user.Email); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrEmailChangeNotFound
+		}
+		return nil, fail(err)
 	}
 
-	if _, err := tx.Exec(ctx, querys.UpdatePasswordQuery, pass, userId); err != nil {
-		return "", fail(err)
+	if _, err := tx.Exec(ctx, querys.UpdateUserEmailQuery, user.Email, user.Id); err != nil {
+		if strings.Contains(err.Error(), "violates unique constraint") {
+			return nil, ErrDuplicateEmail
+		}
+		return nil, fail(err)
+	}
+
+	if _, err := tx.Exec(ctx, querys.UpdateEmailChangeConfirmedQuery, changeId); err != nil {
+		return nil, fail(err)
 	}
 
 	if err = tx.Commit(ctx); err != nil {
-		return "", fail(err)
+		return nil, fail(err)
 	}
 
-	return email.String, nil
+	return &user, nil
 }
@@ -0,0 +1,329 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go_pro/internal/apperrors"
+	"go_pro/internal/database/querys"
+	"go_pro/internal/models"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrWorkspaceNotFound = apperrors.NewRepositoryError(errors.New("workspace not found"))
+var ErrWorkspaceMemberNotFound = apperrors.NewRepositoryError(errors.New("workspace member not found"))
+var ErrAlreadyWorkspaceMember = apperrors.NewRepositoryError(errors.New("already a member of the workspace"))
+var ErrInvitationEmailMismatch = apperrors.NewRepositoryError(errors.New("invitation sent to another email"))
+var ErrOwnsWorkspace = apperrors.NewRepositoryError(errors.New("user owns a workspace"))
+
+type WorkspaceRepository interface {
+	ListForUser(ctx context.Context, userId int) ([]models.Workspace, error)
+	Get(ctx context.Context, userId, workspaceId int) (*models.Workspace, error)
+	Create(ctx context.Context, userId int, name string) (*models.Workspace, error)
+	Delete(ctx context.Context, workspaceId int) error
+	ListMembers(ctx context.Context, workspaceId int) ([]models.WorkspaceMember, error)
+	SetMemberRole(ctx context.Context, workspaceId, userId int, role string) error
+	RemoveMember(ctx context.Context, workspaceId, userId int) error
+	CreateInvitation(ctx context.Context, workspaceId, invitedBy int, email, role, tokenHash string) error
+	ListInvitations(ctx context.Context, workspaceId int) ([]models.WorkspaceInvitation, error)
+	FindInvitation(ctx context.Context, tokenHash string) (*models.WorkspaceInvitation, error)
+	RevokeInvitation(ctx context.Context, workspaceId, id int) error
+	AcceptInvitation(ctx context.Context, tokenHash string, userId int, email string) (int, error)
+}
+
+type workspaceRepository struct {
+	db            *pgxpool.Pool
+	invitationTTL time.Duration
+}
+
+func NewWorkspaceRepository(db *pgxpool.Pool, invitationTTL time.Duration) WorkspaceRepository {
+	return &workspaceRepository{
+		db:            db,
+		invitationTTL: invitationTTL,
+	}
+}
+
+func (wr *workspaceRepository) ListForUser(ctx context.Context, userId int) ([]models.Workspace, error) {
+	var list []models.Workspace
+
+	rows, err := wr.db.Query(ctx, querys.ListUserWorkspacesQuery, userId)
+
+	if err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.Workspace
+
+		if err = rows.Scan(&row.Id, &row.Name, &row.Role, &row.CreatedAt); err != nil {
+			return nil, apperrors.NewRepositoryError(err)
+		}
+
+		list = append(list, row)
+	}
+
+	return list, nil
+}
+
+// Get returns a workspace the user is a member of, with their role in it.
+func (wr *workspaceRepository) Get(ctx context.Context, userId, workspaceId int) (*models.Workspace, error) {
+	var workspace models.Workspace
+
+	row := wr.db.QueryRow(ctx, querys.GetUserWorkspaceQuery, userId, workspaceId)
+
+	if err := row.Scan(&workspace.Id, &workspace.Name, &workspace.Role, &workspace.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrWorkspaceNotFound
+		}
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	return &workspace, nil
+}
+
+// Create adds a workspace owned by the user.
+func (wr *workspaceRepository) Create(ctx context.Context, userId int, name string) (*models.Workspace, error) {
+	var workspace models.Workspace
+
+	tx, err := wr.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, querys.CreateWorkspaceQuery, name).Scan(&workspace.Id, &workspace.CreatedAt); err != nil {
+		return nil, fail(err)
+	}
+
+	if _, err := tx.Exec(ctx, querys.CreateWorkspaceMemberQuery, workspace.Id, userId, models.WorkspaceRoleOwner); err != nil {
+		return nil, fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fail(err)
+	}
+
+	workspace.Name.String, workspace.Name.Valid = name, true
+	workspace.Role.String, workspace.Role.Valid = models.WorkspaceRoleOwner, true
+
+	return &workspace, nil
+}
+
+// Delete removes the workspace along with its notes, members and invitations.
+func (wr *workspaceRepository) Delete(ctx context.Context, workspaceId int) error {
+	if _, err := wr.db.Exec(ctx, querys.DeleteWorkspaceQuery, workspaceId); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+func (wr *workspaceRepository) ListMembers(ctx context.Context, workspaceId int) ([]models.WorkspaceMember, error) {
+	var list []models.WorkspaceMember
+
+	rows, err := wr.db.Query(ctx, querys.ListWorkspaceMembersQuery, workspaceId)
+
+	if err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.WorkspaceMember
+
+		if err = rows.Scan(&row.UserId, &row.Email, &row.Name, &row.Role, &row.CreatedAt); err != nil {
+			return nil, apperrors.NewRepositoryError(err)
+		}
+
+		list = append(list, row)
+	}
+
+	return list, nil
+}
+
+// SetMemberRole changes the role of a member. The owner keeps their role.
+func (wr *workspaceRepository) SetMemberRole(ctx context.Context, workspaceId, userId int, role string) error {
+	tag, err := wr.db.Exec(ctx, querys.UpdateWorkspaceMemberRoleQuery, workspaceId, userId, role)
+
+	if err != nil {
+		return fail(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrWorkspaceMemberNotFound
+	}
+
+	return nil
+}
+
+// RemoveMember takes a member out of the workspace. The notes they created
+// stay in it. The owner can not be removed.
+func (wr *workspaceRepository) RemoveMember(ctx context.Context, workspaceId, userId int) error {
+	tag, err := wr.db.Exec(ctx, querys.DeleteWorkspaceMemberQuery, workspaceId, userId)
+
+	if err != nil {
+		return fail(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrWorkspaceMemberNotFound
+	}
+
+	return nil
+}
+
+// CreateInvitation saves the hash of an invitation to join the workspace with
+// role. The previous invitations sent to the same email stop working.
+func (wr *workspaceRepository) CreateInvitation(ctx context.Context, workspaceId, invitedBy int, email, role, tokenHash string) error {
+	tx, err := wr.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	var member bool
+
+	if err := tx.QueryRow(ctx, querys.IsWorkspaceEmailMemberQuery, workspaceId, email).Scan(&member); err != nil {
+		return fail(err)
+	}
+
+	if member {
+		return ErrAlreadyWorkspaceMember
+	}
+
+	if _, err := tx.Exec(ctx, querys.DeletePendingWorkspaceInvitationsQuery, workspaceId, email); err != nil {
+		return fail(err)
+	}
+
+	if _, err := tx.Exec(ctx, querys.CreateWorkspaceInvitationQuery, invitedBy, tokenHash, wr.invitationTTL.Seconds(),
+		workspaceId, email, role); err != nil {
+		return fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// ListInvitations returns the invitations of the workspace that were neither
+// accepted nor expired.
+func (wr *workspaceRepository) ListInvitations(ctx context.Context, workspaceId int) ([]models.WorkspaceInvitation, error) {
+	var list []models.WorkspaceInvitation
+
+	rows, err := wr.db.Query(ctx, querys.ListWorkspaceInvitationsQuery, workspaceId)
+
+	if err != nil {
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.WorkspaceInvitation
+
+		if err = rows.Scan(&row.Id, &row.WorkspaceId, &row.WorkspaceName, &row.Email, &row.Role,
+			&row.InvitedBy, &row.ExpiresAt); err != nil {
+			return nil, apperrors.NewRepositoryError(err)
+		}
+
+		list = append(list, row)
+	}
+
+	return list, nil
+}
+
+func (wr *workspaceRepository) FindInvitation(ctx context.Context, tokenHash string) (*models.WorkspaceInvitation, error) {
+	var invitation models.WorkspaceInvitation
+
+	row := wr.db.QueryRow(ctx, querys.GetWorkspaceInvitationQuery, tokenHash)
+
+	if err := row.Scan(&invitation.Id, &invitation.WorkspaceId, &invitation.WorkspaceName, &invitation.Email,
+		&invitation.Role, &invitation.InvitedBy, &invitation.ExpiresAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrInvalidToken
+		}
+		return nil, apperrors.NewRepositoryError(err)
+	}
+
+	return &invitation, nil
+}
+
+func (wr *workspaceRepository) RevokeInvitation(ctx context.Context, workspaceId, id int) error {
+	tag, err := wr.db.Exec(ctx, querys.DeleteWorkspaceInvitationQuery, workspaceId, id)
+
+	if err != nil {
+		return fail(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+// AcceptInvitation adds the user to the workspace of the invitation and
+// returns its id. Only the user signed in with the invited email can accept
+// it, and only once.
+func (wr *workspaceRepository) AcceptInvitation(ctx context.Context, tokenHash string, userId int, email string) (int, error) {
+	tx, err := wr.db.BeginTx(ctx, pgx.TxOptions{})
+
+	if err != nil {
+		return 0, fail(err)
+	}
+
+	defer tx.Rollback(ctx)
+
+	if _, err := useToken(ctx, tx, models.TokenPurposeWorkspaceInvitation, tokenHash); err != nil {
+		return 0, err
+	}
+
+	var invitation models.WorkspaceInvitation
+
+	if err := tx.QueryRow(ctx, querys.GetUsedWorkspaceInvitationQuery, tokenHash).Scan(&invitation.WorkspaceId,
+		&invitation.Email, &invitation.Role); err != nil {
+		return 0, fail(err)
+	}
+
+	if !strings.EqualFold(invitation.Email.String, email) {
+		return 0, ErrInvitationEmailMismatch
+	}
+
+	if _, err := tx.Exec(ctx, querys.CreateWorkspaceMemberQuery, invitation.WorkspaceId, userId, invitation.Role); err != nil {
+		return 0, fail(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fail(err)
+	}
+
+	return int(invitation.WorkspaceId.Int.Int64()), nil
+}
+
+// checkNoOwnedWorkspace returns ErrOwnsWorkspace when the user owns a
+// workspace. Deleting them would leave it without anyone to manage it.
+func checkNoOwnedWorkspace(ctx context.Context, tx pgx.Tx, userId int) error {
+	var owner bool
+
+	if err := tx.QueryRow(ctx, querys.OwnsWorkspaceQuery, userId).Scan(&owner); err != nil {
+		return err
+	}
+
+	if owner {
+		return ErrOwnsWorkspace
+	}
+
+	return nil
+}
@@ -2,8 +2,12 @@ package router
 
 import (
 	"go_pro/assets"
+	"go_pro/config"
 	"go_pro/internal/handlers"
 	"go_pro/internal/mailers"
+	"go_pro/internal/models"
+	"go_pro/internal/oidc"
+	"go_pro/internal/passwords"
 	"go_pro/internal/render"
 	"go_pro/internal/repositories"
 	"io/fs"
@@ -14,7 +18,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func LoadRoutes(sessionManager *scs.SessionManager, db *pgxpool.Pool, noteRepo repositories.NoteRepository, userRepo repositories.UserRepository, mail mailers.MailService) http.Handler {
+func LoadRoutes(sessionManager *scs.SessionManager, db *pgxpool.Pool, noteRepo repositories.NoteRepository, userRepo repositories.UserRepository, commentRepo repositories.CommentRepository, dashboardRepo repositories.DashboardRepository, calendarRepo repositories.CalendarRepository, quotaRepo repositories.QuotaRepository, sessionRepo repositories.SessionRepository, accountRepo repositories.AccountRepository, twoFactorRepo repositories.TwoFactorRepository, accessTokenRepo repositories.AccessTokenRepository, loginSecurityRepo repositories.LoginSecurityRepository, rateLimitRepo repositories.RateLimitRepository, adminRepo repositories.AdminRepository, profileRepo repositories.ProfileRepository, workspaceRepo repositories.WorkspaceRepository, passwordChecker *passwords.Checker, passwordHasher *passwords.Hasher, mail mailers.MailService, cfg config.Config) http.Handler {
 	static, err := fs.Sub(assets.Files, ".")
 
 	if err != nil {
@@ -23,37 +27,147 @@ func LoadRoutes(sessionManager *scs.SessionManager, db *pgxpool.Pool, noteRepo r
 	}
 
 	mux := http.NewServeMux()
-	render := render.NewRender(sessionManager)
+	render := render.NewRender(sessionManager, cfg.GetBaseURL())
 	staticHandler := http.FileServerFS(static)
-	noteHandlers := handlers.NewNoteHandler(render, sessionManager, noteRepo)
-	userHandlers := handlers.NewUserHandler(render, sessionManager, userRepo, mail)
-	authMidd := handlers.NewAuthMiddleware(sessionManager)
+	sso := loadSSOProvider(db, cfg)
+	noteHandlers := handlers.NewNoteHandler(render, sessionManager, noteRepo, commentRepo, quotaRepo, passwordChecker, passwordHasher, cfg.GetNoteUnlockWindow(), cfg.GetNoteSessionKey())
+	commentHandlers := handlers.NewCommentHandler(render, sessionManager, noteRepo, commentRepo, mail)
+	userHandlers := handlers.NewUserHandler(render, sessionManager, userRepo, sessionRepo, accountRepo, twoFactorRepo, loginSecurityRepo, sso, mail, passwordChecker, passwordHasher, cfg.GetLockoutPolicy(), cfg.GetMagicLinkPolicy(), cfg.GetSessionPolicy(), cfg.IsProxyTrusted())
+	dashboardHandlers := handlers.NewDashboardHandler(render, sessionManager, dashboardRepo)
+	calendarHandlers := handlers.NewCalendarHandler(calendarRepo, noteRepo, cfg.GetBaseURL())
+	accountHandlers := handlers.NewAccountHandler(render, sessionManager, userRepo, accountRepo, twoFactorRepo, accessTokenRepo, loginSecurityRepo, sessionRepo, profileRepo, mail, passwordChecker, passwordHasher, sso, cfg.GetUserTokenTTL().EmailChange, cfg.GetAccountDeletionGrace())
+	integrationsHandlers := handlers.NewIntegrationsHandler(render, sessionManager, calendarRepo, userRepo, cfg.GetInboxDomain(), cfg.GetBaseURL())
+	adminHandlers := handlers.NewAdminHandler(render, sessionManager, adminRepo, userRepo, mail)
+	workspaceHandlers := handlers.NewWorkspaceHandler(render, sessionManager, workspaceRepo, mail, cfg.GetUserTokenTTL().WorkspaceInvitation)
+	authMidd := handlers.NewAuthMiddleware(sessionManager, accessTokenRepo, sessionRepo, userRepo, cfg.IsProxyTrusted(), cfg.GetSessionPolicy().IdleTimeout)
 	errorMidd := handlers.NewErrorHandlerMiddleware(render)
+	rateLimit := handlers.NewRateLimitMiddleware(render, rateLimitRepo, cfg.IsProxyTrusted())
+	signinLimit := rateLimit.Limit("signin", cfg.GetSigninRateLimit())
+	signupLimit := rateLimit.Limit("signup", cfg.GetSignupRateLimit())
+	forgetPasswordLimit := rateLimit.Limit("forgetpassword", cfg.GetForgetPasswordRateLimit())
+	resendConfirmationLimit := rateLimit.Limit("resendconfirmation", cfg.GetResendConfirmationRateLimit())
+	magicLinkLimit := rateLimit.Limit("magiclink", cfg.GetMagicLinkRateLimit())
+	twoFactorLimit := rateLimit.LimitBy("twofactor", cfg.GetTwoFactorRateLimit(), handlers.PendingUserAccount(sessionManager))
+	linkSigninLimit := rateLimit.Limit("linksignin", cfg.GetLinkSigninRateLimit())
+	oidcCallbackLimit := rateLimit.Limit("oidccallback", cfg.GetOIDCCallbackRateLimit())
+	noteUnlockLimit := rateLimit.LimitBy("noteunlock", cfg.GetNoteUnlockRateLimit(), handlers.NoteUnlockAccount(sessionManager))
+	workspaceInviteLimit := rateLimit.Limit("workspaceinvite", cfg.GetWorkspaceInviteRateLimit())
+	requireAdmin := authMidd.RequireRole(models.RoleAdmin)
+	pageData := handlers.NewPageDataMiddleware(sessionManager, quotaRepo, profileRepo, workspaceRepo)
 
 	mux.Handle("GET /assets/", http.StripPrefix("/assets/", staticHandler))
 
 	mux.HandleFunc("GET /", handlers.NewHomeHandler(render).HomeHandler)
 
-	mux.Handle("GET /notes", authMidd.RequireAuth(errorMidd.HandlerError(noteHandlers.NoteList)))
-	mux.Handle("GET /notes/{id}", authMidd.RequireAuth(errorMidd.HandlerError(noteHandlers.NoteView)))
-	mux.Handle("GET /notes/new", authMidd.RequireAuth(errorMidd.HandlerError(noteHandlers.NoteNew)))
-	mux.Handle("POST /notes", authMidd.RequireAuth(errorMidd.HandlerError(noteHandlers.NoteSave)))
-	mux.Handle("DELETE /notes/{id}", authMidd.RequireAuth(errorMidd.HandlerError(noteHandlers.NoteDelete)))
-	mux.Handle("GET /notes/{id}/update", authMidd.RequireAuth(errorMidd.HandlerError(noteHandlers.NoteEdit)))
+	mux.Handle("GET /notes", authMidd.RequireAuthOrToken(errorMidd.HandlerError(noteHandlers.NoteList)))
+	mux.Handle("GET /notes/{id}", authMidd.RequireAuthOrToken(errorMidd.HandlerError(noteHandlers.NoteView)))
+	mux.Handle("GET /notes/new", authMidd.RequireAuthOrToken(errorMidd.HandlerError(noteHandlers.NoteNew)))
+	mux.Handle("POST /notes", authMidd.RequireAuthOrToken(errorMidd.HandlerError(noteHandlers.NoteSave)))
+	mux.Handle("DELETE /notes/{id}", authMidd.RequireAuthOrToken(errorMidd.HandlerError(noteHandlers.NoteDelete)))
+	mux.Handle("GET /notes/{id}/update", authMidd.RequireAuthOrToken(errorMidd.HandlerError(noteHandlers.NoteEdit)))
+	mux.Handle("POST /notes/{id}/move", authMidd.RequireAuthOrToken(errorMidd.HandlerError(noteHandlers.NoteMove)))
+	mux.Handle("POST /notes/{id}/unlock", authMidd.RequireAuth(noteUnlockLimit(errorMidd.HandlerError(noteHandlers.NoteUnlock))))
+	mux.Handle("POST /notes/{id}/relock", authMidd.RequireAuth(errorMidd.HandlerError(noteHandlers.NoteRelock)))
+	mux.Handle("POST /notes/{id}/lock", authMidd.RequireAuth(errorMidd.HandlerError(noteHandlers.NoteLock)))
+	mux.Handle("POST /notes/{id}/lock/remove", authMidd.RequireAuth(errorMidd.HandlerError(noteHandlers.NoteRemoveLock)))
+
+	mux.Handle("POST /notes/{id}/comments", authMidd.RequireAuthOrToken(errorMidd.HandlerError(commentHandlers.CommentCreate)))
+	mux.Handle("POST /notes/{id}/comments/{commentId}", authMidd.RequireAuthOrToken(errorMidd.HandlerError(commentHandlers.CommentUpdate)))
+	mux.Handle("DELETE /notes/{id}/comments/{commentId}", authMidd.RequireAuthOrToken(errorMidd.HandlerError(commentHandlers.CommentDelete)))
+
+	mux.Handle("GET /dashboard", authMidd.RequireAuthOrToken(errorMidd.HandlerError(dashboardHandlers.Dashboard)))
+
+	mux.Handle("GET /integrations", authMidd.RequireAuth(errorMidd.HandlerError(integrationsHandlers.Integrations)))
+	mux.Handle("POST /integrations/calendar", authMidd.RequireAuth(errorMidd.HandlerError(integrationsHandlers.RegenerateCalendarToken)))
+	mux.Handle("POST /integrations/calendar/revoke", authMidd.RequireAuth(errorMidd.HandlerError(integrationsHandlers.RevokeCalendarToken)))
+	mux.Handle("POST /integrations/inbox", authMidd.RequireAuth(errorMidd.HandlerError(integrationsHandlers.RegenerateInboxToken)))
+	mux.Handle("POST /integrations/inbox/revoke", authMidd.RequireAuth(errorMidd.HandlerError(integrationsHandlers.RevokeInboxToken)))
+
+	mux.Handle("GET /workspaces", authMidd.RequireAuth(errorMidd.HandlerError(workspaceHandlers.Workspaces)))
+	mux.Handle("POST /workspaces", authMidd.RequireAuth(errorMidd.HandlerError(workspaceHandlers.WorkspaceCreate)))
+	mux.Handle("POST /workspaces/switch", authMidd.RequireAuth(errorMidd.HandlerError(workspaceHandlers.WorkspaceSwitch)))
+	mux.Handle("GET /workspaces/{id}", authMidd.RequireAuth(errorMidd.HandlerError(workspaceHandlers.WorkspaceView)))
+	mux.Handle("POST /workspaces/{id}/invitations", authMidd.RequireAuth(workspaceInviteLimit(errorMidd.HandlerError(workspaceHandlers.WorkspaceInvite))))
+	mux.Handle("POST /workspaces/{id}/invitations/{invitationId}/revoke", authMidd.RequireAuth(errorMidd.HandlerError(workspaceHandlers.WorkspaceRevokeInvitation)))
+	mux.Handle("POST /workspaces/{id}/members/{userId}/role", authMidd.RequireAuth(errorMidd.HandlerError(workspaceHandlers.WorkspaceMemberRole)))
+	mux.Handle("POST /workspaces/{id}/members/{userId}/remove", authMidd.RequireAuth(errorMidd.HandlerError(workspaceHandlers.WorkspaceMemberRemove)))
+	mux.Handle("POST /workspaces/{id}/delete", authMidd.RequireAuth(errorMidd.HandlerError(workspaceHandlers.WorkspaceDelete)))
+	mux.Handle("GET /workspace-invitations/{token}", errorMidd.HandlerError(workspaceHandlers.InvitationView))
+	mux.Handle("POST /workspace-invitations/{token}", authMidd.RequireAuth(errorMidd.HandlerError(workspaceHandlers.InvitationAccept)))
+
+	mux.Handle("GET /cal/{file}", errorMidd.HandlerError(calendarHandlers.Feed))
 
 	mux.Handle("GET /user/signup", errorMidd.HandlerError(userHandlers.SignupForm))
-	mux.Handle("POST /user/signup", errorMidd.HandlerError(userHandlers.Signup))
+	mux.Handle("POST /user/signup", signupLimit(errorMidd.HandlerError(userHandlers.Signup)))
 	mux.Handle("GET /user/signin", errorMidd.HandlerError(userHandlers.SigninForm))
-	mux.Handle("POST /user/signin", errorMidd.HandlerError(userHandlers.Signin))
+	mux.Handle("POST /user/signin", signinLimit(errorMidd.HandlerError(userHandlers.Signin)))
+	mux.Handle("GET /user/signin/2fa", errorMidd.HandlerError(userHandlers.TwoFactorForm))
+	mux.Handle("POST /user/signin/2fa", twoFactorLimit(errorMidd.HandlerError(userHandlers.TwoFactor)))
+	mux.Handle("POST /user/signin/link", magicLinkLimit(errorMidd.HandlerError(userHandlers.SendMagicLink)))
+	mux.Handle("GET /user/signin/link/{token}", errorMidd.HandlerError(userHandlers.MagicLinkForm))
+	mux.Handle("POST /user/signin/link/{token}", linkSigninLimit(errorMidd.HandlerError(userHandlers.MagicLinkSignin)))
+	mux.Handle("GET /user/oidc/login", errorMidd.HandlerError(userHandlers.OIDCLogin))
+	mux.Handle("GET /user/oidc/callback", oidcCallbackLimit(errorMidd.HandlerError(userHandlers.OIDCCallback)))
 	mux.Handle("GET /user/signout", errorMidd.HandlerError(userHandlers.Signout))
+	mux.Handle("GET /user/unlock/{token}", errorMidd.HandlerError(userHandlers.Unlock))
 	mux.Handle("GET /user/forgetpassword", errorMidd.HandlerError(userHandlers.ForgetPasswordForm))
-	mux.Handle("POST /user/forgetpassword", errorMidd.HandlerError(userHandlers.ForgetPassword))
+	mux.Handle("POST /user/forgetpassword", forgetPasswordLimit(errorMidd.HandlerError(userHandlers.ForgetPassword)))
 	mux.Handle("POST /user/password", errorMidd.HandlerError(userHandlers.ResetPassword))
 	mux.Handle("GET /user/password/{token}", errorMidd.HandlerError(userHandlers.ResetPasswordForm))
 
+	mux.Handle("GET /account", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.Account)))
+	mux.Handle("POST /account/email", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.ChangeEmail)))
+	mux.Handle("POST /account/password", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.ChangePassword)))
+	mux.Handle("GET /account/profile", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.Profile)))
+	mux.Handle("POST /account/profile", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.UpdateProfile)))
+	mux.Handle("POST /account/avatar", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.UploadAvatar)))
+	mux.Handle("POST /account/avatar/delete", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.DeleteAvatar)))
+	mux.Handle("GET /account/avatar/{size}", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.Avatar)))
+	mux.Handle("GET /account/security", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.Security)))
+	mux.Handle("GET /account/sessions", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.Sessions)))
+	mux.Handle("POST /account/sessions/{id}/revoke", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.RevokeSession)))
+	mux.Handle("POST /account/sessions/revoke-others", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.RevokeOtherSessions)))
+	mux.Handle("GET /account/2fa", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.TwoFactorSetup)))
+	mux.Handle("POST /account/2fa", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.TwoFactorEnable)))
+	mux.Handle("POST /account/2fa/recovery-codes", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.TwoFactorRecoveryCodes)))
+	mux.Handle("POST /account/2fa/disable", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.TwoFactorDisable)))
+	mux.Handle("POST /account/tokens", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.CreateAccessToken)))
+	mux.Handle("POST /account/tokens/{id}/revoke", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.RevokeAccessToken)))
+	mux.Handle("POST /account/reauth", authMidd.RequireAuth(errorMidd.HandlerError(userHandlers.OIDCReauth)))
+	mux.Handle("POST /account/delete", authMidd.RequireAuth(errorMidd.HandlerError(accountHandlers.DeleteAccount)))
+	mux.Handle("GET /account/export/{token}", errorMidd.HandlerError(accountHandlers.Export))
+	mux.Handle("GET /account/email/{token}", errorMidd.HandlerError(accountHandlers.ConfirmEmail))
+
+	mux.Handle("GET /admin/users", requireAdmin(errorMidd.HandlerError(adminHandlers.Users)))
+	mux.Handle("POST /admin/users/{id}/disable", requireAdmin(errorMidd.HandlerError(adminHandlers.DisableUser)))
+	mux.Handle("POST /admin/users/{id}/enable", requireAdmin(errorMidd.HandlerError(adminHandlers.EnableUser)))
+	mux.Handle("POST /admin/users/{id}/confirm", requireAdmin(errorMidd.HandlerError(adminHandlers.ConfirmUser)))
+	mux.Handle("POST /admin/users/{id}/password-reset", requireAdmin(errorMidd.HandlerError(adminHandlers.ResetUserPassword)))
+	mux.Handle("POST /admin/users/{id}/delete", requireAdmin(errorMidd.HandlerError(adminHandlers.DeleteUser)))
+	mux.Handle("GET /admin/audit", requireAdmin(errorMidd.HandlerError(adminHandlers.Audit)))
+
 	mux.Handle("GET /confirmation/{token}", errorMidd.HandlerError(userHandlers.Confirm))
+	mux.Handle("GET /user/confirmation/resend", errorMidd.HandlerError(userHandlers.ResendConfirmationForm))
+	mux.Handle("POST /user/confirmation/resend", resendConfirmationLimit(errorMidd.HandlerError(userHandlers.ResendConfirmation)))
 
 	mux.Handle("GET /me", errorMidd.HandlerError(userHandlers.Me))
 
-	return mux
+	return pageData.Load(mux)
+}
+
+func loadSSOProvider(db *pgxpool.Pool, cfg config.Config) *handlers.SSOProvider {
+	if !cfg.IsOIDCEnabled() {
+		return nil
+	}
+
+	return &handlers.SSOProvider{
+		Name: cfg.OIDCProviderName,
+		Provider: oidc.NewProvider(oidc.Config{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+		}),
+		Identities: repositories.NewIdentityRepository(db),
+	}
 }
@@ -0,0 +1 @@
+drop index if exists notes_user_id_created_at_idx;
\ No newline at end of file
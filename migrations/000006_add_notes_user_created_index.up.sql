@@ -0,0 +1 @@
+create index if not exists notes_user_id_created_at_idx on notes (user_id, created_at);
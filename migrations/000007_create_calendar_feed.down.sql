@@ -0,0 +1,4 @@
+drop table if exists calendar_tokens;
+
+alter table notes
+drop column if exists due_at;
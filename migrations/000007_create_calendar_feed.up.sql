@@ -0,0 +1,8 @@
+alter table notes
+add column due_at timestamp;
+
+create table if not exists calendar_tokens (
+    user_id bigint primary key references users(id) on delete cascade,
+    token text not null unique,
+    created_at timestamp default current_timestamp
+);
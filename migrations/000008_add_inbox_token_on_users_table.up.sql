@@ -0,0 +1,2 @@
+alter table users
+add column inbox_token text unique;
@@ -0,0 +1,3 @@
+drop index if exists note_comments_note_id_idx;
+
+drop table if exists note_comments;
@@ -0,0 +1,10 @@
+create table if not exists note_comments (
+    id bigserial primary key,
+    note_id bigint not null references notes(id) on delete cascade,
+    user_id bigint not null references users(id) on delete cascade,
+    body text not null,
+    created_at timestamp default current_timestamp,
+    updated_at timestamp
+);
+
+create index note_comments_note_id_idx on note_comments (note_id, created_at);
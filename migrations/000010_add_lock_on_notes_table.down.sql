@@ -0,0 +1,4 @@
+alter table notes
+drop column if exists lock_salt,
+drop column if exists lock_password,
+drop column if exists locked;
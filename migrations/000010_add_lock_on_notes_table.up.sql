@@ -0,0 +1,4 @@
+alter table notes
+add column locked boolean not null default false,
+add column lock_password text,
+add column lock_salt bytea;
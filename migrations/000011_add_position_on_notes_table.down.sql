@@ -0,0 +1,4 @@
+drop index if exists notes_user_id_position_idx;
+
+alter table notes
+drop column if exists position;
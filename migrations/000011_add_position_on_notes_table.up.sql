@@ -0,0 +1,13 @@
+alter table notes
+add column position text collate "C";
+
+update notes n set position = lpad(o.rank::text, 10, '0') || 'V'
+from (
+    select id, row_number() over (partition by user_id order by created_at, id) as rank from notes
+) o
+where n.id = o.id;
+
+alter table notes
+alter column position set not null;
+
+create index notes_user_id_position_idx on notes (user_id, position);
@@ -0,0 +1,8 @@
+create table if not exists user_quotas (
+    user_id bigint primary key references users(id) on delete cascade,
+    max_notes integer,
+    max_note_size integer,
+    max_storage bigint,
+    created_at timestamp default current_timestamp,
+    updated_at timestamp
+);
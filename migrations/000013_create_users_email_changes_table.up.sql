@@ -0,0 +1,11 @@
+create table if not exists users_email_changes (
+    id bigserial primary key,
+    user_id bigint not null references users(id) on delete cascade,
+    new_email text not null,
+    token text not null unique,
+    confirmed boolean not null default false,
+    created_at timestamp default current_timestamp,
+    updated_at timestamp
+);
+
+create index users_email_changes_user_id_idx on users_email_changes (user_id);
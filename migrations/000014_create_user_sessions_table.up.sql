@@ -0,0 +1,7 @@
+create table if not exists user_sessions (
+    token text primary key,
+    user_id bigint not null references users(id) on delete cascade,
+    created_at timestamp default current_timestamp
+);
+
+create index user_sessions_user_id_idx on user_sessions (user_id);
@@ -0,0 +1,4 @@
+drop table if exists account_exports;
+
+alter table users
+drop column if exists deletion_requested_at;
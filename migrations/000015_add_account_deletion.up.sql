@@ -0,0 +1,11 @@
+alter table users
+add column deletion_requested_at timestamp;
+
+create table if not exists account_exports (
+    id bigserial primary key,
+    user_id bigint not null references users(id) on delete cascade,
+    token text not null unique,
+    data jsonb not null,
+    created_at timestamp default current_timestamp,
+    expires_at timestamp not null
+);
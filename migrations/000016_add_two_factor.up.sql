@@ -0,0 +1,14 @@
+alter table users
+add column totp_secret text,
+add column totp_enabled boolean not null default false,
+add column totp_last_step bigint not null default 0;
+
+create table if not exists user_recovery_codes (
+    id bigserial primary key,
+    user_id bigint not null references users(id) on delete cascade,
+    code_hash text not null,
+    used_at timestamp,
+    created_at timestamp default current_timestamp
+);
+
+create unique index user_recovery_codes_user_id_code_hash_idx on user_recovery_codes (user_id, code_hash);
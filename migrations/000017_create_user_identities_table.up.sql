@@ -0,0 +1,12 @@
+create table if not exists user_identities (
+    id bigserial primary key,
+    user_id bigint not null references users(id) on delete cascade,
+    issuer text not null,
+    subject text not null,
+    email text,
+    created_at timestamp default current_timestamp,
+    updated_at timestamp,
+    unique (issuer, subject)
+);
+
+create index user_identities_user_id_idx on user_identities (user_id);
@@ -0,0 +1,12 @@
+create table if not exists personal_access_tokens (
+    id bigserial primary key,
+    user_id bigint not null references users(id) on delete cascade,
+    name text not null,
+    token_hash text not null unique,
+    scope text not null check (scope in ('read', 'write')),
+    expires_at timestamp,
+    last_used_at timestamp,
+    created_at timestamp default current_timestamp
+);
+
+create index personal_access_tokens_user_id_idx on personal_access_tokens (user_id);
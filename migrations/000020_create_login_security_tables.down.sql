@@ -0,0 +1,3 @@
+drop table if exists user_devices;
+drop table if exists user_failed_logins;
+drop table if exists user_lockouts;
@@ -0,0 +1,29 @@
+create table if not exists user_lockouts (
+    user_id bigint primary key references users(id) on delete cascade,
+    failed_attempts int not null default 0,
+    lockout_count int not null default 0,
+    locked_until timestamp,
+    unlock_token_hash text unique,
+    updated_at timestamp default current_timestamp
+);
+
+create table if not exists user_failed_logins (
+    id bigserial primary key,
+    user_id bigint not null references users(id) on delete cascade,
+    ip text not null,
+    user_agent text not null,
+    created_at timestamp default current_timestamp
+);
+
+create index user_failed_logins_user_id_idx on user_failed_logins (user_id, created_at);
+
+create table if not exists user_devices (
+    id bigserial primary key,
+    user_id bigint not null references users(id) on delete cascade,
+    device_hash text not null,
+    ip text not null,
+    user_agent text not null,
+    created_at timestamp default current_timestamp,
+    last_seen_at timestamp default current_timestamp,
+    unique (user_id, device_hash)
+);
@@ -0,0 +1,26 @@
+create table if not exists user_tokens (
+    id bigserial primary key,
+    user_id bigint not null references users(id) on delete cascade,
+    purpose text not null check (purpose in ('confirmation', 'password_reset')),
+    token_hash text not null unique,
+    expires_at timestamp not null,
+    used_at timestamp,
+    created_at timestamp default current_timestamp
+);
+
+create index user_tokens_user_id_idx on user_tokens (user_id, purpose);
+
+-- The old table served both purposes: an unconfirmed token of an inactive
+-- user confirms the sign up, of an active user it resets the password.
+insert into user_tokens (user_id, purpose, token_hash, expires_at, used_at, created_at)
+select t.user_id,
+    case when u.active then 'password_reset' else 'confirmation' end,
+    encode(sha256(convert_to(t.token, 'UTF8')), 'hex'),
+    t.created_at + case when u.active then interval '4 hours' else interval '48 hours' end,
+    case when t.confirmed then coalesce(t.updated_at, t.created_at) end,
+    t.created_at
+from users_confirmation_tokens t
+inner join users u on u.id = t.user_id
+on conflict (token_hash) do nothing;
+
+drop table if exists users_confirmation_tokens;
@@ -0,0 +1,5 @@
+alter table user_sessions
+drop column if exists last_seen_at,
+drop column if exists user_agent,
+drop column if exists ip,
+drop column if exists id;
@@ -0,0 +1,5 @@
+alter table user_sessions
+add column if not exists id bigserial unique,
+add column if not exists ip text not null default '',
+add column if not exists user_agent text not null default '',
+add column if not exists last_seen_at timestamp default current_timestamp;
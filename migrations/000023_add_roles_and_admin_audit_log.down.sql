@@ -0,0 +1,6 @@
+drop table if exists admin_audit_log;
+
+alter table users
+drop column if exists last_login_at,
+drop column if exists disabled_at,
+drop column if exists role;
@@ -0,0 +1,18 @@
+-- Admins are promoted by hand:
+-- update users set role = 'admin' where email = '...';
+alter table users
+add column if not exists role text not null default 'user' check (role in ('user', 'admin')),
+add column if not exists disabled_at timestamp,
+add column if not exists last_login_at timestamp;
+
+create table if not exists admin_audit_log (
+    id bigserial primary key,
+    admin_id bigint references users(id) on delete set null,
+    admin_email text not null,
+    target_user_id bigint,
+    target_email text not null,
+    action text not null,
+    created_at timestamp default current_timestamp
+);
+
+create index admin_audit_log_created_at_idx on admin_audit_log (created_at);
@@ -0,0 +1,8 @@
+delete from user_tokens where purpose = 'magic_link';
+
+alter table user_tokens drop column if exists device_hash;
+
+alter table user_tokens drop constraint if exists user_tokens_purpose_check;
+
+alter table user_tokens
+add constraint user_tokens_purpose_check check (purpose in ('confirmation', 'password_reset'));
@@ -0,0 +1,8 @@
+alter table user_tokens drop constraint if exists user_tokens_purpose_check;
+
+alter table user_tokens
+add constraint user_tokens_purpose_check check (purpose in ('confirmation', 'password_reset', 'magic_link'));
+
+-- Hash of the device cookie of the browser that asked for a magic link, for
+-- when the link only works there.
+alter table user_tokens add column if not exists device_hash text;
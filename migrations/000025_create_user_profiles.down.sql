@@ -0,0 +1,2 @@
+drop table if exists user_avatars;
+drop table if exists user_profiles;
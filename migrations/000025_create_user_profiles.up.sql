@@ -0,0 +1,16 @@
+create table if not exists user_profiles (
+    user_id bigint primary key references users(id) on delete cascade,
+    display_name text,
+    locale text not null default 'pt-BR',
+    timezone text not null default 'America/Sao_Paulo',
+    updated_at timestamp default current_timestamp
+);
+
+-- The avatar is kept already resized, one image per size.
+create table if not exists user_avatars (
+    user_id bigint not null references users(id) on delete cascade,
+    size int not null,
+    image bytea not null,
+    created_at timestamp default current_timestamp,
+    primary key (user_id, size)
+);
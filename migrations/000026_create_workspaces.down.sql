@@ -0,0 +1,18 @@
+drop index if exists notes_workspace_id_position_idx;
+
+delete from notes where workspace_id is not null;
+
+alter table notes drop column if exists workspace_id;
+
+drop table if exists workspace_invitations;
+
+delete from user_tokens where purpose = 'workspace_invitation';
+
+alter table user_tokens drop constraint if exists user_tokens_purpose_check;
+
+alter table user_tokens
+add constraint user_tokens_purpose_check check (purpose in ('confirmation', 'password_reset', 'magic_link'));
+
+drop table if exists workspace_members;
+
+drop table if exists workspaces;
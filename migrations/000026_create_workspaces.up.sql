@@ -0,0 +1,35 @@
+create table if not exists workspaces (
+    id bigserial primary key,
+    name text not null,
+    created_at timestamp default current_timestamp
+);
+
+create table if not exists workspace_members (
+    workspace_id bigint not null references workspaces(id) on delete cascade,
+    user_id bigint not null references users(id) on delete cascade,
+    role text not null check (role in ('owner', 'admin', 'member')),
+    created_at timestamp default current_timestamp,
+    primary key (workspace_id, user_id)
+);
+
+create index workspace_members_user_id_idx on workspace_members (user_id);
+
+alter table user_tokens drop constraint if exists user_tokens_purpose_check;
+
+alter table user_tokens
+add constraint user_tokens_purpose_check check (purpose in ('confirmation', 'password_reset', 'magic_link', 'workspace_invitation'));
+
+-- The token of an invitation belongs to the member who sent it. The invited
+-- email may not have an account yet.
+create table if not exists workspace_invitations (
+    token_id bigint primary key references user_tokens(id) on delete cascade,
+    workspace_id bigint not null references workspaces(id) on delete cascade,
+    email text not null,
+    role text not null check (role in ('admin', 'member'))
+);
+
+-- Notes of a workspace keep the user that created them in user_id. Personal
+-- notes have no workspace.
+alter table notes add column if not exists workspace_id bigint references workspaces(id) on delete cascade;
+
+create index notes_workspace_id_position_idx on notes (workspace_id, position);
@@ -0,0 +1 @@
+alter table user_tokens drop column if exists password;
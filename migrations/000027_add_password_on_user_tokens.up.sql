@@ -0,0 +1,4 @@
+-- Password hash chosen at a repeated sign up of an unconfirmed email. It only
+-- replaces the stored password once its confirmation link is used, so only
+-- whoever proves the email picks the password.
+alter table user_tokens add column if not exists password text;
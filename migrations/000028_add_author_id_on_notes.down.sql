@@ -0,0 +1,9 @@
+alter table notes drop constraint if exists notes_owner_check;
+
+update notes set user_id = author_id where user_id is null;
+
+delete from notes where user_id is null;
+
+alter table notes alter column user_id set not null;
+
+alter table notes drop column if exists author_id;
@@ -0,0 +1,13 @@
+-- Notes of a workspace belong to the workspace, not to the member who wrote
+-- them: user_id is only set on personal notes, so deleting an account keeps
+-- its workspace notes, and author_id just loses its value.
+alter table notes add column if not exists author_id bigint references users(id) on delete set null;
+
+alter table notes alter column user_id drop not null;
+
+update notes set author_id = user_id;
+
+update notes set user_id = null where workspace_id is not null;
+
+alter table notes
+add constraint notes_owner_check check ((user_id is null) <> (workspace_id is null));
@@ -0,0 +1 @@
+alter table user_lockouts drop column if exists unlock_expires_at;
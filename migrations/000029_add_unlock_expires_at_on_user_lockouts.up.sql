@@ -0,0 +1,4 @@
+-- The unlock link sent with a lockout stops working after this time.
+alter table user_lockouts add column if not exists unlock_expires_at timestamp;
+
+update user_lockouts set unlock_token_hash = null where unlock_token_hash is not null;
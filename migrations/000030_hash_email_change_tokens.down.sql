@@ -0,0 +1,4 @@
+-- The tokens are only stored hashed, the pending links cannot be restored.
+delete from users_email_changes where confirmed = false;
+
+alter table users_email_changes rename column token_hash to token;
@@ -0,0 +1,5 @@
+-- The tokens are hashed the way tools.HashToken does, the pending links keep
+-- working.
+alter table users_email_changes rename column token to token_hash;
+
+update users_email_changes set token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
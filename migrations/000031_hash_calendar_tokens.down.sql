@@ -0,0 +1,5 @@
+-- The tokens are only stored hashed, the calendar addresses cannot be
+-- restored.
+delete from calendar_tokens;
+
+alter table calendar_tokens rename column token_hash to token;
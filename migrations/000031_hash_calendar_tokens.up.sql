@@ -0,0 +1,5 @@
+-- The tokens are hashed the way tools.HashToken does, the subscribed
+-- calendars keep working.
+alter table calendar_tokens rename column token to token_hash;
+
+update calendar_tokens set token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
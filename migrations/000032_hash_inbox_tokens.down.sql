@@ -0,0 +1,4 @@
+-- The tokens are only stored hashed, the inbox addresses cannot be restored.
+update users set inbox_token_hash = null;
+
+alter table users rename column inbox_token_hash to inbox_token;
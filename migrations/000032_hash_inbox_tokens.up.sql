@@ -0,0 +1,6 @@
+-- The tokens are hashed the way tools.HashToken does, the inbox addresses
+-- keep working.
+alter table users rename column inbox_token to inbox_token_hash;
+
+update users set inbox_token_hash = encode(sha256(convert_to(inbox_token_hash, 'UTF8')), 'hex')
+where inbox_token_hash is not null;
@@ -0,0 +1,4 @@
+-- The tokens are only stored hashed, the export links cannot be restored.
+delete from account_exports;
+
+alter table account_exports rename column token_hash to token;
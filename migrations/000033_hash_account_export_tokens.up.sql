@@ -0,0 +1,5 @@
+-- The tokens are hashed the way tools.HashToken does, the links already sent
+-- keep working.
+alter table account_exports rename column token to token_hash;
+
+update account_exports set token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
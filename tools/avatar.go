@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+)
+
+// maxAvatarPixels caps the size of an uploaded image before it is decoded, so
+// a small file cannot expand into a huge bitmap.
+const maxAvatarPixels = 4096 * 4096
+
+var ErrInvalidImage = errors.New("invalid image")
+
+// ResizeAvatar crops the uploaded image to the square in its center and
+// resizes it to each of sizes, returning PNG images keyed by their size.
+func ResizeAvatar(r io.Reader, sizes []int) (map[int][]byte, error) {
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+
+	if err != nil || config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxAvatarPixels {
+		return nil, ErrInvalidImage
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	square := image.Rect(0, 0, side, side).Add(image.Pt(
+		bounds.Min.X+(bounds.Dx()-side)/2,
+		bounds.Min.Y+(bounds.Dy()-side)/2,
+	))
+
+	images := make(map[int][]byte, len(sizes))
+
+	for _, size := range sizes {
+		buff := &bytes.Buffer{}
+
+		if err := png.Encode(buff, resize(src, square, size)); err != nil {
+			return nil, err
+		}
+
+		images[size] = buff.Bytes()
+	}
+
+	return images, nil
+}
+
+// resize scales the area of src to a size x size image. Every pixel of the
+// result is the average of the source pixels it covers, or the nearest one
+// when the image is enlarged.
+func resize(src image.Image, area image.Rectangle, size int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	side := area.Dx()
+
+	for y := 0; y < size; y++ {
+		y0 := area.Min.Y + y*side/size
+		y1 := max(area.Min.Y+(y+1)*side/size, y0+1)
+
+		for x := 0; x < size; x++ {
+			x0 := area.Min.X + x*side/size
+			x1 := max(area.Min.X+(x+1)*side/size, x0+1)
+
+			var r, g, b, a, n uint64
+
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+
+			// the sums are premultiplied by alpha, NRGBA is not
+			if a == 0 {
+				continue
+			}
+
+			dst.Pix[i+0] = uint8(r * 0xff / a)
+			dst.Pix[i+1] = uint8(g * 0xff / a)
+			dst.Pix[i+2] = uint8(b * 0xff / a)
+			dst.Pix[i+3] = uint8((a / n) >> 8)
+		}
+	}
+
+	return dst
+}
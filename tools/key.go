@@ -2,7 +2,9 @@ package tools
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 )
 
 func GenerateToken() string {
@@ -11,3 +13,20 @@ func GenerateToken() string {
 
 	return base64.URLEncoding.EncodeToString(r)
 }
+
+// GenerateHexToken returns a lowercase token, safe to use where the value may
+// be case-folded, such as the local part of an email address.
+func GenerateHexToken() string {
+	r := make([]byte, 16)
+	rand.Read(r)
+
+	return hex.EncodeToString(r)
+}
+
+// HashToken hashes a random token for storage. Unlike passwords, tokens have
+// enough entropy for a plain SHA-256, which also allows looking them up.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
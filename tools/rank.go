@@ -0,0 +1,68 @@
+package tools
+
+import "strings"
+
+const rankDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// RankBetween returns a key that sorts strictly between a and b, comparing
+// bytes. An empty a means "before everything" and an empty b means "after
+// everything". Keys never end with the smallest digit, so there is always
+// room for another key between two existing ones.
+func RankBetween(a, b string) string {
+	if b != "" && a >= b {
+		return rankMidpoint(a, "")
+	}
+
+	return rankMidpoint(a, b)
+}
+
+func rankMidpoint(a, b string) string {
+	if b != "" {
+		n := 0
+
+		for n < len(b) && rankDigitAt(a, n) == strings.IndexByte(rankDigits, b[n]) {
+			n++
+		}
+
+		if n > 0 {
+			rest := ""
+
+			if n < len(a) {
+				rest = a[n:]
+			}
+
+			return b[:n] + rankMidpoint(rest, b[n:])
+		}
+	}
+
+	digitA := rankDigitAt(a, 0)
+	digitB := len(rankDigits)
+
+	if b != "" {
+		digitB = strings.IndexByte(rankDigits, b[0])
+	}
+
+	if digitB-digitA > 1 {
+		return string(rankDigits[(digitA+digitB)/2])
+	}
+
+	if len(b) > 1 {
+		return b[:1]
+	}
+
+	rest := ""
+
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+
+	return string(rankDigits[digitA]) + rankMidpoint(rest, "")
+}
+
+func rankDigitAt(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+
+	return strings.IndexByte(rankDigits, s[i])
+}
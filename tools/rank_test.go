@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+// checkRank fails unless key sorts strictly between a and b, where empty
+// bounds are open, and can still have a key put before it.
+func checkRank(t *testing.T, a, b, key string) {
+	t.Helper()
+
+	if key == "" {
+		t.Fatalf("RankBetween(%q, %q) returned an empty key", a, b)
+	}
+
+	if key <= a || (b != "" && key >= b) {
+		t.Fatalf("RankBetween(%q, %q) = %q, not between them", a, b, key)
+	}
+
+	if strings.HasSuffix(key, rankDigits[:1]) {
+		t.Fatalf("RankBetween(%q, %q) = %q, ends with the smallest digit", a, b, key)
+	}
+
+	if strings.Trim(key, rankDigits) != "" {
+		t.Fatalf("RankBetween(%q, %q) = %q, has characters outside the digits", a, b, key)
+	}
+}
+
+func TestRankBetween(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"empty list", "", ""},
+		{"before the first key", "", "V"},
+		{"after the last key", "V", ""},
+		{"before the smallest key", "", "1"},
+		{"before a key starting with the smallest digit", "", "01"},
+		{"after the largest digit", "z", ""},
+		{"after a run of the largest digit", "zzz", ""},
+		{"between far apart keys", "A", "z"},
+		{"between adjacent digits", "A", "B"},
+		{"between a key and its extension", "A", "A1"},
+		{"between keys with a shared prefix", "AB", "AC"},
+		{"between a short and a longer key", "A", "Az"},
+		{"between keys of different lengths", "Azz", "B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkRank(t, tt.a, tt.b, RankBetween(tt.a, tt.b))
+		})
+	}
+}
+
+// Bounds out of order, as when two clients moved notes at the same time,
+// still give a key after a.
+func TestRankBetweenOutOfOrder(t *testing.T) {
+	for _, bounds := range [][2]string{{"B", "A"}, {"A", "A"}, {"z", "1"}} {
+		if key := RankBetween(bounds[0], bounds[1]); key <= bounds[0] {
+			t.Errorf("RankBetween(%q, %q) = %q, want a key after %q", bounds[0], bounds[1], key, bounds[0])
+		}
+	}
+}
+
+func TestRankBetweenRepeated(t *testing.T) {
+	t.Run("front", func(t *testing.T) {
+		first := "V"
+
+		for range 500 {
+			key := RankBetween("", first)
+			checkRank(t, "", first, key)
+			first = key
+		}
+	})
+
+	t.Run("back", func(t *testing.T) {
+		last := "V"
+
+		for range 500 {
+			key := RankBetween(last, "")
+			checkRank(t, last, "", key)
+			last = key
+		}
+	})
+
+	t.Run("same gap", func(t *testing.T) {
+		a, b := "A", "B"
+
+		for range 500 {
+			key := RankBetween(a, b)
+			checkRank(t, a, b, key)
+			b = key
+		}
+	})
+}
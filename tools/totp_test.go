@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 key of the RFC 6238 test vectors,
+// "12345678901234567890", base32 encoded.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The RFC lists 8 digit codes, these are their last 6 digits.
+func TestTOTPCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(tt.unix, 0)))
+
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", tt.unix, err)
+		}
+
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestTOTPCodeAcceptsPaddingAndLowercase(t *testing.T) {
+	want, _ := TOTPCode(rfc6238Secret, 1)
+
+	for _, secret := range []string{"gezdgnbvgy3tqojqgezdgnbvgy3tqojq", rfc6238Secret + "===="} {
+		if got, err := TOTPCode(secret, 1); err != nil || got != want {
+			t.Errorf("TOTPCode(%q) = %q, %v, want %q", secret, got, err, want)
+		}
+	}
+
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("TOTPCode accepted a secret that is not base32")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := TOTPStep(now)
+
+	code := func(step int64) string {
+		c, err := TOTPCode(rfc6238Secret, step)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOk   bool
+	}{
+		{"current step", code(current), current, true},
+		{"previous step", code(current - 1), current - 1, true},
+		{"next step", code(current + 1), current + 1, true},
+		{"spaces", " " + code(current)[:3] + " " + code(current)[3:] + " ", current, true},
+		{"two steps ago", code(current - 2), 0, false},
+		{"two steps ahead", code(current + 2), 0, false},
+		{"too short", code(current)[:5], 0, false},
+		{"too long", code(current) + "0", 0, false},
+		{"empty", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, tt.code, now)
+
+			if ok != tt.wantOk || step != tt.wantStep {
+				t.Fatalf("ValidateTOTP() = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOk)
+			}
+		})
+	}
+}
+
+// A code reused while it is still inside the window must report the step it
+// was generated for, not the current one, so the step already stored for the
+// user refuses it.
+func TestValidateTOTPReportsStepOfReplayedCode(t *testing.T) {
+	issued := time.Unix(1111111111, 0)
+	step := TOTPStep(issued)
+
+	code, err := TOTPCode(rfc6238Secret, step)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, later := range []time.Duration{0, 10 * time.Second, 30 * time.Second, 45 * time.Second} {
+		got, ok := ValidateTOTP(rfc6238Secret, code, issued.Add(later))
+
+		if !ok || got != step {
+			t.Errorf("ValidateTOTP %v later = %d, %v, want %d, true", later, got, ok, step)
+		}
+	}
+
+	if _, ok := ValidateTOTP(rfc6238Secret, code, issued.Add(2*time.Minute)); ok {
+		t.Error("ValidateTOTP accepted a code outside the window")
+	}
+}
@@ -0,0 +1,50 @@
+package tools
+
+import "strings"
+
+type userAgentRule struct {
+	token string
+	name  string
+}
+
+// The order matters: Edge and Opera also announce Chrome, and Chrome also
+// announces Safari.
+var browserRules = []userAgentRule{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"CriOS/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+}
+
+var platformRules = []userAgentRule{
+	{"Android", "Android"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// ParseUserAgent returns the browser and the operating system named by a
+// User-Agent header, or empty strings when they are not recognized.
+func ParseUserAgent(userAgent string) (browser, platform string) {
+	for _, rule := range browserRules {
+		if strings.Contains(userAgent, rule.token) {
+			browser = rule.name
+			break
+		}
+	}
+
+	for _, rule := range platformRules {
+		if strings.Contains(userAgent, rule.token) {
+			platform = rule.name
+			break
+		}
+	}
+
+	return
+}